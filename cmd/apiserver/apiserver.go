@@ -51,11 +51,14 @@ var (
 	etcdServerList        util.StringList
 	machineList           util.StringList
 	corsAllowedOriginList util.StringList
+	capabilityList        util.StringList
 	allowPrivileged       = flag.Bool("allow_privileged", false, "If true, allow privileged containers.")
+	allowHostNetwork      = flag.Bool("allow_host_network", false, "If true, allow pods to use the network namespace of the node.")
 )
 
 func init() {
 	flag.Var(&etcdServerList, "etcd_servers", "List of etcd servers to watch (http://ip:port), comma separated")
+	flag.Var(&capabilityList, "allowed_capabilities", "List of Linux capabilities containers may add, comma separated, e.g. NET_ADMIN. ALL allows any capability.")
 	flag.Var(&machineList, "machines", "List of machines to schedule onto, comma separated.")
 	flag.Var(&corsAllowedOriginList, "cors_allowed_origins", "List of allowed origins for CORS, comma separated.  An allowed origin can be a regular expression to support subdomain matching.  If this list is empty CORS will not be enabled.")
 }
@@ -116,7 +119,9 @@ func main() {
 	}
 
 	capabilities.Initialize(capabilities.Capabilities{
-		AllowPrivileged: *allowPrivileged,
+		AllowPrivileged:     *allowPrivileged,
+		AllowHostNetwork:    *allowHostNetwork,
+		AllowedCapabilities: capabilityList,
 	})
 
 	cloud := initCloudProvider(*cloudProvider, *cloudConfigFile)
@@ -126,6 +131,12 @@ func main() {
 		Port:   *minionPort,
 	}
 
+	// An unreachable minion must not hold up the refresh of the conditions of the others.
+	nodeConditionGetter := &client.HTTPNodeConditionGetter{
+		Client: &http.Client{Timeout: 5 * time.Second},
+		Port:   *minionPort,
+	}
+
 	client, err := client.New(net.JoinHostPort(*address, strconv.Itoa(int(*port))), *storageVersion, nil)
 	if err != nil {
 		glog.Fatalf("Invalid server address: %v", err)
@@ -137,14 +148,15 @@ func main() {
 	}
 
 	m := master.New(&master.Config{
-		Client:             client,
-		Cloud:              cloud,
-		EtcdHelper:         helper,
-		HealthCheckMinions: *healthCheckMinions,
-		Minions:            machineList,
-		MinionCacheTTL:     *minionCacheTTL,
-		MinionRegexp:       *minionRegexp,
-		PodInfoGetter:      podInfoGetter,
+		Client:              client,
+		Cloud:               cloud,
+		EtcdHelper:          helper,
+		HealthCheckMinions:  *healthCheckMinions,
+		Minions:             machineList,
+		MinionCacheTTL:      *minionCacheTTL,
+		MinionRegexp:        *minionRegexp,
+		PodInfoGetter:       podInfoGetter,
+		NodeConditionGetter: nodeConditionGetter,
 	})
 
 	mux := http.NewServeMux()
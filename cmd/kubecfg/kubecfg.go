@@ -69,6 +69,7 @@ var parser = kubecfg.NewParser(map[string]runtime.Object{
 	"services":               &api.Service{},
 	"replicationControllers": &api.ReplicationController{},
 	"minions":                &api.Minion{},
+	"secrets":                &api.Secret{},
 })
 
 func usage() {
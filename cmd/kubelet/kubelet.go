@@ -23,6 +23,7 @@ package main
 import (
 	"flag"
 	"math/rand"
+	"net"
 	"net/http"
 	"os"
 	"os/exec"
@@ -35,10 +36,12 @@ import (
 	_ "github.com/GoogleCloudPlatform/kubernetes/pkg/healthz"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet"
 	kconfig "github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/config"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/network"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/master"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/version/verflag"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/volume"
 	"github.com/coreos/go-etcd/etcd"
 	"github.com/fsouza/go-dockerclient"
 	"github.com/golang/glog"
@@ -50,6 +53,7 @@ const defaultRootDir = "/var/lib/kubelet"
 var (
 	config             = flag.String("config", "", "Path to the config file or directory of files")
 	syncFrequency      = flag.Duration("sync_frequency", 10*time.Second, "Max period between synchronizing running containers and config")
+	relistFrequency    = flag.Duration("relist_frequency", 2*time.Second, "Duration between relisting containers to find the pods whose containers changed")
 	fileCheckFrequency = flag.Duration("file_check_frequency", 20*time.Second, "Duration between checking config files for new data")
 	httpCheckFrequency = flag.Duration("http_check_frequency", 20*time.Second, "Duration between checking http for new data")
 	manifestURL        = flag.String("manifest_url", "", "URL for accessing the container manifest")
@@ -59,12 +63,20 @@ var (
 	hostnameOverride   = flag.String("hostname_override", "", "If non-empty, will use this string as identification instead of the actual hostname.")
 	dockerEndpoint     = flag.String("docker_endpoint", "", "If non-empty, use this for the docker endpoint to communicate with")
 	etcdServerList     util.StringList
+	capabilityList     util.StringList
 	rootDirectory      = flag.String("root_dir", defaultRootDir, "Directory path for managing kubelet files (volume mounts,etc).")
 	allowPrivileged    = flag.Bool("allow_privileged", false, "If true, allow containers to request privileged mode. [default=false]")
+	allowHostNetwork   = flag.Bool("allow_host_network", false, "If true, allow pods to request host networking. [default=false]")
+	evictionMemoryMB   = flag.Int64("eviction_memory_available_mb", 0, "Evict pods when less than this much memory, in megabytes, is available on the node. 0 disables memory eviction.")
+	evictionDiskMB     = flag.Int64("eviction_disk_available_mb", 0, "Evict pods when less than this much disk space, in megabytes, is available to the root directory or to Docker. 0 disables disk eviction.")
+	clusterDNS         = flag.String("cluster_dns", "", "If non-empty, the IP address of the cluster DNS server, which containers use unless their pod sets another DNS policy.")
+	clusterDomain      = flag.String("cluster_domain", "", "If non-empty, the domain containers search first when they use the cluster DNS server.")
+	networkPlugin      = flag.String("network_plugin", "", "If non-empty, the path of an executable which sets up the network of pods, called with setup, teardown or status, the full name of the pod, the ID of its network container and the path of its network namespace.")
 )
 
 func init() {
 	flag.Var(&etcdServerList, "etcd_servers", "List of etcd servers to watch (http://ip:port), comma separated")
+	flag.Var(&capabilityList, "allowed_capabilities", "List of Linux capabilities containers may add, comma separated, e.g. NET_ADMIN. ALL allows any capability.")
 }
 
 func getDockerEndpoint() string {
@@ -106,7 +118,9 @@ func main() {
 	etcd.SetLogger(util.NewLogger("etcd "))
 
 	capabilities.Initialize(capabilities.Capabilities{
-		AllowPrivileged: *allowPrivileged,
+		AllowPrivileged:     *allowPrivileged,
+		AllowHostNetwork:    *allowHostNetwork,
+		AllowedCapabilities: capabilityList,
 	})
 
 	dockerClient, err := docker.NewClient(getDockerEndpoint())
@@ -132,12 +146,12 @@ func main() {
 
 	// define file config source
 	if *config != "" {
-		kconfig.NewSourceFile(*config, *fileCheckFrequency, cfg.Channel("file"))
+		kconfig.NewSourceFile(*config, *fileCheckFrequency, cfg.Channel(kubelet.FileSource))
 	}
 
 	// define url config source
 	if *manifestURL != "" {
-		kconfig.NewSourceURL(*manifestURL, *httpCheckFrequency, cfg.Channel("http"))
+		kconfig.NewSourceURL(*manifestURL, *httpCheckFrequency, cfg.Channel(kubelet.HTTPSource))
 	}
 
 	// define etcd config source and initialize etcd client
@@ -145,11 +159,27 @@ func main() {
 	if len(etcdServerList) > 0 {
 		glog.Infof("Watching for etcd configs at %v", etcdServerList)
 		etcdClient = etcd.NewClient(etcdServerList)
-		kconfig.NewSourceEtcd(kconfig.EtcdKeyForHost(hostname), etcdClient, cfg.Channel("etcd"))
+		kconfig.NewSourceEtcd(kconfig.EtcdKeyForHost(hostname), etcdClient, cfg.Channel(kubelet.EtcdSource))
 	}
 
-	// TODO: block until all sources have delivered at least one update to the channel, or break the sync loop
-	// up into "per source" synchronizations
+	var clusterDNSIP net.IP
+	if *clusterDNS != "" {
+		if clusterDNSIP = net.ParseIP(*clusterDNS); clusterDNSIP == nil {
+			glog.Fatalf("Invalid cluster DNS server address: %s", *clusterDNS)
+		}
+	}
+
+	var networkPluginImpl network.Plugin
+	if *networkPlugin != "" {
+		networkPluginImpl = network.NewExecPlugin(*networkPlugin)
+	}
+
+	// The secret and downward API volumes read from the kubelet, so their plugins are added once
+	// it is created.
+	volumePlugins, err := volume.NewPluginRegistry(volume.DefaultPlugins()...)
+	if err != nil {
+		glog.Fatalf("Couldn't register the volume plugins: %v", err)
+	}
 
 	k := kubelet.NewMainKubelet(
 		getHostname(),
@@ -157,11 +187,32 @@ func main() {
 		cadvisorClient,
 		etcdClient,
 		*rootDirectory,
-		*syncFrequency)
+		*syncFrequency,
+		*relistFrequency,
+		kubelet.EvictionThresholds{
+			MemoryAvailable: *evictionMemoryMB * 1024 * 1024,
+			DiskAvailable:   *evictionDiskMB * 1024 * 1024,
+		},
+		networkPluginImpl,
+		clusterDNSIP,
+		*clusterDomain,
+		volumePlugins)
 
 	health.AddHealthChecker("exec", health.NewExecHealthChecker(k))
 	health.AddHealthChecker("http", health.NewHTTPHealthChecker(&http.Client{}))
 	health.AddHealthChecker("tcp", &health.TCPHealthChecker{})
+	for _, plugin := range []volume.Plugin{volume.NewSecretPlugin(k), volume.NewDownwardAPIPlugin(k)} {
+		if err := volumePlugins.Register(plugin); err != nil {
+			glog.Fatalf("Couldn't register the volume plugins: %v", err)
+		}
+	}
+
+	// The kubelet server sends its pods on the channel of the http source, which is only waited
+	// for when --manifest_url is set. The channel is created before the first sync.
+	var serverUpdates chan<- interface{}
+	if *enableServer {
+		serverUpdates = cfg.OptionalChannel(kubelet.HTTPSource)
+	}
 
 	// start the kubelet
 	go util.Forever(func() { k.Run(cfg.Updates()) }, 0)
@@ -169,7 +220,7 @@ func main() {
 	// start the kubelet server
 	if *enableServer {
 		go util.Forever(func() {
-			kubelet.ListenAndServeKubeletServer(k, cfg.Channel("http"), *address, *port)
+			kubelet.ListenAndServeKubeletServer(k, serverUpdates, *address, *port)
 		}, 0)
 	}
 
@@ -18,10 +18,12 @@ package main
 
 import (
 	"flag"
+	"net"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/latest"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/dns"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/proxy"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/proxy/config"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
@@ -35,6 +37,9 @@ var (
 	master         = flag.String("master", "", "The address of the Kubernetes API server (optional)")
 	etcdServerList util.StringList
 	bindAddress    = flag.String("bindaddress", "0.0.0.0", "The address for the proxy server to serve on (set to 0.0.0.0 or \"\" for all interfaces)")
+	dnsAddress     = flag.String("dns_address", "", "If non-empty, the UDP address (ip:port) for a DNS server resolving services in the cluster domain to serve on")
+	clusterDomain  = flag.String("cluster_domain", "kubernetes.local", "The domain the DNS server resolves services in")
+	dnsProxyIP     = flag.String("dns_proxy_ip", "", "If non-empty, the IP address of the proxy services resolve to; otherwise they resolve to their endpoints")
 )
 
 func init() {
@@ -92,6 +97,22 @@ func main() {
 	// And wire loadBalancer to handle changes to endpoints to services
 	endpointsConfig.RegisterHandler(loadBalancer)
 
+	if *dnsAddress != "" {
+		var proxyIP net.IP
+		if *dnsProxyIP != "" {
+			proxyIP = net.ParseIP(*dnsProxyIP)
+			if proxyIP.To4() == nil {
+				glog.Fatalf("Invalid -dns_proxy_ip: %q", *dnsProxyIP)
+			}
+		}
+		dnsServer := dns.NewServer(*clusterDomain, proxyIP)
+		dnsServer.Register(serviceConfig, endpointsConfig)
+		go func() {
+			glog.Fatalf("DNS server failed: %v", dnsServer.ListenAndServe(*dnsAddress))
+		}()
+		glog.Infof("Serving DNS for %s on %s", *clusterDomain, *dnsAddress)
+	}
+
 	// Just loop forever for now...
 	select {}
 }
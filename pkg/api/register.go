@@ -41,5 +41,7 @@ func init() {
 		&Binding{},
 		&Event{},
 		&EventList{},
+		&Secret{},
+		&SecretList{},
 	)
 }
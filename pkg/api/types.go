@@ -56,11 +56,36 @@ type ContainerManifest struct {
 	// TODO: UUID on Manifest is deprecated in the future once we are done
 	// with the API refactoring. It is required for now to determine the instance
 	// of a Pod.
-	UUID          string        `yaml:"uuid,omitempty" json:"uuid,omitempty"`
-	Volumes       []Volume      `yaml:"volumes" json:"volumes"`
-	Containers    []Container   `yaml:"containers" json:"containers"`
-	RestartPolicy RestartPolicy `json:"restartPolicy,omitempty" yaml:"restartPolicy,omitempty"`
-}
+	UUID string `yaml:"uuid,omitempty" json:"uuid,omitempty"`
+	// Optional: The labels of the pod. Set from the labels of the Pod by the apiserver.
+	Labels  map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Volumes []Volume          `yaml:"volumes" json:"volumes"`
+	// Optional: Containers run in order before the other containers are started. Each must
+	// exit successfully before the next one is started. Their names must be unique among
+	// all the containers of the manifest.
+	InitContainers []Container   `yaml:"initContainers,omitempty" json:"initContainers,omitempty"`
+	Containers     []Container   `yaml:"containers" json:"containers"`
+	RestartPolicy  RestartPolicy `json:"restartPolicy,omitempty" yaml:"restartPolicy,omitempty"`
+	// Optional: Seconds the containers are given to run their PreStop handlers and exit after
+	// the pod is deleted, before they are killed. Defaults to 30 seconds if zero.
+	TerminationGracePeriodSeconds int64 `json:"terminationGracePeriodSeconds,omitempty" yaml:"terminationGracePeriodSeconds,omitempty"`
+	// Optional: The IDs of the Secrets holding the credentials to pull the images of the
+	// containers with, in addition to the credentials of the node. Each Secret must hold the
+	// contents of a .dockercfg file under the key ".dockercfg". Any Secret can be named, see Secret.
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty" yaml:"imagePullSecrets,omitempty"`
+	// Optional: How the DNS resolver of the containers is configured. Defaults to "ClusterFirst".
+	DNSPolicy DNSPolicy `json:"dnsPolicy,omitempty" yaml:"dnsPolicy,omitempty"`
+	// Optional: The DNS resolver configuration of the containers. Required if DNSPolicy is
+	// "None", and not allowed otherwise.
+	DNSConfig *DNSConfig `json:"dnsConfig,omitempty" yaml:"dnsConfig,omitempty"`
+	// Optional: The containers use the network namespace of the node, instead of the one of
+	// the pod. Only allowed if host networking is enabled, and the host port of each container
+	// port must then equal the container port.
+	HostNetwork bool `json:"hostNetwork,omitempty" yaml:"hostNetwork,omitempty"`
+}
+
+// DefaultTerminationGracePeriodSeconds is used for manifests which don't set TerminationGracePeriodSeconds.
+const DefaultTerminationGracePeriodSeconds = 30
 
 // ContainerManifestList is used to communicate container manifests to kubelet.
 type ContainerManifestList struct {
@@ -91,6 +116,12 @@ type VolumeSource struct {
 	HostDirectory *HostDirectory `yaml:"hostDir" json:"hostDir"`
 	// EmptyDirectory represents a temporary directory that shares a pod's lifetime.
 	EmptyDirectory *EmptyDirectory `yaml:"emptyDir" json:"emptyDir"`
+	// GitRepo represents a git repository at a particular revision.
+	GitRepo *GitRepo `yaml:"gitRepo" json:"gitRepo"`
+	// Secret represents the data of a Secret, as files in a memory-backed directory.
+	Secret *SecretSource `yaml:"secret" json:"secret"`
+	// DownwardAPI represents fields of the pod, as files which are updated when the fields change.
+	DownwardAPI *DownwardAPIVolumeSource `yaml:"downwardAPI" json:"downwardAPI"`
 }
 
 // HostDirectory represents bare host directory volume.
@@ -98,7 +129,52 @@ type HostDirectory struct {
 	Path string `yaml:"path" json:"path"`
 }
 
-type EmptyDirectory struct{}
+// EmptyDirectory represents a temporary directory that shares a pod's lifetime.
+type EmptyDirectory struct {
+	// Optional: The storage medium backing the directory, defaults to the disk of the node.
+	Medium StorageMedium `yaml:"medium,omitempty" json:"medium,omitempty"`
+	// Optional: The maximum size of the directory in bytes, 0 for no limit. Memory-backed
+	// directories can't grow beyond it; pods whose disk-backed directories do are evicted.
+	SizeLimit int64 `yaml:"sizeLimit,omitempty" json:"sizeLimit,omitempty"`
+}
+
+// SecretSource represents a volume holding a file for each key of a Secret. Updates to the
+// Secret are eventually reflected in the files.
+type SecretSource struct {
+	// The ID of the Secret.
+	SecretID string `yaml:"secretID" json:"secretID"`
+}
+
+// DownwardAPIVolumeSource represents a volume holding a file for each of its items.
+type DownwardAPIVolumeSource struct {
+	Items []DownwardAPIVolumeFile `yaml:"items" json:"items"`
+}
+
+// DownwardAPIVolumeFile represents a file holding the value of a field of the pod.
+type DownwardAPIVolumeFile struct {
+	// Required: The name of the file, unique within the volume.
+	Name string `yaml:"name" json:"name"`
+	// Required: The field of the pod the file holds: "id", "host", "labels" or "labels.<KEY>".
+	FieldPath string `yaml:"fieldPath" json:"fieldPath"`
+}
+
+// StorageMedium defines the storage backing an EmptyDirectory.
+type StorageMedium string
+
+const (
+	// StorageMediumDefault stores the directory on the disk of the node.
+	StorageMediumDefault StorageMedium = ""
+	// StorageMediumMemory stores the directory in a tmpfs, so it never touches the disk.
+	StorageMediumMemory StorageMedium = "Memory"
+)
+
+// GitRepo represents a volume that is pulled from git when the pod is created.
+type GitRepo struct {
+	// Repository URL, with the http, https, git or ssh scheme, or scp-like, e.g. user@host:path.
+	Repository string `yaml:"repository" json:"repository"`
+	// Commit hash, branch or tag to check out, defaults to the head of the default branch.
+	Revision string `yaml:"revision,omitempty" json:"revision,omitempty"`
+}
 
 // Port represents a network port in a single container.
 type Port struct {
@@ -123,14 +199,43 @@ type VolumeMount struct {
 	ReadOnly bool `yaml:"readOnly,omitempty" json:"readOnly,omitempty"`
 	// Required.
 	MountPath string `yaml:"mountPath,omitempty" json:"mountPath,omitempty"`
+	// Optional: The path within the volume to mount instead of its root, e.g. so that several
+	// containers each mount their own subdirectory of one volume. Defaults to "" (the root).
+	// Secret and downward API volumes can't be mounted with a subPath.
+	SubPath string `yaml:"subPath,omitempty" json:"subPath,omitempty"`
+	// Optional: How mounts are propagated between the node and the container. Defaults to "".
+	MountPropagation MountPropagationMode `yaml:"mountPropagation,omitempty" json:"mountPropagation,omitempty"`
 }
 
+// MountPropagationMode describes how mounts are propagated between the node and a container.
+type MountPropagationMode string
+
+const (
+	// MountPropagationDefault doesn't propagate mounts in either direction.
+	MountPropagationDefault MountPropagationMode = ""
+	// MountPropagationHostToContainer propagates the mounts made on the node under the volume
+	// to the container, but not the other way around.
+	MountPropagationHostToContainer MountPropagationMode = "HostToContainer"
+	// MountPropagationBidirectional also propagates the mounts made by the container to the
+	// node. Only privileged containers may use it.
+	MountPropagationBidirectional MountPropagationMode = "Bidirectional"
+)
+
 // EnvVar represents an environment variable present in a Container.
 type EnvVar struct {
 	// Required: This must be a C_IDENTIFIER.
 	Name string `yaml:"name" json:"name"`
 	// Optional: defaults to "".
 	Value string `yaml:"value,omitempty" json:"value,omitempty"`
+	// Optional: The source of the value, instead of Value.
+	ValueFrom *EnvVarSource `yaml:"valueFrom,omitempty" json:"valueFrom,omitempty"`
+}
+
+// EnvVarSource represents the source of the value of an environment variable.
+type EnvVarSource struct {
+	// Required: The field of the pod the value is read from: "id", "host", "podIP", "labels"
+	// or "labels.<KEY>". "labels" is all the labels of the pod, one key="value" per line.
+	FieldPath string `yaml:"fieldPath" json:"fieldPath"`
 }
 
 // HTTPGetAction describes an action based on HTTP Get requests.
@@ -171,6 +276,15 @@ type LivenessProbe struct {
 	Exec *ExecAction `yaml:"exec,omitempty" json:"exec,omitempty"`
 	// Length of time before health checking is activated.  In seconds.
 	InitialDelaySeconds int64 `yaml:"initialDelaySeconds,omitempty" json:"initialDelaySeconds,omitempty"`
+	// Optional: How often to run the probe, in seconds. Defaults to 10.
+	PeriodSeconds int64 `yaml:"periodSeconds,omitempty" json:"periodSeconds,omitempty"`
+	// Optional: Seconds after which the probe counts as failed. Defaults to 1.
+	TimeoutSeconds int64 `yaml:"timeoutSeconds,omitempty" json:"timeoutSeconds,omitempty"`
+	// Optional: Consecutive successes for a failing probe to be considered passing. Defaults to 1.
+	// Must be 1 for liveness probes, which pass again as soon as they succeed once.
+	SuccessThreshold int `yaml:"successThreshold,omitempty" json:"successThreshold,omitempty"`
+	// Optional: Consecutive failures for a passing probe to be considered failing. Defaults to 3.
+	FailureThreshold int `yaml:"failureThreshold,omitempty" json:"failureThreshold,omitempty"`
 }
 
 // Container represents a single container that is expected to be run on the host.
@@ -192,9 +306,34 @@ type Container struct {
 	CPU           int            `yaml:"cpu,omitempty" json:"cpu,omitempty"`
 	VolumeMounts  []VolumeMount  `yaml:"volumeMounts,omitempty" json:"volumeMounts,omitempty"`
 	LivenessProbe *LivenessProbe `yaml:"livenessProbe,omitempty" json:"livenessProbe,omitempty"`
-	Lifecycle     *Lifecycle     `yaml:"lifecycle,omitempty" json:"lifecycle,omitempty"`
+	// Optional: Checks whether the container is ready to serve. Pods with containers that are
+	// not ready are excluded from the endpoints of services. Uses the same probes as LivenessProbe.
+	ReadinessProbe *LivenessProbe `yaml:"readinessProbe,omitempty" json:"readinessProbe,omitempty"`
+	Lifecycle      *Lifecycle     `yaml:"lifecycle,omitempty" json:"lifecycle,omitempty"`
 	// Optional: Default to false.
 	Privileged bool `json:"privileged,omitempty" yaml:"privileged,omitempty"`
+	// Optional: The security settings of the container. Defaults to the ones of the container runtime.
+	SecurityContext *SecurityContext `json:"securityContext,omitempty" yaml:"securityContext,omitempty"`
+}
+
+// SecurityContext holds the security settings of a container.
+type SecurityContext struct {
+	// Optional: The UID the processes of the container run as. Defaults to the user of the image.
+	RunAsUser *int64 `json:"runAsUser,omitempty" yaml:"runAsUser,omitempty"`
+	// Optional: The GID the processes of the container run as. Requires RunAsUser.
+	RunAsGroup *int64 `json:"runAsGroup,omitempty" yaml:"runAsGroup,omitempty"`
+	// Optional: The Linux capabilities added to or dropped from the default set of the container
+	// runtime. Capabilities can only be added if the cluster allows it.
+	Capabilities *Capabilities `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
+}
+
+// Capability is a Linux capability, without the "CAP_" prefix, e.g. "NET_ADMIN".
+type Capability string
+
+// Capabilities are the Linux capabilities added to and dropped from a container.
+type Capabilities struct {
+	Add  []Capability `json:"add,omitempty" yaml:"add,omitempty"`
+	Drop []Capability `json:"drop,omitempty" yaml:"drop,omitempty"`
 }
 
 // Handler defines a specific action that should be taken
@@ -240,6 +379,8 @@ const (
 	PodWaiting PodStatus = "Waiting"
 	// PodRunning means that the pod is up and running.
 	PodRunning PodStatus = "Running"
+	// PodTerminating means that the pod was deleted, and its containers are being stopped.
+	PodTerminating PodStatus = "Terminating"
 	// PodTerminated means that the pod has stopped.
 	PodTerminated PodStatus = "Terminated"
 )
@@ -271,6 +412,8 @@ type ContainerStatus struct {
 	// defined for container?
 	State        ContainerState `json:"state,omitempty" yaml:"state,omitempty"`
 	RestartCount int            `json:"restartCount" yaml:"restartCount"`
+	// Ready is true if the container is running and passes its readiness probe, if any.
+	Ready bool `json:"ready,omitempty" yaml:"ready,omitempty"`
 	// TODO(dchen1107): Introduce our own NetworkSettings struct here?
 	// TODO(dchen1107): Once we have done with integration with cadvisor, resource
 	// usage should be included.
@@ -299,6 +442,28 @@ type RestartPolicy struct {
 	Never     *RestartPolicyNever     `json:"never,omitempty" yaml:"never,omitempty"`
 }
 
+// DNSPolicy defines how the DNS resolver of the containers of a pod is configured.
+type DNSPolicy string
+
+const (
+	// DNSClusterFirst resolves names with the cluster DNS server, searching the cluster domain
+	// before the search paths of the node. Pods use the resolver of the node if the kubelet has
+	// no cluster DNS server.
+	DNSClusterFirst DNSPolicy = "ClusterFirst"
+	// DNSDefault resolves names like the node does.
+	DNSDefault DNSPolicy = "Default"
+	// DNSNone resolves names as configured by the DNSConfig of the manifest.
+	DNSNone DNSPolicy = "None"
+)
+
+// DNSConfig is the DNS resolver configuration of the containers of a pod.
+type DNSConfig struct {
+	// Required: The IP addresses of the name servers, at most 3.
+	Nameservers []string `json:"nameservers,omitempty" yaml:"nameservers,omitempty"`
+	// Optional: The domains searched for names with few dots, at most 6.
+	Searches []string `json:"searches,omitempty" yaml:"searches,omitempty"`
+}
+
 // PodState is the state of a pod, used as either input (desired state) or output (current state).
 type PodState struct {
 	Manifest ContainerManifest `json:"manifest,omitempty" yaml:"manifest,omitempty"`
@@ -334,6 +499,11 @@ type Pod struct {
 
 func (*Pod) IsAnAPIObject() {}
 
+// MirrorPodLabel is the label of the mirror pods which kubelets create for the pods they run
+// from their config file or URL. Its value is the host running the pod. Mirror pods are
+// read-only in the apiserver, they change with the config of their kubelet.
+const MirrorPodLabel = "mirrorPodHost"
+
 // ReplicationControllerState is the state of a replication controller, either input (create, update) or as output (list, get).
 type ReplicationControllerState struct {
 	Replicas        int               `json:"replicas" yaml:"replicas"`
@@ -421,10 +591,29 @@ type Minion struct {
 	JSONBase `json:",inline" yaml:",inline"`
 	// Queried from cloud provider, if available.
 	HostIP string `json:"hostIP,omitempty" yaml:"hostIP,omitempty"`
+	// The conditions which currently hold on the minion, as reported by its kubelet.
+	Conditions []NodeCondition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
 }
 
 func (*Minion) IsAnAPIObject() {}
 
+// NodeConditionKind is the kind of a condition of a minion.
+type NodeConditionKind string
+
+// These are the valid kinds of node conditions.
+const (
+	// NodeMemoryPressure means the minion is running low on memory, and is evicting pods.
+	NodeMemoryPressure NodeConditionKind = "MemoryPressure"
+	// NodeDiskPressure means the minion is running low on disk space, and is evicting pods.
+	NodeDiskPressure NodeConditionKind = "DiskPressure"
+)
+
+// NodeCondition is a condition which currently holds on a minion.
+type NodeCondition struct {
+	Kind   NodeConditionKind `json:"kind" yaml:"kind"`
+	Reason string            `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
 // MinionList is a list of minions.
 type MinionList struct {
 	JSONBase `json:",inline" yaml:",inline"`
@@ -672,3 +861,31 @@ type EventList struct {
 }
 
 func (*EventList) IsAnAPIObject() {}
+
+// Secret holds secret data, such as credentials, for use by pods. Secrets are managed through
+// the secrets resource of the apiserver.
+// TODO: Secrets are not scoped yet: any pod can refer to any Secret by its ID, and anyone who can
+// read pods or create them can get at the data of any Secret. Scope Secrets to users or namespaces
+// once the apiserver has them.
+type Secret struct {
+	JSONBase `yaml:",inline" json:",inline"`
+
+	// Data holds the base64 encoded secret data, keyed by filename.
+	Data map[string]string `json:"data,omitempty" yaml:"data,omitempty"`
+}
+
+func (*Secret) IsAnAPIObject() {}
+
+// SecretList is a list of Secrets.
+type SecretList struct {
+	JSONBase `yaml:",inline" json:",inline"`
+	Items    []Secret `yaml:"items,omitempty" json:"items,omitempty"`
+}
+
+func (*SecretList) IsAnAPIObject() {}
+
+// SecretDockerConfigKey is the key of the .dockercfg contents in a Secret used to pull images.
+const SecretDockerConfigKey = ".dockercfg"
+
+// MaxSecretSize is the maximum size of the decoded data of a Secret, in bytes.
+const MaxSecretSize = 1 * 1024 * 1024
@@ -28,7 +28,7 @@ func init() {
 			out.Value = in.Value
 			out.Key = in.Name
 			out.Name = in.Name
-			return nil
+			return s.Convert(&in.ValueFrom, &out.ValueFrom, 0)
 		},
 		func(in *EnvVar, out *newer.EnvVar, s conversion.Scope) error {
 			out.Value = in.Value
@@ -37,7 +37,7 @@ func init() {
 			} else {
 				out.Name = in.Key
 			}
-			return nil
+			return s.Convert(&in.ValueFrom, &out.ValueFrom, 0)
 		},
 
 		// Path & MountType are deprecated.
@@ -47,6 +47,8 @@ func init() {
 			out.MountPath = in.MountPath
 			out.Path = in.MountPath
 			out.MountType = "" // MountType is ignored.
+			out.SubPath = in.SubPath
+			out.MountPropagation = MountPropagationMode(in.MountPropagation)
 			return nil
 		},
 		func(in *VolumeMount, out *newer.VolumeMount, s conversion.Scope) error {
@@ -57,6 +59,8 @@ func init() {
 			} else {
 				out.MountPath = in.MountPath
 			}
+			out.SubPath = in.SubPath
+			out.MountPropagation = newer.MountPropagationMode(in.MountPropagation)
 			return nil
 		},
 
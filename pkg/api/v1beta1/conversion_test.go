@@ -70,6 +70,9 @@ func TestVolumeMountConversionToOld(t *testing.T) {
 		{
 			in:  newer.VolumeMount{Name: "foo", MountPath: "/dev/foo", ReadOnly: true},
 			out: v1beta1.VolumeMount{Name: "foo", MountPath: "/dev/foo", Path: "/dev/foo", ReadOnly: true},
+		}, {
+			in:  newer.VolumeMount{Name: "foo", MountPath: "/dev/foo", SubPath: "bar", MountPropagation: newer.MountPropagationHostToContainer},
+			out: v1beta1.VolumeMount{Name: "foo", MountPath: "/dev/foo", Path: "/dev/foo", SubPath: "bar", MountPropagation: v1beta1.MountPropagationHostToContainer},
 		},
 	}
 	for _, item := range table {
@@ -99,6 +102,9 @@ func TestVolumeMountConversionToNew(t *testing.T) {
 		}, {
 			in:  v1beta1.VolumeMount{Name: "foo", Path: "/dev/bar", ReadOnly: true},
 			out: newer.VolumeMount{Name: "foo", MountPath: "/dev/bar", ReadOnly: true},
+		}, {
+			in:  v1beta1.VolumeMount{Name: "foo", MountPath: "/dev/foo", SubPath: "bar", MountPropagation: v1beta1.MountPropagationBidirectional},
+			out: newer.VolumeMount{Name: "foo", MountPath: "/dev/foo", SubPath: "bar", MountPropagation: newer.MountPropagationBidirectional},
 		},
 	}
 	for _, item := range table {
@@ -43,5 +43,7 @@ func init() {
 		&Binding{},
 		&Event{},
 		&EventList{},
+		&Secret{},
+		&SecretList{},
 	)
 }
@@ -17,6 +17,11 @@ limitations under the License.
 package validation
 
 import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
@@ -62,7 +67,19 @@ func validateSource(source *api.VolumeSource) errs.ErrorList {
 	}
 	if source.EmptyDirectory != nil {
 		numVolumes++
-		//EmptyDirs have nothing to validate
+		allErrs = append(allErrs, validateEmptyDir(source.EmptyDirectory).Prefix("emptyDirectory")...)
+	}
+	if source.GitRepo != nil {
+		numVolumes++
+		allErrs = append(allErrs, validateGitRepo(source.GitRepo).Prefix("gitRepo")...)
+	}
+	if source.Secret != nil {
+		numVolumes++
+		allErrs = append(allErrs, validateSecretSource(source.Secret).Prefix("secret")...)
+	}
+	if source.DownwardAPI != nil {
+		numVolumes++
+		allErrs = append(allErrs, validateDownwardAPISource(source.DownwardAPI).Prefix("downwardAPI")...)
 	}
 	if numVolumes != 1 {
 		allErrs = append(allErrs, errs.NewFieldInvalid("", source))
@@ -78,6 +95,94 @@ func validateHostDir(hostDir *api.HostDirectory) errs.ErrorList {
 	return allErrs
 }
 
+var supportedStorageMedia = util.NewStringSet(string(api.StorageMediumDefault), string(api.StorageMediumMemory))
+
+func validateEmptyDir(emptyDir *api.EmptyDirectory) errs.ErrorList {
+	allErrs := errs.ErrorList{}
+	if !supportedStorageMedia.Has(string(emptyDir.Medium)) {
+		allErrs = append(allErrs, errs.NewFieldNotSupported("medium", emptyDir.Medium))
+	}
+	if emptyDir.SizeLimit < 0 {
+		allErrs = append(allErrs, errs.NewFieldInvalid("sizeLimit", emptyDir.SizeLimit))
+	}
+	return allErrs
+}
+
+// gitRevisionRegexp matches commit hashes, branches and tags which can't be mistaken for options
+// of git.
+var gitRevisionRegexp = regexp.MustCompile("^[A-Za-z0-9_][-A-Za-z0-9_./]*$")
+
+// gitSCPRegexp matches the scp-like syntax of ssh repositories, e.g. user@host:path. Helper
+// transports, e.g. ext::command, don't match, since their host is followed by "::".
+var gitSCPRegexp = regexp.MustCompile("^([A-Za-z0-9_.-]+@)?[A-Za-z0-9][A-Za-z0-9.-]*:[^:]")
+
+// gitSchemes are the URL schemes of the repositories the kubelet may clone. Local paths and
+// helper transports would let a pod read the node or run commands on it.
+var gitSchemes = util.NewStringSet("http", "https", "git", "ssh")
+
+// isValidGitRepository returns true if repository is a remote repository git can clone.
+func isValidGitRepository(repository string) bool {
+	if strings.Contains(repository, "://") {
+		u, err := url.Parse(repository)
+		return err == nil && gitSchemes.Has(u.Scheme) && u.Host != ""
+	}
+	return gitSCPRegexp.MatchString(repository)
+}
+
+func validateGitRepo(gitRepo *api.GitRepo) errs.ErrorList {
+	allErrs := errs.ErrorList{}
+	if gitRepo.Repository == "" {
+		allErrs = append(allErrs, errs.NewFieldRequired("repository", gitRepo.Repository))
+	} else if !isValidGitRepository(gitRepo.Repository) {
+		allErrs = append(allErrs, errs.NewFieldInvalid("repository", gitRepo.Repository))
+	}
+	if gitRepo.Revision != "" && (!gitRevisionRegexp.MatchString(gitRepo.Revision) || strings.Contains(gitRepo.Revision, "..")) {
+		allErrs = append(allErrs, errs.NewFieldInvalid("revision", gitRepo.Revision))
+	}
+	return allErrs
+}
+
+func validateSecretSource(secretSource *api.SecretSource) errs.ErrorList {
+	allErrs := errs.ErrorList{}
+	if len(secretSource.SecretID) == 0 {
+		allErrs = append(allErrs, errs.NewFieldRequired("secretID", secretSource.SecretID))
+	} else if !util.IsDNSSubdomain(secretSource.SecretID) {
+		allErrs = append(allErrs, errs.NewFieldInvalid("secretID", secretSource.SecretID))
+	}
+	return allErrs
+}
+
+func validateDownwardAPISource(source *api.DownwardAPIVolumeSource) errs.ErrorList {
+	allErrs := errs.ErrorList{}
+	allNames := util.StringSet{}
+	for i, item := range source.Items {
+		iErrs := errs.ErrorList{}
+		if len(item.Name) == 0 {
+			iErrs = append(iErrs, errs.NewFieldRequired("name", item.Name))
+		} else if len(item.Name) > 253 || !secretKeyRegexp.MatchString(item.Name) || item.Name == "." || item.Name == ".." {
+			iErrs = append(iErrs, errs.NewFieldInvalid("name", item.Name))
+		} else if allNames.Has(item.Name) {
+			iErrs = append(iErrs, errs.NewFieldDuplicate("name", item.Name))
+		} else {
+			allNames.Insert(item.Name)
+		}
+		// The IP address of the pod isn't known yet when its volumes are set up.
+		if item.FieldPath == "podIP" || !isPodFieldPath(item.FieldPath) {
+			iErrs = append(iErrs, errs.NewFieldNotSupported("fieldPath", item.FieldPath))
+		}
+		allErrs = append(allErrs, iErrs.PrefixIndex(i).Prefix("items")...)
+	}
+	return allErrs
+}
+
+var supportedPodFieldPaths = util.NewStringSet("id", "host", "podIP", "labels")
+
+// isPodFieldPath returns true if fieldPath is a field of a pod which can be exposed to its
+// containers: one of supportedPodFieldPaths, or "labels.<KEY>".
+func isPodFieldPath(fieldPath string) bool {
+	return supportedPodFieldPaths.Has(fieldPath) || (strings.HasPrefix(fieldPath, "labels.") && len(fieldPath) > len("labels."))
+}
+
 var supportedPortProtocols = util.NewStringSet("TCP", "UDP")
 
 func validatePorts(ports []api.Port) errs.ErrorList {
@@ -126,6 +231,16 @@ func validateEnv(vars []api.EnvVar) errs.ErrorList {
 		if !util.IsCIdentifier(ev.Name) {
 			vErrs = append(vErrs, errs.NewFieldInvalid("name", ev.Name))
 		}
+		if ev.ValueFrom != nil {
+			if len(ev.Value) != 0 {
+				vErrs = append(vErrs, errs.NewFieldInvalid("value", ev.Value))
+			}
+			if len(ev.ValueFrom.FieldPath) == 0 {
+				vErrs = append(vErrs, errs.NewFieldRequired("valueFrom.fieldPath", ev.ValueFrom.FieldPath))
+			} else if !isPodFieldPath(ev.ValueFrom.FieldPath) {
+				vErrs = append(vErrs, errs.NewFieldNotSupported("valueFrom.fieldPath", ev.ValueFrom.FieldPath))
+			}
+		}
 		allErrs = append(allErrs, vErrs.PrefixIndex(i)...)
 	}
 	return allErrs
@@ -145,11 +260,72 @@ func validateVolumeMounts(mounts []api.VolumeMount, volumes util.StringSet) errs
 		if len(mnt.MountPath) == 0 {
 			mErrs = append(mErrs, errs.NewFieldRequired("mountPath", mnt.MountPath))
 		}
+		if !isValidSubPath(mnt.SubPath) {
+			mErrs = append(mErrs, errs.NewFieldInvalid("subPath", mnt.SubPath))
+		}
+		if !supportedMountPropagationModes.Has(string(mnt.MountPropagation)) {
+			mErrs = append(mErrs, errs.NewFieldNotSupported("mountPropagation", mnt.MountPropagation))
+		}
 		allErrs = append(allErrs, mErrs.PrefixIndex(i)...)
 	}
 	return allErrs
 }
 
+// capabilityRegexp matches the names of Linux capabilities, without the "CAP_" prefix.
+var capabilityRegexp = regexp.MustCompile("^[A-Z][A-Z_]*$")
+
+// validateSecurityContext tests that the IDs of a security context are valid, and that the
+// capabilities it adds are allowed.
+func validateSecurityContext(sc *api.SecurityContext, allowed capabilities.Capabilities) errs.ErrorList {
+	allErrs := errs.ErrorList{}
+	if sc.RunAsUser != nil && *sc.RunAsUser < 0 {
+		allErrs = append(allErrs, errs.NewFieldInvalid("runAsUser", *sc.RunAsUser))
+	}
+	if sc.RunAsGroup != nil {
+		if *sc.RunAsGroup < 0 {
+			allErrs = append(allErrs, errs.NewFieldInvalid("runAsGroup", *sc.RunAsGroup))
+		}
+		// Docker only accepts a group with a user.
+		if sc.RunAsUser == nil {
+			allErrs = append(allErrs, errs.NewFieldRequired("runAsUser", nil))
+		}
+	}
+	if sc.Capabilities != nil {
+		for i, capability := range sc.Capabilities.Add {
+			if !capabilityRegexp.MatchString(string(capability)) || strings.HasPrefix(string(capability), "CAP_") {
+				allErrs = append(allErrs, errs.NewFieldInvalid(fmt.Sprintf("capabilities.add[%d]", i), capability))
+			} else if !allowed.AllowsCapability(string(capability)) {
+				allErrs = append(allErrs, errs.NewFieldNotSupported(fmt.Sprintf("capabilities.add[%d]", i), capability))
+			}
+		}
+		for i, capability := range sc.Capabilities.Drop {
+			if !capabilityRegexp.MatchString(string(capability)) || strings.HasPrefix(string(capability), "CAP_") {
+				allErrs = append(allErrs, errs.NewFieldInvalid(fmt.Sprintf("capabilities.drop[%d]", i), capability))
+			}
+		}
+	}
+	return allErrs
+}
+
+var supportedMountPropagationModes = util.NewStringSet(
+	string(api.MountPropagationDefault),
+	string(api.MountPropagationHostToContainer),
+	string(api.MountPropagationBidirectional))
+
+// isValidSubPath returns true if subPath is a path within a volume: relative, and without any
+// ".." element.
+func isValidSubPath(subPath string) bool {
+	if strings.HasPrefix(subPath, "/") {
+		return false
+	}
+	for _, element := range strings.Split(subPath, "/") {
+		if element == ".." {
+			return false
+		}
+	}
+	return true
+}
+
 // AccumulateUniquePorts runs an extraction function on each Port of each Container,
 // accumulating the results and returning an error if any ports conflict.
 func AccumulateUniquePorts(containers []api.Container, accumulator map[int]bool, extract func(*api.Port) int) errs.ErrorList {
@@ -220,6 +396,26 @@ func validateLifecycle(lifecycle *api.Lifecycle) errs.ErrorList {
 	return allErrs
 }
 
+func validateProbe(probe *api.LivenessProbe) errs.ErrorList {
+	allErrs := errs.ErrorList{}
+	if probe.InitialDelaySeconds < 0 {
+		allErrs = append(allErrs, errs.NewFieldInvalid("initialDelaySeconds", probe.InitialDelaySeconds))
+	}
+	if probe.PeriodSeconds < 0 {
+		allErrs = append(allErrs, errs.NewFieldInvalid("periodSeconds", probe.PeriodSeconds))
+	}
+	if probe.TimeoutSeconds < 0 {
+		allErrs = append(allErrs, errs.NewFieldInvalid("timeoutSeconds", probe.TimeoutSeconds))
+	}
+	if probe.SuccessThreshold < 0 {
+		allErrs = append(allErrs, errs.NewFieldInvalid("successThreshold", probe.SuccessThreshold))
+	}
+	if probe.FailureThreshold < 0 {
+		allErrs = append(allErrs, errs.NewFieldInvalid("failureThreshold", probe.FailureThreshold))
+	}
+	return allErrs
+}
+
 func validateContainers(containers []api.Container, volumes util.StringSet) errs.ErrorList {
 	allErrs := errs.ErrorList{}
 
@@ -245,9 +441,27 @@ func validateContainers(containers []api.Container, volumes util.StringSet) errs
 		if ctr.Lifecycle != nil {
 			cErrs = append(cErrs, validateLifecycle(ctr.Lifecycle).Prefix("lifecycle")...)
 		}
+		if ctr.LivenessProbe != nil {
+			cErrs = append(cErrs, validateProbe(ctr.LivenessProbe).Prefix("livenessProbe")...)
+			if ctr.LivenessProbe.SuccessThreshold > 1 {
+				cErrs = append(cErrs, errs.NewFieldInvalid("livenessProbe.successThreshold", ctr.LivenessProbe.SuccessThreshold))
+			}
+		}
+		if ctr.ReadinessProbe != nil {
+			cErrs = append(cErrs, validateProbe(ctr.ReadinessProbe).Prefix("readinessProbe")...)
+		}
 		cErrs = append(cErrs, validatePorts(ctr.Ports).Prefix("ports")...)
 		cErrs = append(cErrs, validateEnv(ctr.Env).Prefix("env")...)
 		cErrs = append(cErrs, validateVolumeMounts(ctr.VolumeMounts, volumes).Prefix("volumeMounts")...)
+		if ctr.SecurityContext != nil {
+			cErrs = append(cErrs, validateSecurityContext(ctr.SecurityContext, capabilities).Prefix("securityContext")...)
+		}
+		for j, mnt := range ctr.VolumeMounts {
+			// The mounts of the container would leak out to the node.
+			if mnt.MountPropagation == api.MountPropagationBidirectional && !ctr.Privileged {
+				cErrs = append(cErrs, errs.NewFieldInvalid(fmt.Sprintf("volumeMounts[%d].mountPropagation", j), mnt.MountPropagation))
+			}
+		}
 		allErrs = append(allErrs, cErrs.PrefixIndex(i)...)
 	}
 	// Check for colliding ports across all containers.
@@ -276,8 +490,155 @@ func ValidateManifest(manifest *api.ContainerManifest) errs.ErrorList {
 	}
 	allVolumes, vErrs := validateVolumes(manifest.Volumes)
 	allErrs = append(allErrs, vErrs.Prefix("volumes")...)
+	allErrs = append(allErrs, validateInitContainers(manifest.InitContainers, manifest.Containers, allVolumes).Prefix("initContainers")...)
 	allErrs = append(allErrs, validateContainers(manifest.Containers, allVolumes).Prefix("containers")...)
+	allErrs = append(allErrs, validateSubPaths(manifest)...)
 	allErrs = append(allErrs, validateRestartPolicy(&manifest.RestartPolicy).Prefix("restartPolicy")...)
+	if manifest.TerminationGracePeriodSeconds < 0 {
+		allErrs = append(allErrs, errs.NewFieldInvalid("terminationGracePeriodSeconds", manifest.TerminationGracePeriodSeconds))
+	}
+	allErrs = append(allErrs, validateImagePullSecrets(manifest.ImagePullSecrets).Prefix("imagePullSecrets")...)
+	allErrs = append(allErrs, validateDNS(manifest.DNSPolicy, manifest.DNSConfig)...)
+	if manifest.HostNetwork {
+		allErrs = append(allErrs, validateHostNetwork(manifest)...)
+	}
+	return allErrs
+}
+
+// validateHostNetwork checks that host networking is allowed, and that the ports of the containers
+// are exposed on the node as they are. The host ports default to the container ports.
+func validateHostNetwork(manifest *api.ContainerManifest) errs.ErrorList {
+	allErrs := errs.ErrorList{}
+	if !capabilities.Get().AllowHostNetwork {
+		allErrs = append(allErrs, errs.NewFieldInvalid("hostNetwork", manifest.HostNetwork))
+	}
+	allErrs = append(allErrs, validateHostNetworkPorts(manifest.InitContainers).Prefix("initContainers")...)
+	allErrs = append(allErrs, validateHostNetworkPorts(manifest.Containers).Prefix("containers")...)
+	return allErrs
+}
+
+func validateHostNetworkPorts(containers []api.Container) errs.ErrorList {
+	allErrs := errs.ErrorList{}
+	for i := range containers {
+		pErrs := errs.ErrorList{}
+		for j := range containers[i].Ports {
+			port := &containers[i].Ports[j] // so we can set default values
+			if port.HostPort == 0 {
+				port.HostPort = port.ContainerPort
+			} else if port.HostPort != port.ContainerPort {
+				pErrs = append(pErrs, errs.ErrorList{errs.NewFieldInvalid("hostPort", port.HostPort)}.PrefixIndex(j)...)
+			}
+		}
+		allErrs = append(allErrs, pErrs.Prefix("ports").PrefixIndex(i)...)
+	}
+	return allErrs
+}
+
+var supportedDNSPolicies = util.NewStringSet(string(api.DNSClusterFirst), string(api.DNSDefault), string(api.DNSNone))
+
+const (
+	// The limits of the glibc resolver.
+	maxDNSNameservers = 3
+	maxDNSSearches    = 6
+)
+
+func validateDNS(policy api.DNSPolicy, config *api.DNSConfig) errs.ErrorList {
+	allErrs := errs.ErrorList{}
+	if len(policy) != 0 && !supportedDNSPolicies.Has(string(policy)) {
+		allErrs = append(allErrs, errs.NewFieldNotSupported("dnsPolicy", policy))
+	}
+	if policy != api.DNSNone {
+		if config != nil {
+			allErrs = append(allErrs, errs.NewFieldInvalid("dnsConfig", config))
+		}
+		return allErrs
+	}
+	if config == nil {
+		return append(allErrs, errs.NewFieldRequired("dnsConfig", config))
+	}
+	cErrs := errs.ErrorList{}
+	if len(config.Nameservers) == 0 {
+		cErrs = append(cErrs, errs.NewFieldRequired("nameservers", config.Nameservers))
+	} else if len(config.Nameservers) > maxDNSNameservers {
+		cErrs = append(cErrs, errs.NewFieldInvalid("nameservers", config.Nameservers))
+	}
+	for i, nameserver := range config.Nameservers {
+		if net.ParseIP(nameserver) == nil {
+			cErrs = append(cErrs, errs.NewFieldInvalid(fmt.Sprintf("nameservers[%d]", i), nameserver))
+		}
+	}
+	if len(config.Searches) > maxDNSSearches {
+		cErrs = append(cErrs, errs.NewFieldInvalid("searches", config.Searches))
+	}
+	for i, search := range config.Searches {
+		if !util.IsDNSSubdomain(search) {
+			cErrs = append(cErrs, errs.NewFieldInvalid(fmt.Sprintf("searches[%d]", i), search))
+		}
+	}
+	return append(allErrs, cErrs.Prefix("dnsConfig")...)
+}
+
+// validateSubPaths tests that the volumes whose files are written by the kubelet, secrets and
+// the downward API, aren't mounted with a subPath: the subdirectory would be created among the
+// files, which the kubelet replaces.
+func validateSubPaths(manifest *api.ContainerManifest) errs.ErrorList {
+	written := util.StringSet{}
+	for _, vol := range manifest.Volumes {
+		if vol.Source != nil && (vol.Source.Secret != nil || vol.Source.DownwardAPI != nil) {
+			written.Insert(vol.Name)
+		}
+	}
+	check := func(containers []api.Container) errs.ErrorList {
+		allErrs := errs.ErrorList{}
+		for i := range containers {
+			for j, mnt := range containers[i].VolumeMounts {
+				if mnt.SubPath != "" && written.Has(mnt.Name) {
+					allErrs = append(allErrs, errs.NewFieldInvalid(fmt.Sprintf("[%d].volumeMounts[%d].subPath", i, j), mnt.SubPath))
+				}
+			}
+		}
+		return allErrs
+	}
+	allErrs := check(manifest.InitContainers).Prefix("initContainers")
+	return append(allErrs, check(manifest.Containers).Prefix("containers")...)
+}
+
+// validateInitContainers validates the init containers of a manifest, whose names must not be
+// used by its other containers. Init containers run to completion, so they can't be probed
+// and have no lifecycle handlers.
+func validateInitContainers(initContainers, containers []api.Container, volumes util.StringSet) errs.ErrorList {
+	allErrs := validateContainers(initContainers, volumes)
+	otherNames := util.StringSet{}
+	for i := range containers {
+		otherNames.Insert(containers[i].Name)
+	}
+	for i := range initContainers {
+		cErrs := errs.ErrorList{}
+		ctr := &initContainers[i]
+		if otherNames.Has(ctr.Name) {
+			cErrs = append(cErrs, errs.NewFieldDuplicate("name", ctr.Name))
+		}
+		if ctr.Lifecycle != nil {
+			cErrs = append(cErrs, errs.NewFieldInvalid("lifecycle", ctr.Lifecycle))
+		}
+		if ctr.LivenessProbe != nil {
+			cErrs = append(cErrs, errs.NewFieldInvalid("livenessProbe", ctr.LivenessProbe))
+		}
+		if ctr.ReadinessProbe != nil {
+			cErrs = append(cErrs, errs.NewFieldInvalid("readinessProbe", ctr.ReadinessProbe))
+		}
+		allErrs = append(allErrs, cErrs.PrefixIndex(i)...)
+	}
+	return allErrs
+}
+
+func validateImagePullSecrets(secrets []string) errs.ErrorList {
+	allErrs := errs.ErrorList{}
+	for i, secret := range secrets {
+		if !util.IsDNSSubdomain(secret) {
+			allErrs = append(allErrs, errs.ErrorList{errs.NewFieldInvalid("", secret)}.PrefixIndex(i)...)
+		}
+	}
 	return allErrs
 }
 
@@ -366,3 +727,34 @@ func ValidateReplicationControllerState(state *api.ReplicationControllerState) e
 	allErrs = append(allErrs, ValidateManifest(&state.PodTemplate.DesiredState.Manifest).Prefix("podTemplate.desiredState.manifest")...)
 	return allErrs
 }
+
+// secretKeyRegexp matches the keys of Secrets, which are used as filenames.
+var secretKeyRegexp = regexp.MustCompile("^[-._a-zA-Z0-9]+$")
+
+// ValidateSecret tests if required fields in the secret are set, and that the keys of its data
+// are valid filenames and its values base64 encoded data of at most api.MaxSecretSize bytes.
+func ValidateSecret(secret *api.Secret) errs.ErrorList {
+	allErrs := errs.ErrorList{}
+	if len(secret.ID) == 0 {
+		allErrs = append(allErrs, errs.NewFieldRequired("id", secret.ID))
+	} else if !util.IsDNSSubdomain(secret.ID) {
+		allErrs = append(allErrs, errs.NewFieldInvalid("id", secret.ID))
+	}
+	totalSize := 0
+	for key, value := range secret.Data {
+		if len(key) > 253 || !secretKeyRegexp.MatchString(key) || key == "." || key == ".." {
+			allErrs = append(allErrs, errs.NewFieldInvalid("data", key))
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			allErrs = append(allErrs, errs.NewFieldInvalid("data["+key+"]", "<secret data>"))
+			continue
+		}
+		totalSize += len(decoded)
+	}
+	if totalSize > api.MaxSecretSize {
+		allErrs = append(allErrs, errs.NewFieldInvalid("data", fmt.Sprintf("%d bytes", totalSize)))
+	}
+	return allErrs
+}
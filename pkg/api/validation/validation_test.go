@@ -17,6 +17,7 @@ limitations under the License.
 package validation
 
 import (
+	"encoding/base64"
 	"strings"
 	"testing"
 
@@ -40,12 +41,22 @@ func TestValidateVolumes(t *testing.T) {
 		{Name: "123", Source: &api.VolumeSource{HostDirectory: &api.HostDirectory{"/mnt/path2"}}},
 		{Name: "abc-123", Source: &api.VolumeSource{HostDirectory: &api.HostDirectory{"/mnt/path3"}}},
 		{Name: "empty", Source: &api.VolumeSource{EmptyDirectory: &api.EmptyDirectory{}}},
+		{Name: "tmpfs", Source: &api.VolumeSource{EmptyDirectory: &api.EmptyDirectory{Medium: api.StorageMediumMemory, SizeLimit: 1 << 20}}},
+		{Name: "secret", Source: &api.VolumeSource{Secret: &api.SecretSource{SecretID: "my-secret"}}},
+		{Name: "gitrepo", Source: &api.VolumeSource{GitRepo: &api.GitRepo{Repository: "https://example.com/my-repo.git", Revision: "hashstring"}}},
+		{Name: "gitrepo-head", Source: &api.VolumeSource{GitRepo: &api.GitRepo{Repository: "git@example.com:my-repo.git"}}},
+		{Name: "gitrepo-ssh", Source: &api.VolumeSource{GitRepo: &api.GitRepo{Repository: "ssh://git@example.com/my-repo.git"}}},
+		{Name: "downward", Source: &api.VolumeSource{DownwardAPI: &api.DownwardAPIVolumeSource{Items: []api.DownwardAPIVolumeFile{
+			{Name: "labels", FieldPath: "labels"},
+			{Name: "tier", FieldPath: "labels.tier"},
+			{Name: "pod.id", FieldPath: "id"},
+		}}}},
 	}
 	names, errs := validateVolumes(successCase)
 	if len(errs) != 0 {
 		t.Errorf("expected success: %v", errs)
 	}
-	if len(names) != 4 || !names.HasAll("abc", "123", "abc-123", "empty") {
+	if len(names) != 10 || !names.HasAll("abc", "123", "abc-123", "empty", "tmpfs", "secret", "gitrepo", "gitrepo-head", "gitrepo-ssh", "downward") {
 		t.Errorf("wrong names result: %v", names)
 	}
 
@@ -58,6 +69,62 @@ func TestValidateVolumes(t *testing.T) {
 		"name > 63 characters": {[]api.Volume{{Name: strings.Repeat("a", 64)}}, errors.ValidationErrorTypeInvalid, "[0].name"},
 		"name not a DNS label": {[]api.Volume{{Name: "a.b.c"}}, errors.ValidationErrorTypeInvalid, "[0].name"},
 		"name not unique":      {[]api.Volume{{Name: "abc"}, {Name: "abc"}}, errors.ValidationErrorTypeDuplicate, "[1].name"},
+		"empty dir medium not supported": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{EmptyDirectory: &api.EmptyDirectory{Medium: "Floppy"}}}},
+			errors.ValidationErrorTypeNotSupported, "[0].source.emptyDirectory.medium",
+		},
+		"empty dir size limit negative": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{EmptyDirectory: &api.EmptyDirectory{SizeLimit: -1}}}},
+			errors.ValidationErrorTypeInvalid, "[0].source.emptyDirectory.sizeLimit",
+		},
+		"secret id missing": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{Secret: &api.SecretSource{}}}},
+			errors.ValidationErrorTypeRequired, "[0].source.secret.secretID",
+		},
+		"git repository missing": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{GitRepo: &api.GitRepo{Revision: "master"}}}},
+			errors.ValidationErrorTypeRequired, "[0].source.gitRepo.repository",
+		},
+		"git repository a local path": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{GitRepo: &api.GitRepo{Repository: "/var/lib/kubelet"}}}},
+			errors.ValidationErrorTypeInvalid, "[0].source.gitRepo.repository",
+		},
+		"git repository a file url": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{GitRepo: &api.GitRepo{Repository: "file:///var/lib/kubelet"}}}},
+			errors.ValidationErrorTypeInvalid, "[0].source.gitRepo.repository",
+		},
+		"git repository a helper transport": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{GitRepo: &api.GitRepo{Repository: "ext::sh -c touch% /tmp/pwned"}}}},
+			errors.ValidationErrorTypeInvalid, "[0].source.gitRepo.repository",
+		},
+		"git repository an unknown scheme": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{GitRepo: &api.GitRepo{Repository: "ftp://example.com/my-repo.git"}}}},
+			errors.ValidationErrorTypeInvalid, "[0].source.gitRepo.repository",
+		},
+		"git repository relative": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{GitRepo: &api.GitRepo{Repository: "my-repo"}}}},
+			errors.ValidationErrorTypeInvalid, "[0].source.gitRepo.repository",
+		},
+		"git revision an option": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{GitRepo: &api.GitRepo{Repository: "https://example.com/my-repo.git", Revision: "--upload-pack=touch"}}}},
+			errors.ValidationErrorTypeInvalid, "[0].source.gitRepo.revision",
+		},
+		"git revision a range": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{GitRepo: &api.GitRepo{Repository: "https://example.com/my-repo.git", Revision: "master..branch"}}}},
+			errors.ValidationErrorTypeInvalid, "[0].source.gitRepo.revision",
+		},
+		"downward api file name a path": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{DownwardAPI: &api.DownwardAPIVolumeSource{Items: []api.DownwardAPIVolumeFile{{Name: "../labels", FieldPath: "labels"}}}}}},
+			errors.ValidationErrorTypeInvalid, "[0].source.downwardAPI.items[0].name",
+		},
+		"downward api file name duplicate": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{DownwardAPI: &api.DownwardAPIVolumeSource{Items: []api.DownwardAPIVolumeFile{{Name: "id", FieldPath: "id"}, {Name: "id", FieldPath: "host"}}}}}},
+			errors.ValidationErrorTypeDuplicate, "[0].source.downwardAPI.items[1].name",
+		},
+		"downward api pod ip": {
+			[]api.Volume{{Name: "abc", Source: &api.VolumeSource{DownwardAPI: &api.DownwardAPIVolumeSource{Items: []api.DownwardAPIVolumeFile{{Name: "ip", FieldPath: "podIP"}}}}}},
+			errors.ValidationErrorTypeNotSupported, "[0].source.downwardAPI.items[0].fieldPath",
+		},
 	}
 	for k, v := range errorCases {
 		_, errs := validateVolumes(v.V)
@@ -138,6 +205,9 @@ func TestValidateEnv(t *testing.T) {
 		{Name: "ABC", Value: "value"},
 		{Name: "AbC_123", Value: "value"},
 		{Name: "abc", Value: ""},
+		{Name: "POD_ID", ValueFrom: &api.EnvVarSource{FieldPath: "id"}},
+		{Name: "POD_IP", ValueFrom: &api.EnvVarSource{FieldPath: "podIP"}},
+		{Name: "TIER", ValueFrom: &api.EnvVarSource{FieldPath: "labels.tier"}},
 	}
 	if errs := validateEnv(successCase); len(errs) != 0 {
 		t.Errorf("expected success: %v", errs)
@@ -146,6 +216,10 @@ func TestValidateEnv(t *testing.T) {
 	errorCases := map[string][]api.EnvVar{
 		"zero-length name":        {{Name: ""}},
 		"name not a C identifier": {{Name: "a.b.c"}},
+		"value and value from":    {{Name: "abc", Value: "value", ValueFrom: &api.EnvVarSource{FieldPath: "id"}}},
+		"zero-length field path":  {{Name: "abc", ValueFrom: &api.EnvVarSource{}}},
+		"unsupported field path":  {{Name: "abc", ValueFrom: &api.EnvVarSource{FieldPath: "desiredState.host"}}},
+		"zero-length label key":   {{Name: "abc", ValueFrom: &api.EnvVarSource{FieldPath: "labels."}}},
 	}
 	for k, v := range errorCases {
 		if errs := validateEnv(v); len(errs) == 0 {
@@ -161,15 +235,21 @@ func TestValidateVolumeMounts(t *testing.T) {
 		{Name: "abc", MountPath: "/foo"},
 		{Name: "123", MountPath: "/foo"},
 		{Name: "abc-123", MountPath: "/bar"},
+		{Name: "abc", MountPath: "/baz", SubPath: "baz"},
+		{Name: "abc", MountPath: "/data", SubPath: "data/..data", ReadOnly: true},
+		{Name: "abc", MountPath: "/mnt", MountPropagation: api.MountPropagationHostToContainer},
 	}
 	if errs := validateVolumeMounts(successCase, volumes); len(errs) != 0 {
 		t.Errorf("expected success: %v", errs)
 	}
 
 	errorCases := map[string][]api.VolumeMount{
-		"empty name":      {{Name: "", MountPath: "/foo"}},
-		"name not found":  {{Name: "", MountPath: "/foo"}},
-		"empty mountpath": {{Name: "abc", MountPath: ""}},
+		"empty name":                    {{Name: "", MountPath: "/foo"}},
+		"name not found":                {{Name: "", MountPath: "/foo"}},
+		"empty mountpath":               {{Name: "abc", MountPath: ""}},
+		"absolute subpath":              {{Name: "abc", MountPath: "/foo", SubPath: "/etc"}},
+		"subpath leaving the volume":    {{Name: "abc", MountPath: "/foo", SubPath: "foo/../.."}},
+		"unsupported mount propagation": {{Name: "abc", MountPath: "/foo", MountPropagation: "Shared"}},
 	}
 	for k, v := range errorCases {
 		if errs := validateVolumeMounts(v, volumes); len(errs) == 0 {
@@ -179,10 +259,12 @@ func TestValidateVolumeMounts(t *testing.T) {
 }
 
 func TestValidateContainers(t *testing.T) {
-	volumes := util.StringSet{}
+	volumes := util.NewStringSet("abc")
 	capabilities.SetForTests(capabilities.Capabilities{
-		AllowPrivileged: true,
+		AllowPrivileged:     true,
+		AllowedCapabilities: []string{"NET_ADMIN"},
 	})
+	uid, gid, negative := int64(1000), int64(0), int64(-1)
 
 	successCase := []api.Container{
 		{Name: "abc", Image: "image"},
@@ -198,6 +280,21 @@ func TestValidateContainers(t *testing.T) {
 			},
 		},
 		{Name: "abc-1234", Image: "image", Privileged: true},
+		{
+			Name:         "bidirectional",
+			Image:        "image",
+			Privileged:   true,
+			VolumeMounts: []api.VolumeMount{{Name: "abc", MountPath: "/mnt", MountPropagation: api.MountPropagationBidirectional}},
+		},
+		{
+			Name:  "security-context",
+			Image: "image",
+			SecurityContext: &api.SecurityContext{
+				RunAsUser:    &uid,
+				RunAsGroup:   &gid,
+				Capabilities: &api.Capabilities{Add: []api.Capability{"NET_ADMIN"}, Drop: []api.Capability{"ALL"}},
+			},
+		},
 	}
 	if errs := validateContainers(successCase, volumes); len(errs) != 0 {
 		t.Errorf("expected success: %v", errs)
@@ -225,6 +322,15 @@ func TestValidateContainers(t *testing.T) {
 		"unknown volume name": {
 			{Name: "abc", Image: "image", VolumeMounts: []api.VolumeMount{{Name: "anything", MountPath: "/foo"}}},
 		},
+		"negative liveness probe period": {
+			{Name: "abc", Image: "image", LivenessProbe: &api.LivenessProbe{PeriodSeconds: -1}},
+		},
+		"negative readiness probe failure threshold": {
+			{Name: "abc", Image: "image", ReadinessProbe: &api.LivenessProbe{FailureThreshold: -1}},
+		},
+		"liveness probe success threshold above 1": {
+			{Name: "abc", Image: "image", LivenessProbe: &api.LivenessProbe{SuccessThreshold: 2}},
+		},
 		"invalid lifecycle, no exec command.": {
 			{
 				Name:  "life-123",
@@ -259,6 +365,21 @@ func TestValidateContainers(t *testing.T) {
 		"privilege disabled": {
 			{Name: "abc", Image: "image", Privileged: true},
 		},
+		"negative run as user": {
+			{Name: "abc", Image: "image", SecurityContext: &api.SecurityContext{RunAsUser: &negative}},
+		},
+		"run as group without user": {
+			{Name: "abc", Image: "image", SecurityContext: &api.SecurityContext{RunAsGroup: &gid}},
+		},
+		"capability not allowed": {
+			{Name: "abc", Image: "image", SecurityContext: &api.SecurityContext{Capabilities: &api.Capabilities{Add: []api.Capability{"SYS_ADMIN"}}}},
+		},
+		"capability with prefix": {
+			{Name: "abc", Image: "image", SecurityContext: &api.SecurityContext{Capabilities: &api.Capabilities{Drop: []api.Capability{"CAP_NET_RAW"}}}},
+		},
+		"bidirectional mount propagation without privilege": {
+			{Name: "abc", Image: "image", VolumeMounts: []api.VolumeMount{{Name: "abc", MountPath: "/mnt", MountPropagation: api.MountPropagationBidirectional}}},
+		},
 	}
 	for k, v := range errorCases {
 		if errs := validateContainers(v, volumes); len(errs) == 0 {
@@ -306,6 +427,20 @@ func TestValidateManifest(t *testing.T) {
 		{Version: "v1beta1", ID: "abc"},
 		{Version: "v1beta2", ID: "123"},
 		{Version: "V1BETA1", ID: "abc.123.do-re-mi"},
+		{Version: "v1beta1", ID: "abc", ImagePullSecrets: []string{"registry-creds"}},
+		{Version: "v1beta1", ID: "abc", DNSPolicy: api.DNSDefault},
+		{
+			Version:   "v1beta1",
+			ID:        "abc",
+			DNSPolicy: api.DNSNone,
+			DNSConfig: &api.DNSConfig{Nameservers: []string{"10.0.0.10"}, Searches: []string{"example.com"}},
+		},
+		{
+			Version:        "v1beta1",
+			ID:             "abc",
+			InitContainers: []api.Container{{Name: "migrate", Image: "image"}, {Name: "fetch", Image: "image"}},
+			Containers:     []api.Container{{Name: "abc", Image: "image"}},
+		},
 		{
 			Version: "v1beta1",
 			ID:      "abc",
@@ -356,6 +491,64 @@ func TestValidateManifest(t *testing.T) {
 			ID:         "abc",
 			Containers: []api.Container{{Name: "ctr.1", Image: "image"}},
 		},
+		"negative termination grace period": {
+			Version:                       "v1beta1",
+			ID:                            "abc",
+			TerminationGracePeriodSeconds: -1,
+		},
+		"init container name used by a container": {
+			Version:        "v1beta1",
+			ID:             "abc",
+			InitContainers: []api.Container{{Name: "abc", Image: "image"}},
+			Containers:     []api.Container{{Name: "abc", Image: "image"}},
+		},
+		"init container with a probe": {
+			Version: "v1beta1",
+			ID:      "abc",
+			InitContainers: []api.Container{{
+				Name:           "abc",
+				Image:          "image",
+				ReadinessProbe: &api.LivenessProbe{Type: "exec", Exec: &api.ExecAction{Command: []string{"true"}}},
+			}},
+		},
+		"subpath of a secret volume": {
+			Version: "v1beta1",
+			ID:      "abc",
+			Volumes: []api.Volume{{Name: "creds", Source: &api.VolumeSource{Secret: &api.SecretSource{SecretID: "foo"}}}},
+			Containers: []api.Container{{
+				Name:         "abc",
+				Image:        "image",
+				VolumeMounts: []api.VolumeMount{{Name: "creds", MountPath: "/etc/creds", SubPath: "foo"}},
+			}},
+		},
+		"subpath of a downward API volume": {
+			Version: "v1beta1",
+			ID:      "abc",
+			Volumes: []api.Volume{{Name: "info", Source: &api.VolumeSource{DownwardAPI: &api.DownwardAPIVolumeSource{}}}},
+			InitContainers: []api.Container{{
+				Name:         "abc",
+				Image:        "image",
+				VolumeMounts: []api.VolumeMount{{Name: "info", MountPath: "/etc/info", SubPath: "foo"}},
+			}},
+		},
+		"unsupported dns policy": {Version: "v1beta1", ID: "abc", DNSPolicy: "ClusterOnly"},
+		"dns config without the None policy": {
+			Version:   "v1beta1",
+			ID:        "abc",
+			DNSConfig: &api.DNSConfig{Nameservers: []string{"10.0.0.10"}},
+		},
+		"None dns policy without a config": {Version: "v1beta1", ID: "abc", DNSPolicy: api.DNSNone},
+		"invalid nameserver": {
+			Version:   "v1beta1",
+			ID:        "abc",
+			DNSPolicy: api.DNSNone,
+			DNSConfig: &api.DNSConfig{Nameservers: []string{"dns.example.com"}},
+		},
+		"invalid image pull secret": {
+			Version:          "v1beta1",
+			ID:               "abc",
+			ImagePullSecrets: []string{"registry_creds"},
+		},
 	}
 	for k, v := range errorCases {
 		if errs := ValidateManifest(&v); len(errs) == 0 {
@@ -364,6 +557,43 @@ func TestValidateManifest(t *testing.T) {
 	}
 }
 
+func TestValidateHostNetwork(t *testing.T) {
+	capabilities.SetForTests(capabilities.Capabilities{
+		AllowHostNetwork: true,
+	})
+	manifest := api.ContainerManifest{
+		Version:     "v1beta1",
+		ID:          "abc",
+		HostNetwork: true,
+		Containers: []api.Container{{
+			Name:  "abc",
+			Image: "image",
+			Ports: []api.Port{{ContainerPort: 80}, {ContainerPort: 443, HostPort: 443}},
+		}},
+	}
+	if errs := ValidateManifest(&manifest); len(errs) != 0 {
+		t.Errorf("expected success: %v", errs)
+	}
+	if port := manifest.Containers[0].Ports[0]; port.HostPort != 80 {
+		t.Errorf("expected the host port to default to the container port, got %d", port.HostPort)
+	}
+
+	manifest.Containers[0].Ports[1].HostPort = 8443
+	errs := ValidateManifest(&manifest)
+	if len(errs) != 1 || errs[0].(errors.ValidationError).Field != "containers[0].ports[1].hostPort" {
+		t.Errorf("expected a host port error, got %v", errs)
+	}
+
+	capabilities.SetForTests(capabilities.Capabilities{
+		AllowHostNetwork: false,
+	})
+	manifest.Containers[0].Ports[1].HostPort = 443
+	errs = ValidateManifest(&manifest)
+	if len(errs) != 1 || errs[0].(errors.ValidationError).Field != "hostNetwork" {
+		t.Errorf("expected host networking to be disallowed, got %v", errs)
+	}
+}
+
 func TestValidatePod(t *testing.T) {
 	errs := ValidatePod(&api.Pod{
 		JSONBase: api.JSONBase{ID: "foo"},
@@ -615,3 +845,32 @@ func TestValidateReplicationController(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateSecret(t *testing.T) {
+	value := base64.StdEncoding.EncodeToString([]byte("secret"))
+	successCases := []api.Secret{
+		{JSONBase: api.JSONBase{ID: "abc"}},
+		{JSONBase: api.JSONBase{ID: "abc.def"}, Data: map[string]string{"password": value, ".dockercfg": value, "my_key-1.txt": value}},
+		{JSONBase: api.JSONBase{ID: "big"}, Data: map[string]string{"big": base64.StdEncoding.EncodeToString(make([]byte, api.MaxSecretSize))}},
+	}
+	for _, successCase := range successCases {
+		if errs := ValidateSecret(&successCase); len(errs) != 0 {
+			t.Errorf("expected success: %v", errs)
+		}
+	}
+
+	errorCases := map[string]api.Secret{
+		"missing id":       {Data: map[string]string{"password": value}},
+		"invalid id":       {JSONBase: api.JSONBase{ID: "a b"}},
+		"key with a slash": {JSONBase: api.JSONBase{ID: "abc"}, Data: map[string]string{"../password": value}},
+		"dot key":          {JSONBase: api.JSONBase{ID: "abc"}, Data: map[string]string{"..": value}},
+		"empty key":        {JSONBase: api.JSONBase{ID: "abc"}, Data: map[string]string{"": value}},
+		"value not base64": {JSONBase: api.JSONBase{ID: "abc"}, Data: map[string]string{"password": "not base64!"}},
+		"data too large":   {JSONBase: api.JSONBase{ID: "abc"}, Data: map[string]string{"a": base64.StdEncoding.EncodeToString(make([]byte, api.MaxSecretSize/2+1)), "b": base64.StdEncoding.EncodeToString(make([]byte, api.MaxSecretSize/2))}},
+	}
+	for k, v := range errorCases {
+		if errs := ValidateSecret(&v); len(errs) == 0 {
+			t.Errorf("expected failure for %s", k)
+		}
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"time"
 
 	"code.google.com/p/go.net/html"
 	"code.google.com/p/go.net/html/atom"
@@ -46,6 +47,10 @@ func handleProxyMinion(w http.ResponseWriter, req *http.Request) {
 	//
 	// To query logs on a minion, path string can be:
 	// ${minion}/logs/
+	//
+	// To query the logs of a container, path string can be:
+	// ${minion}/containerLogs/<podid>/<containerName>?follow=1&previous=1&
+	//     sinceSeconds=<seconds>&sinceTime=<RFC3339>&timestamps=1&limitBytes=<bytes>&tail=<lines>
 	parts := strings.SplitN(path, "/", 2)
 	if len(parts) != 2 {
 		badGatewayError(w, req)
@@ -71,6 +76,8 @@ func handleProxyMinion(w http.ResponseWriter, req *http.Request) {
 
 	proxy := httputil.NewSingleHostReverseProxy(minionURL)
 	proxy.Transport = &minionTransport{}
+	// Flush periodically, so that followed container logs stream through.
+	proxy.FlushInterval = 200 * time.Millisecond
 	proxy.ServeHTTP(w, newReq)
 }
 
@@ -24,6 +24,21 @@ import (
 // For now these are global.  Eventually they may be per-user
 type Capabilities struct {
 	AllowPrivileged bool
+	// Pods may use the network namespace of the node.
+	AllowHostNetwork bool
+	// The Linux capabilities containers may add to the default set of the container runtime,
+	// e.g. "NET_ADMIN". "ALL" allows any capability.
+	AllowedCapabilities []string
+}
+
+// AllowsCapability returns true if containers may add a Linux capability.
+func (c Capabilities) AllowsCapability(capability string) bool {
+	for _, allowed := range c.AllowedCapabilities {
+		if allowed == capability || allowed == "ALL" {
+			return true
+		}
+	}
+	return false
 }
 
 var once sync.Once
@@ -46,7 +61,8 @@ func SetForTests(c Capabilities) {
 func Get() Capabilities {
 	if capabilities == nil {
 		Initialize(Capabilities{
-			AllowPrivileged: false,
+			AllowPrivileged:  false,
+			AllowHostNetwork: false,
 		})
 	}
 	return *capabilities
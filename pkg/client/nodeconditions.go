@@ -0,0 +1,68 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// ErrNodeConditionsNotAvailable may be returned when the conditions of a minion are not known.
+var ErrNodeConditionsNotAvailable = errors.New("no node conditions available")
+
+// NodeConditionGetter is an interface for things that can get the conditions of a minion.
+// Injectable for easy testing.
+type NodeConditionGetter interface {
+	// GetNodeConditions returns the conditions which currently hold on host.
+	GetNodeConditions(host string) ([]api.NodeCondition, error)
+}
+
+// HTTPNodeConditionGetter is the default implementation of NodeConditionGetter, accesses the kubelet over HTTP.
+type HTTPNodeConditionGetter struct {
+	Client *http.Client
+	Port   uint
+}
+
+// GetNodeConditions gets the conditions of the specified minion.
+func (c *HTTPNodeConditionGetter) GetNodeConditions(host string) ([]api.NodeCondition, error) {
+	response, err := c.Client.Get(fmt.Sprintf(
+		"http://%s/nodeConditions",
+		net.JoinHostPort(host, strconv.FormatUint(uint64(c.Port), 10))))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status getting the conditions of %s: %d", host, response.StatusCode)
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	var conditions []api.NodeCondition
+	if err := json.Unmarshal(body, &conditions); err != nil {
+		return nil, err
+	}
+	return conditions, nil
+}
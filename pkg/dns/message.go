@@ -0,0 +1,211 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+)
+
+// The subset of the DNS protocol (RFC 1035, RFC 2782) the server implements.
+const (
+	typeA   uint16 = 1
+	typeSRV uint16 = 33
+	typeANY uint16 = 255
+
+	classIN uint16 = 1
+
+	rcodeSuccess        = 0
+	rcodeFormatError    = 1
+	rcodeNameError      = 3
+	rcodeNotImplemented = 4
+	rcodeRefused        = 5
+
+	headerLen = 12
+	// The largest message sent over UDP without EDNS.
+	maxUDPMessageLen = 512
+)
+
+var errMalformedMessage = errors.New("malformed DNS message")
+
+type header struct {
+	id      uint16
+	flags   uint16
+	qdCount uint16
+	anCount uint16
+	nsCount uint16
+	arCount uint16
+}
+
+func (h *header) opcode() int {
+	return int(h.flags>>11) & 0xF
+}
+
+type question struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+}
+
+// srvData is the data of a SRV record.
+type srvData struct {
+	priority uint16
+	weight   uint16
+	port     uint16
+	target   string
+}
+
+// resourceRecord is an A or a SRV record.
+type resourceRecord struct {
+	name   string
+	rrtype uint16
+	ttl    uint32
+	ip     net.IP
+	srv    *srvData
+}
+
+// parseQuery parses the header and the first question of a query.
+func parseQuery(msg []byte) (header, question, error) {
+	var h header
+	var q question
+	if len(msg) < headerLen {
+		return h, q, errMalformedMessage
+	}
+	h.id = binary.BigEndian.Uint16(msg[0:])
+	h.flags = binary.BigEndian.Uint16(msg[2:])
+	h.qdCount = binary.BigEndian.Uint16(msg[4:])
+	h.anCount = binary.BigEndian.Uint16(msg[6:])
+	h.nsCount = binary.BigEndian.Uint16(msg[8:])
+	h.arCount = binary.BigEndian.Uint16(msg[10:])
+	if h.qdCount == 0 {
+		return h, q, errMalformedMessage
+	}
+	name, offset, err := parseName(msg, headerLen)
+	if err != nil {
+		return h, q, err
+	}
+	if offset+4 > len(msg) {
+		return h, q, errMalformedMessage
+	}
+	q.name = name
+	q.qtype = binary.BigEndian.Uint16(msg[offset:])
+	q.qclass = binary.BigEndian.Uint16(msg[offset+2:])
+	return h, q, nil
+}
+
+// parseName parses an uncompressed domain name at offset, and returns it lowercased with a
+// trailing dot, along with the offset following it.
+func parseName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	for {
+		if offset >= len(msg) {
+			return "", 0, errMalformedMessage
+		}
+		length := int(msg[offset])
+		offset++
+		if length == 0 {
+			break
+		}
+		// Compression pointers and extended labels aren't used in questions.
+		if length > 63 || offset+length > len(msg) {
+			return "", 0, errMalformedMessage
+		}
+		labels = append(labels, strings.ToLower(string(msg[offset:offset+length])))
+		offset += length
+	}
+	return strings.Join(labels, ".") + ".", offset, nil
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendName(b []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+func appendRecord(b []byte, rr *resourceRecord) []byte {
+	b = appendName(b, rr.name)
+	b = appendUint16(b, rr.rrtype)
+	b = appendUint16(b, classIN)
+	b = appendUint32(b, rr.ttl)
+	var data []byte
+	switch rr.rrtype {
+	case typeA:
+		data = rr.ip.To4()
+	case typeSRV:
+		data = appendUint16(data, rr.srv.priority)
+		data = appendUint16(data, rr.srv.weight)
+		data = appendUint16(data, rr.srv.port)
+		data = appendName(data, rr.srv.target)
+	}
+	b = appendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+// buildResponse builds the response to a query. The additional records are left out if the
+// response doesn't fit in maxLen bytes, and the response is truncated if it still doesn't.
+func buildResponse(query header, q *question, rcode int, answers, additional []resourceRecord, maxLen int) []byte {
+	// QR and AA set, the opcode and RD copied from the query.
+	flags := uint16(1<<15) | query.flags&(0xF<<11) | 1<<10 | query.flags&(1<<8) | uint16(rcode)
+	build := func(flags uint16, answers, additional []resourceRecord) []byte {
+		b := make([]byte, 0, maxLen)
+		b = appendUint16(b, query.id)
+		b = appendUint16(b, flags)
+		qdCount := uint16(0)
+		if q != nil {
+			qdCount = 1
+		}
+		b = appendUint16(b, qdCount)
+		b = appendUint16(b, uint16(len(answers)))
+		b = appendUint16(b, 0)
+		b = appendUint16(b, uint16(len(additional)))
+		if q != nil {
+			b = appendName(b, q.name)
+			b = appendUint16(b, q.qtype)
+			b = appendUint16(b, q.qclass)
+		}
+		for i := range answers {
+			b = appendRecord(b, &answers[i])
+		}
+		for i := range additional {
+			b = appendRecord(b, &additional[i])
+		}
+		return b
+	}
+	if msg := build(flags, answers, additional); len(msg) <= maxLen {
+		return msg
+	}
+	if msg := build(flags, answers, nil); len(msg) <= maxLen {
+		return msg
+	}
+	// Set TC, so that the client knows the answers were left out.
+	return build(flags|1<<9, nil, nil)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"encoding/binary"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func newQuery(id uint16, name string, qtype uint16) []byte {
+	b := appendUint16(nil, id)
+	b = appendUint16(b, 1<<8)
+	b = appendUint16(b, 1)
+	b = appendUint16(b, 0)
+	b = appendUint16(b, 0)
+	b = appendUint16(b, 0)
+	b = appendName(b, name)
+	b = appendUint16(b, qtype)
+	return appendUint16(b, classIN)
+}
+
+// parseResponse parses a response built by buildResponse, which doesn't compress names.
+func parseResponse(t *testing.T, msg []byte) (header, []resourceRecord) {
+	h, _, err := parseQuery(msg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, offset, _ := parseName(msg, headerLen)
+	offset += 4
+	var records []resourceRecord
+	for i := 0; i < int(h.anCount)+int(h.arCount); i++ {
+		var rr resourceRecord
+		rr.name, offset, err = parseName(msg, offset)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		rr.rrtype = binary.BigEndian.Uint16(msg[offset:])
+		rr.ttl = binary.BigEndian.Uint32(msg[offset+4:])
+		length := int(binary.BigEndian.Uint16(msg[offset+8:]))
+		offset += 10
+		data := msg[offset : offset+length]
+		switch rr.rrtype {
+		case typeA:
+			rr.ip = net.IP(data)
+		case typeSRV:
+			target, _, err := parseName(data, 6)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			rr.srv = &srvData{
+				priority: binary.BigEndian.Uint16(data),
+				weight:   binary.BigEndian.Uint16(data[2:]),
+				port:     binary.BigEndian.Uint16(data[4:]),
+				target:   target,
+			}
+		}
+		offset += length
+		records = append(records, rr)
+	}
+	if offset != len(msg) {
+		t.Errorf("Unexpected trailing data: %v", msg[offset:])
+	}
+	return h, records
+}
+
+func TestParseQuery(t *testing.T) {
+	h, q, err := parseQuery(newQuery(42, "Foo.Cluster.Local.", typeSRV))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if h.id != 42 || h.qdCount != 1 || h.opcode() != 0 {
+		t.Errorf("Unexpected header: %#v", h)
+	}
+	expected := question{name: "foo.cluster.local.", qtype: typeSRV, qclass: classIN}
+	if q != expected {
+		t.Errorf("Expected %#v, got %#v", expected, q)
+	}
+}
+
+func TestParseQueryMalformed(t *testing.T) {
+	query := newQuery(1, "foo.local.", typeA)
+	noQuestion := newQuery(1, "foo.local.", typeA)[:headerLen]
+	noQuestion[5] = 0
+	compressed := append(newQuery(1, "", typeA)[:headerLen], 0xC0, 0x0C, 0, 1, 0, 1)
+	for _, msg := range [][]byte{
+		query[:headerLen-1],
+		query[:headerLen+5],
+		query[:len(query)-2],
+		noQuestion,
+		compressed,
+	} {
+		if _, _, err := parseQuery(msg); err != errMalformedMessage {
+			t.Errorf("Expected an error for %v, got %v", msg, err)
+		}
+	}
+}
+
+func TestBuildResponse(t *testing.T) {
+	query, q, _ := parseQuery(newQuery(7, "_http._tcp.foo.local.", typeSRV))
+	answers := []resourceRecord{{
+		name:   "_http._tcp.foo.local.",
+		rrtype: typeSRV,
+		ttl:    30,
+		srv:    &srvData{priority: 0, weight: 10, port: 8080, target: "foo.local."},
+	}}
+	additional := []resourceRecord{{name: "foo.local.", rrtype: typeA, ttl: 30, ip: net.IPv4(10, 0, 0, 1).To4()}}
+	h, records := parseResponse(t, buildResponse(query, &q, rcodeSuccess, answers, additional, maxUDPMessageLen))
+	if h.id != 7 || h.flags != 1<<15|1<<10|1<<8 || h.anCount != 1 || h.arCount != 1 {
+		t.Errorf("Unexpected header: %#v", h)
+	}
+	if expected := append(answers, additional...); !reflect.DeepEqual(expected, records) {
+		t.Errorf("Expected %#v, got %#v", expected, records)
+	}
+}
+
+func TestBuildResponseTruncates(t *testing.T) {
+	query, q, _ := parseQuery(newQuery(7, "foo.local.", typeA))
+	answers := []resourceRecord{}
+	for i := 0; i < 40; i++ {
+		answers = append(answers, resourceRecord{name: "foo.local.", rrtype: typeA, ttl: 30, ip: net.IPv4(10, 0, 0, byte(i)).To4()})
+	}
+	msg := buildResponse(query, &q, rcodeSuccess, answers[:10], answers, maxUDPMessageLen)
+	if h, records := parseResponse(t, msg); h.flags&(1<<9) != 0 || len(records) != 10 {
+		t.Errorf("Expected only the answers, got %#v %#v", h, records)
+	}
+	msg = buildResponse(query, &q, rcodeSuccess, answers, nil, maxUDPMessageLen)
+	if h, records := parseResponse(t, msg); h.flags&(1<<9) == 0 || len(records) != 0 {
+		t.Errorf("Expected a truncated response, got %#v %#v", h, records)
+	}
+}
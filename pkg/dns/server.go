@@ -0,0 +1,303 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/proxy/config"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/golang/glog"
+)
+
+// The TTL of the records served, in seconds. Services change, so this is kept short.
+const defaultTTL = 30
+
+// Server answers DNS queries for the services in a cluster domain:
+//
+//	<service>.<domain>                       A records for the service
+//	_<port>._<protocol>.<service>.<domain>   SRV records for a named container port of the service
+//	<a-b-c-d>.<service>.<domain>             an A record for the endpoint with IP a.b.c.d
+//
+// If the server has a proxy address, services resolve to it, and SRV records point to the
+// service port on it. Otherwise the services have no virtual IP, and resolve to their endpoints.
+type Server struct {
+	domain  string
+	proxyIP net.IP
+	ttl     uint32
+
+	lock      sync.RWMutex
+	services  map[string]api.Service
+	endpoints map[string][]endpoint
+}
+
+type endpoint struct {
+	ip   net.IP
+	port int
+}
+
+// NewServer creates a Server for the names in domain. proxyIP may be nil, if the services don't
+// have a virtual IP.
+func NewServer(domain string, proxyIP net.IP) *Server {
+	return &Server{
+		domain:    strings.ToLower(strings.Trim(domain, ".")) + ".",
+		proxyIP:   proxyIP.To4(),
+		ttl:       defaultTTL,
+		services:  map[string]api.Service{},
+		endpoints: map[string][]endpoint{},
+	}
+}
+
+// Register wires the server to handle changes to services and endpoints.
+func (s *Server) Register(services *config.ServiceConfig, endpoints *config.EndpointsConfig) {
+	services.RegisterHandler(serviceHandler{s})
+	endpoints.RegisterHandler(endpointsHandler{s})
+}
+
+type serviceHandler struct {
+	server *Server
+}
+
+// OnUpdate implements config.ServiceConfigHandler.
+func (h serviceHandler) OnUpdate(services []api.Service) {
+	h.server.updateServices(services)
+}
+
+type endpointsHandler struct {
+	server *Server
+}
+
+// OnUpdate implements config.EndpointsConfigHandler.
+func (h endpointsHandler) OnUpdate(endpoints []api.Endpoints) {
+	h.server.updateEndpoints(endpoints)
+}
+
+func (s *Server) updateServices(services []api.Service) {
+	byName := map[string]api.Service{}
+	for _, service := range services {
+		byName[strings.ToLower(service.ID)] = service
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.services = byName
+}
+
+func (s *Server) updateEndpoints(endpoints []api.Endpoints) {
+	byName := map[string][]endpoint{}
+	for _, e := range endpoints {
+		var parsed []endpoint
+		for _, hostPort := range e.Endpoints {
+			host, port, err := net.SplitHostPort(hostPort)
+			if err != nil {
+				glog.Errorf("Invalid endpoint %q of service %q: %v", hostPort, e.ID, err)
+				continue
+			}
+			ip := net.ParseIP(host).To4()
+			portNum, err := strconv.Atoi(port)
+			if ip == nil || err != nil {
+				glog.Errorf("Invalid endpoint %q of service %q", hostPort, e.ID)
+				continue
+			}
+			parsed = append(parsed, endpoint{ip, portNum})
+		}
+		byName[strings.ToLower(e.ID)] = parsed
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.endpoints = byName
+}
+
+// ListenAndServe listens for queries on the UDP address addr, and answers them.
+func (s *Server) ListenAndServe(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return s.Serve(conn)
+}
+
+// Serve answers the queries read from conn, until reading fails.
+func (s *Server) Serve(conn net.PacketConn) error {
+	buf := make([]byte, maxUDPMessageLen)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		response := s.handle(buf[:n])
+		if response == nil {
+			continue
+		}
+		if _, err := conn.WriteTo(response, addr); err != nil {
+			glog.Errorf("Failed to answer DNS query from %v: %v", addr, err)
+		}
+	}
+}
+
+// handle returns the response to a query, or nil if it shouldn't be answered.
+func (s *Server) handle(msg []byte) []byte {
+	h, q, err := parseQuery(msg)
+	if err != nil {
+		if len(msg) < headerLen || h.flags&(1<<15) != 0 {
+			return nil
+		}
+		return buildResponse(h, nil, rcodeFormatError, nil, nil, maxUDPMessageLen)
+	}
+	if h.flags&(1<<15) != 0 {
+		// Don't answer responses.
+		return nil
+	}
+	if h.opcode() != 0 {
+		return buildResponse(h, &q, rcodeNotImplemented, nil, nil, maxUDPMessageLen)
+	}
+	if q.qclass != classIN && q.qclass != typeANY {
+		return buildResponse(h, &q, rcodeRefused, nil, nil, maxUDPMessageLen)
+	}
+	rcode, answers, additional := s.answer(q.name, q.qtype)
+	return buildResponse(h, &q, rcode, answers, additional, maxUDPMessageLen)
+}
+
+// answer returns the response code, answers and additional records for a query of name, which
+// must be lowercase and fully qualified.
+func (s *Server) answer(name string, qtype uint16) (int, []resourceRecord, []resourceRecord) {
+	if name != s.domain && !strings.HasSuffix(name, "."+s.domain) {
+		return rcodeRefused, nil, nil
+	}
+	if name == s.domain {
+		return rcodeSuccess, nil, nil
+	}
+	labels := strings.Split(strings.TrimSuffix(name, "."+s.domain), ".")
+	serviceName := labels[len(labels)-1]
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	service, ok := s.services[serviceName]
+	if !ok {
+		return rcodeNameError, nil, nil
+	}
+	endpoints := s.endpoints[serviceName]
+	serviceDomain := serviceName + "." + s.domain
+
+	switch len(labels) {
+	case 1:
+		if qtype != typeA && qtype != typeANY {
+			return rcodeSuccess, nil, nil
+		}
+		return rcodeSuccess, s.serviceRecords(name, endpoints), nil
+	case 2:
+		if s.proxyIP != nil {
+			return rcodeNameError, nil, nil
+		}
+		for _, e := range endpoints {
+			if endpointLabel(e.ip) != labels[0] {
+				continue
+			}
+			if qtype != typeA && qtype != typeANY {
+				return rcodeSuccess, nil, nil
+			}
+			return rcodeSuccess, []resourceRecord{s.aRecord(name, e.ip)}, nil
+		}
+		return rcodeNameError, nil, nil
+	case 3:
+		if !matchesPort(&service, labels[0], labels[1]) {
+			return rcodeNameError, nil, nil
+		}
+		if qtype != typeSRV && qtype != typeANY {
+			return rcodeSuccess, nil, nil
+		}
+		answers, additional := s.srvRecords(name, &service, serviceDomain, endpoints)
+		return rcodeSuccess, answers, additional
+	}
+	return rcodeNameError, nil, nil
+}
+
+// serviceRecords returns the A records of a service: the proxy address, or the address of each
+// endpoint of the service.
+func (s *Server) serviceRecords(name string, endpoints []endpoint) []resourceRecord {
+	if s.proxyIP != nil {
+		return []resourceRecord{s.aRecord(name, s.proxyIP)}
+	}
+	records := []resourceRecord{}
+	seen := util.StringSet{}
+	for _, e := range endpoints {
+		if seen.Has(e.ip.String()) {
+			continue
+		}
+		seen.Insert(e.ip.String())
+		records = append(records, s.aRecord(name, e.ip))
+	}
+	return records
+}
+
+// srvRecords returns the SRV records for the named port of a service, and the A records of their
+// targets.
+func (s *Server) srvRecords(name string, service *api.Service, serviceDomain string, endpoints []endpoint) ([]resourceRecord, []resourceRecord) {
+	if s.proxyIP != nil {
+		answer := s.srvRecord(name, service.Port, serviceDomain)
+		return []resourceRecord{answer}, []resourceRecord{s.aRecord(serviceDomain, s.proxyIP)}
+	}
+	answers := []resourceRecord{}
+	additional := []resourceRecord{}
+	seen := util.StringSet{}
+	for _, e := range endpoints {
+		target := endpointLabel(e.ip) + "." + serviceDomain
+		answers = append(answers, s.srvRecord(name, e.port, target))
+		if !seen.Has(target) {
+			seen.Insert(target)
+			additional = append(additional, s.aRecord(target, e.ip))
+		}
+	}
+	return answers, additional
+}
+
+func (s *Server) aRecord(name string, ip net.IP) resourceRecord {
+	return resourceRecord{name: name, rrtype: typeA, ttl: s.ttl, ip: ip}
+}
+
+func (s *Server) srvRecord(name string, port int, target string) resourceRecord {
+	return resourceRecord{
+		name:   name,
+		rrtype: typeSRV,
+		ttl:    s.ttl,
+		srv:    &srvData{priority: 0, weight: 10, port: uint16(port), target: target},
+	}
+}
+
+// matchesPort returns true if the labels of a SRV query name the container port of a service.
+func matchesPort(service *api.Service, portLabel, protocolLabel string) bool {
+	if service.ContainerPort.Kind != util.IntstrString || service.ContainerPort.StrVal == "" {
+		return false
+	}
+	protocol := service.Protocol
+	if protocol == "" {
+		protocol = "TCP"
+	}
+	return portLabel == "_"+strings.ToLower(service.ContainerPort.StrVal) &&
+		protocolLabel == "_"+strings.ToLower(protocol)
+}
+
+// endpointLabel returns the DNS label of an endpoint with an IPv4 address, e.g. 10-0-0-1.
+func endpointLabel(ip net.IP) string {
+	ip = ip.To4()
+	return fmt.Sprintf("%d-%d-%d-%d", ip[0], ip[1], ip[2], ip[3])
+}
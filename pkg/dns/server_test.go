@@ -0,0 +1,289 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dns
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/proxy/config"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+// fakeWatcher implements config.Watcher with a separate watch for services and endpoints.
+type fakeWatcher struct {
+	services       api.ServiceList
+	endpoints      api.EndpointsList
+	servicesWatch  *watch.FakeWatcher
+	endpointsWatch *watch.FakeWatcher
+}
+
+func (f *fakeWatcher) ListServices(label labels.Selector) (*api.ServiceList, error) {
+	return &f.services, nil
+}
+
+func (f *fakeWatcher) ListEndpoints(label labels.Selector) (*api.EndpointsList, error) {
+	return &f.endpoints, nil
+}
+
+func (f *fakeWatcher) WatchServices(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return f.servicesWatch, nil
+}
+
+func (f *fakeWatcher) WatchEndpoints(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return f.endpointsWatch, nil
+}
+
+func newTestServer(proxyIP net.IP) *Server {
+	s := NewServer("cluster.local", proxyIP)
+	s.updateServices([]api.Service{
+		{
+			JSONBase:      api.JSONBase{ID: "web"},
+			Port:          80,
+			ContainerPort: util.NewIntOrStringFromString("http"),
+		},
+		{
+			JSONBase:      api.JSONBase{ID: "dns"},
+			Port:          53,
+			Protocol:      "UDP",
+			ContainerPort: util.NewIntOrStringFromString("dns"),
+		},
+		{
+			JSONBase:      api.JSONBase{ID: "db"},
+			Port:          5432,
+			ContainerPort: util.NewIntOrStringFromInt(5432),
+		},
+	})
+	s.updateEndpoints([]api.Endpoints{
+		{
+			JSONBase:  api.JSONBase{ID: "web"},
+			Endpoints: []string{"10.0.0.1:8080", "10.0.0.2:8080", "10.0.0.2:8081", "invalid"},
+		},
+		{
+			JSONBase:  api.JSONBase{ID: "dns"},
+			Endpoints: []string{"10.0.0.3:5353"},
+		},
+	})
+	return s
+}
+
+func aRecords(name string, ips ...string) []resourceRecord {
+	records := []resourceRecord{}
+	for _, ip := range ips {
+		records = append(records, resourceRecord{name: name, rrtype: typeA, ttl: defaultTTL, ip: net.ParseIP(ip).To4()})
+	}
+	return records
+}
+
+func srvRecord(name string, port uint16, target string) resourceRecord {
+	return resourceRecord{
+		name:   name,
+		rrtype: typeSRV,
+		ttl:    defaultTTL,
+		srv:    &srvData{priority: 0, weight: 10, port: port, target: target},
+	}
+}
+
+type answerTest struct {
+	name       string
+	qtype      uint16
+	rcode      int
+	answers    []resourceRecord
+	additional []resourceRecord
+}
+
+func checkAnswers(t *testing.T, s *Server, tests []answerTest) {
+	for _, test := range tests {
+		rcode, answers, additional := s.answer(test.name, test.qtype)
+		if rcode != test.rcode {
+			t.Errorf("%s: expected rcode %d, got %d", test.name, test.rcode, rcode)
+		}
+		if !reflect.DeepEqual(answers, test.answers) {
+			t.Errorf("%s: expected answers %#v, got %#v", test.name, test.answers, answers)
+		}
+		if !reflect.DeepEqual(additional, test.additional) {
+			t.Errorf("%s: expected additional records %#v, got %#v", test.name, test.additional, additional)
+		}
+	}
+}
+
+func TestAnswerWithProxy(t *testing.T) {
+	s := newTestServer(net.ParseIP("10.240.0.1"))
+	checkAnswers(t, s, []answerTest{
+		{name: "web.cluster.local.", qtype: typeA, answers: aRecords("web.cluster.local.", "10.240.0.1")},
+		{name: "db.cluster.local.", qtype: typeANY, answers: aRecords("db.cluster.local.", "10.240.0.1")},
+		{name: "web.cluster.local.", qtype: typeSRV},
+		{
+			name:       "_http._tcp.web.cluster.local.",
+			qtype:      typeSRV,
+			answers:    []resourceRecord{srvRecord("_http._tcp.web.cluster.local.", 80, "web.cluster.local.")},
+			additional: aRecords("web.cluster.local.", "10.240.0.1"),
+		},
+		{
+			name:       "_dns._udp.dns.cluster.local.",
+			qtype:      typeSRV,
+			answers:    []resourceRecord{srvRecord("_dns._udp.dns.cluster.local.", 53, "dns.cluster.local.")},
+			additional: aRecords("dns.cluster.local.", "10.240.0.1"),
+		},
+		{name: "_http._tcp.web.cluster.local.", qtype: typeA},
+		{name: "_dns._tcp.dns.cluster.local.", qtype: typeSRV, rcode: rcodeNameError},
+		{name: "_db._tcp.db.cluster.local.", qtype: typeSRV, rcode: rcodeNameError},
+		{name: "10-0-0-1.web.cluster.local.", qtype: typeA, rcode: rcodeNameError},
+		{name: "missing.cluster.local.", qtype: typeA, rcode: rcodeNameError},
+		{name: "cluster.local.", qtype: typeA},
+		{name: "web.example.com.", qtype: typeA, rcode: rcodeRefused},
+		{name: "web.notcluster.local.", qtype: typeA, rcode: rcodeRefused},
+	})
+}
+
+func TestAnswerWithoutVirtualIP(t *testing.T) {
+	s := newTestServer(nil)
+	checkAnswers(t, s, []answerTest{
+		{name: "web.cluster.local.", qtype: typeA, answers: aRecords("web.cluster.local.", "10.0.0.1", "10.0.0.2")},
+		{name: "db.cluster.local.", qtype: typeA, answers: []resourceRecord{}},
+		{name: "10-0-0-2.web.cluster.local.", qtype: typeA, answers: aRecords("10-0-0-2.web.cluster.local.", "10.0.0.2")},
+		{name: "10-0-0-2.web.cluster.local.", qtype: typeSRV},
+		{name: "10-0-0-3.web.cluster.local.", qtype: typeA, rcode: rcodeNameError},
+		{
+			name:  "_http._tcp.web.cluster.local.",
+			qtype: typeSRV,
+			answers: []resourceRecord{
+				srvRecord("_http._tcp.web.cluster.local.", 8080, "10-0-0-1.web.cluster.local."),
+				srvRecord("_http._tcp.web.cluster.local.", 8080, "10-0-0-2.web.cluster.local."),
+				srvRecord("_http._tcp.web.cluster.local.", 8081, "10-0-0-2.web.cluster.local."),
+			},
+			additional: append(aRecords("10-0-0-1.web.cluster.local.", "10.0.0.1"), aRecords("10-0-0-2.web.cluster.local.", "10.0.0.2")...),
+		},
+	})
+}
+
+func TestHandle(t *testing.T) {
+	s := newTestServer(net.ParseIP("10.240.0.1"))
+	h, records := parseResponse(t, s.handle(newQuery(3, "WEB.cluster.local.", typeA)))
+	if h.id != 3 || h.flags&0xF != rcodeSuccess || h.anCount != 1 {
+		t.Errorf("Unexpected header: %#v", h)
+	}
+	if expected := aRecords("web.cluster.local.", "10.240.0.1"); !reflect.DeepEqual(expected, records) {
+		t.Errorf("Expected %#v, got %#v", expected, records)
+	}
+
+	notify := newQuery(4, "web.cluster.local.", typeA)
+	notify[2] |= 4 << 3
+	if h, _ := parseResponse(t, s.handle(notify)); h.flags&0xF != rcodeNotImplemented {
+		t.Errorf("Expected NOTIMP, got %#v", h)
+	}
+
+	query := newQuery(5, "web.cluster.local.", typeA)
+	if response := s.handle(query[:headerLen+3]); len(response) != headerLen || response[3]&0xF != rcodeFormatError {
+		t.Errorf("Expected FORMERR, got %v", response)
+	}
+	if response := s.handle(query[:headerLen-1]); response != nil {
+		t.Errorf("Expected no response, got %v", response)
+	}
+	query[2] |= 1 << 7
+	if response := s.handle(query); response != nil {
+		t.Errorf("Expected no response to a response, got %v", response)
+	}
+}
+
+func waitForAnswer(t *testing.T, s *Server, name string, expected []resourceRecord) {
+	var answers []resourceRecord
+	for i := 0; i < 100; i++ {
+		_, answers, _ = s.answer(name, typeA)
+		if reflect.DeepEqual(answers, expected) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("%s: expected %#v, got %#v", name, expected, answers)
+}
+
+func TestServerFollowsSourceAPI(t *testing.T) {
+	watcher := &fakeWatcher{
+		services: api.ServiceList{
+			JSONBase: api.JSONBase{ResourceVersion: 1},
+			Items:    []api.Service{{JSONBase: api.JSONBase{ID: "web"}, Port: 80}},
+		},
+		endpoints: api.EndpointsList{
+			JSONBase: api.JSONBase{ResourceVersion: 1},
+			Items:    []api.Endpoints{{JSONBase: api.JSONBase{ID: "web"}, Endpoints: []string{"10.0.0.1:8080"}}},
+		},
+		servicesWatch:  watch.NewFake(),
+		endpointsWatch: watch.NewFake(),
+	}
+	serviceConfig := config.NewServiceConfig()
+	endpointsConfig := config.NewEndpointsConfig()
+	s := NewServer("cluster.local", nil)
+	s.Register(serviceConfig, endpointsConfig)
+	config.NewSourceAPI(watcher, time.Minute, serviceConfig.Channel("api"), endpointsConfig.Channel("api"))
+
+	waitForAnswer(t, s, "web.cluster.local.", aRecords("web.cluster.local.", "10.0.0.1"))
+
+	watcher.servicesWatch.Add(&api.Service{JSONBase: api.JSONBase{ID: "db", ResourceVersion: 2}, Port: 5432})
+	watcher.endpointsWatch.Add(&api.Endpoints{JSONBase: api.JSONBase{ID: "db", ResourceVersion: 2}, Endpoints: []string{"10.0.0.5:5432"}})
+	waitForAnswer(t, s, "db.cluster.local.", aRecords("db.cluster.local.", "10.0.0.5"))
+
+	watcher.endpointsWatch.Modify(&api.Endpoints{JSONBase: api.JSONBase{ID: "web", ResourceVersion: 3}, Endpoints: []string{"10.0.0.2:8080"}})
+	waitForAnswer(t, s, "web.cluster.local.", aRecords("web.cluster.local.", "10.0.0.2"))
+
+	watcher.servicesWatch.Delete(&api.Service{JSONBase: api.JSONBase{ID: "web", ResourceVersion: 4}})
+	waitForAnswer(t, s, "web.cluster.local.", nil)
+}
+
+func TestServe(t *testing.T) {
+	s := newTestServer(net.ParseIP("10.240.0.1"))
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- s.Serve(conn)
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer client.Close()
+	if _, err := client.Write(newQuery(9, "web.cluster.local.", typeA)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, maxUDPMessageLen)
+	n, err := client.Read(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	h, records := parseResponse(t, buf[:n])
+	if h.id != 9 {
+		t.Errorf("Unexpected header: %#v", h)
+	}
+	if expected := aRecords("web.cluster.local.", "10.240.0.1"); !reflect.DeepEqual(expected, records) {
+		t.Errorf("Expected %#v, got %#v", expected, records)
+	}
+
+	conn.Close()
+	if err := <-done; err == nil {
+		t.Errorf("Expected an error after closing the connection")
+	}
+}
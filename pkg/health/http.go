@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
@@ -34,11 +35,11 @@ type HTTPGetInterface interface {
 
 // HTTPHealthChecker is an implementation of HealthChecker which checks container health by sending HTTP Get requests.
 type HTTPHealthChecker struct {
-	client HTTPGetInterface
+	client *http.Client
 }
 
 func NewHTTPHealthChecker(client *http.Client) HealthChecker {
-	return &HTTPHealthChecker{client: &http.Client{}}
+	return &HTTPHealthChecker{client: client}
 }
 
 // getURLParts parses the components of the target URL.  For testability.
@@ -98,10 +99,13 @@ func DoHTTPCheck(url string, client HTTPGetInterface) (Status, error) {
 }
 
 // HealthCheck checks if the container is healthy by trying sending HTTP Get requests to the container.
+// The request fails if it doesn't complete within the timeout of the probe.
 func (h *HTTPHealthChecker) HealthCheck(podFullName string, currentState api.PodState, container api.Container) (Status, error) {
 	host, port, path, err := getURLParts(currentState, container)
 	if err != nil {
 		return Unknown, err
 	}
-	return DoHTTPCheck(formatURL(host, port, path), h.client)
+	client := *h.client
+	client.Timeout = time.Duration(container.LivenessProbe.TimeoutSeconds) * time.Second
+	return DoHTTPCheck(formatURL(host, port, path), &client)
 }
@@ -137,3 +137,32 @@ func TestHTTPHealthChecker(t *testing.T) {
 		}
 	}
 }
+
+func TestHTTPHealthCheckerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	container := api.Container{
+		LivenessProbe: &api.LivenessProbe{
+			HTTPGet:        &api.HTTPGetAction{Host: host, Port: util.NewIntOrStringFromString(port)},
+			Type:           "http",
+			TimeoutSeconds: 1,
+		},
+	}
+	hc := &HTTPHealthChecker{client: &http.Client{}}
+	health, err := hc.HealthCheck("test", api.PodState{PodIP: host}, container)
+	if err == nil || health != Unknown {
+		t.Errorf("Expected the check to time out, got %v", health)
+	}
+}
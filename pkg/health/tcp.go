@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
@@ -58,12 +59,13 @@ func getTCPAddrParts(currentState api.PodState, container api.Container) (string
 	return currentState.PodIP, port, nil
 }
 
-// DoTCPCheck checks that a TCP socket to the address can be opened.
+// DoTCPCheck checks that a TCP socket to the address can be opened within the timeout.
 // If the socket can be opened, it returns Healthy.
 // If the socket fails to open, it returns Unhealthy.
+// A zero timeout means no timeout.
 // This is exported because some other packages may want to do direct TCP checks.
-func DoTCPCheck(addr string) (Status, error) {
-	conn, err := net.Dial("tcp", addr)
+func DoTCPCheck(addr string, timeout time.Duration) (Status, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
 	if err != nil {
 		return Unhealthy, nil
 	}
@@ -79,5 +81,6 @@ func (t *TCPHealthChecker) HealthCheck(podFullName string, currentState api.PodS
 	if err != nil {
 		return Unknown, err
 	}
-	return DoTCPCheck(net.JoinHostPort(host, strconv.Itoa(port)))
+	timeout := time.Duration(container.LivenessProbe.TimeoutSeconds) * time.Second
+	return DoTCPCheck(net.JoinHostPort(host, strconv.Itoa(port)), timeout)
 }
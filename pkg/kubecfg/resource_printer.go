@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strings"
 	"text/tabwriter"
 	"text/template"
@@ -142,6 +143,7 @@ var podColumns = []string{"ID", "Image(s)", "Host", "Labels", "Status"}
 var replicationControllerColumns = []string{"ID", "Image(s)", "Selector", "Replicas"}
 var serviceColumns = []string{"ID", "Labels", "Selector", "Port"}
 var minionColumns = []string{"Minion identifier"}
+var secretColumns = []string{"ID", "Keys"}
 var statusColumns = []string{"Status"}
 
 // addDefaultHandlers adds print handlers for default Kubernetes types.
@@ -154,6 +156,8 @@ func (h *HumanReadablePrinter) addDefaultHandlers() {
 	h.Handler(serviceColumns, printServiceList)
 	h.Handler(minionColumns, printMinion)
 	h.Handler(minionColumns, printMinionList)
+	h.Handler(secretColumns, printSecret)
+	h.Handler(secretColumns, printSecretList)
 	h.Handler(statusColumns, printStatus)
 }
 
@@ -244,6 +248,26 @@ func printMinionList(list *api.MinionList, w io.Writer) error {
 	return nil
 }
 
+// printSecret prints the keys of the data of a secret, never its values.
+func printSecret(secret *api.Secret, w io.Writer) error {
+	var keys []string
+	for key := range secret.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	_, err := fmt.Fprintf(w, "%s\t%s\n", secret.ID, strings.Join(keys, ","))
+	return err
+}
+
+func printSecretList(list *api.SecretList, w io.Writer) error {
+	for _, secret := range list.Items {
+		if err := printSecret(&secret, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func printStatus(status *api.Status, w io.Writer) error {
 	_, err := fmt.Fprintf(w, "%v\n", status.Status)
 	return err
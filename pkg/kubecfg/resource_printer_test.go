@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
@@ -161,3 +162,22 @@ func TestUnknownTypePrinting(t *testing.T) {
 		t.Errorf("An error was expected from printing unknown type")
 	}
 }
+
+func TestSecretPrintingHidesData(t *testing.T) {
+	printer := NewHumanReadablePrinter()
+	buffer := &bytes.Buffer{}
+	secret := &api.Secret{
+		JSONBase: api.JSONBase{ID: "foo"},
+		Data:     map[string]string{"password": "c2VjcmV0", "key": "c2VjcmV0"},
+	}
+	if err := printer.PrintObj(secret, buffer); err != nil {
+		t.Fatalf("An error occurred printing the secret: %#v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 3 || !reflect.DeepEqual(strings.Fields(lines[2]), []string{"foo", "key,password"}) {
+		t.Errorf("The secret was not printed as expected:\n%s", buffer.String())
+	}
+	if strings.Contains(buffer.String(), "c2VjcmV0") {
+		t.Errorf("The data of the secret was printed:\n%s", buffer.String())
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/golang/glog"
+)
+
+// The file under the root directory which holds the last known desired pods.
+const checkpointFile = "pods.checkpoint"
+
+// checkpoint is the desired state of the kubelet, persisted so that a restarted kubelet doesn't
+// kill the pods of the config sources which can't be reached yet. The volumes of the pods are
+// kept along with them, since the desired volumes are those of the desired pods.
+type checkpoint struct {
+	Pods []Pod `json:"pods"`
+}
+
+func (kl *Kubelet) checkpointPath() string {
+	return path.Join(kl.rootDirectory, checkpointFile)
+}
+
+// writeCheckpoint persists the desired pods. The file is replaced atomically, so that a crash
+// never leaves a partial checkpoint behind.
+func (kl *Kubelet) writeCheckpoint(pods []Pod) error {
+	data, err := json.Marshal(checkpoint{Pods: pods})
+	if err != nil {
+		return err
+	}
+	tmp := kl.checkpointPath() + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, kl.checkpointPath())
+}
+
+// readCheckpoint returns the pods of the last checkpoint, if there is one.
+func (kl *Kubelet) readCheckpoint() ([]Pod, error) {
+	data, err := ioutil.ReadFile(kl.checkpointPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return c.Pods, nil
+}
+
+// withRestoredPods returns the pods of the config sources, along with the restored pods of the
+// pending sources, which haven't delivered their pods yet. It also returns the restored pods
+// which are still needed, forgetting those of the sources which delivered their pods.
+func withRestoredPods(pods, restored []Pod, pendingSources util.StringSet) ([]Pod, []Pod) {
+	if len(restored) == 0 {
+		return pods, nil
+	}
+	names := util.NewStringSet()
+	for i := range pods {
+		names.Insert(GetPodFullName(&pods[i]))
+	}
+	result := append([]Pod{}, pods...)
+	var remaining []Pod
+	for i := range restored {
+		pod := &restored[i]
+		if !pendingSources.Has(pod.Namespace) {
+			continue
+		}
+		remaining = append(remaining, *pod)
+		if !names.Has(GetPodFullName(pod)) {
+			result = append(result, *pod)
+		}
+	}
+	if len(remaining) == 0 {
+		glog.Infof("All config sources are ready, no longer using the checkpointed pods")
+	}
+	return result, remaining
+}
+
+// withoutRestoredPods returns the pods which are not restored pods of the pending sources.
+// The pending sources haven't delivered any pod, so their pods are all restored.
+func withoutRestoredPods(pods, restored []Pod) []Pod {
+	names := util.NewStringSet()
+	for i := range restored {
+		names.Insert(GetPodFullName(&restored[i]))
+	}
+	result := []Pod{}
+	for i := range pods {
+		if !names.Has(GetPodFullName(&pods[i])) {
+			result = append(result, pods[i])
+		}
+	}
+	return result
+}
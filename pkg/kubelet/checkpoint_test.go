@@ -0,0 +1,254 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+)
+
+// fakeSyncHandler sends the pods of each sync on a channel.
+type fakeSyncHandler struct {
+	synced chan []Pod
+}
+
+func (f *fakeSyncHandler) SyncPods(pods []Pod) error {
+	f.synced <- pods
+	return nil
+}
+
+func (f *fakeSyncHandler) SyncChangedPods(pods []Pod, changed util.StringSet) error {
+	f.synced <- pods
+	return nil
+}
+
+func newCheckpointPod(name, source string) Pod {
+	return Pod{
+		Name:      name,
+		Namespace: source,
+		Manifest: api.ContainerManifest{
+			ID:         name,
+			Containers: []api.Container{{Name: "bar", Image: "busybox"}},
+			Volumes:    []api.Volume{{Name: "data", Source: &api.VolumeSource{EmptyDirectory: &api.EmptyDirectory{}}}},
+		},
+	}
+}
+
+func podFullNames(pods []Pod) []string {
+	names := []string{}
+	for i := range pods {
+		names = append(names, GetPodFullName(&pods[i]))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func expectSyncedPods(t *testing.T, handler *fakeSyncHandler, expected ...string) {
+	select {
+	case pods := <-handler.synced:
+		if names := podFullNames(pods); !reflect.DeepEqual(names, expected) {
+			t.Errorf("Expected %v to be synced, got %v", expected, names)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for %v to be synced", expected)
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	kubelet := &Kubelet{rootDirectory: dir}
+
+	pods, err := kubelet.readCheckpoint()
+	if pods != nil || err != nil {
+		t.Errorf("Expected no checkpoint, got %v %v", pods, err)
+	}
+
+	expected := []Pod{newCheckpointPod("foo", EtcdSource), newCheckpointPod("bar", FileSource)}
+	if err := kubelet.writeCheckpoint(expected); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pods, err = kubelet.readCheckpoint()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(pods, expected) {
+		t.Errorf("Expected %#v, got %#v", expected, pods)
+	}
+
+	if err := ioutil.WriteFile(kubelet.checkpointPath(), []byte("{"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := kubelet.readCheckpoint(); err == nil {
+		t.Errorf("Expected an error for a corrupt checkpoint")
+	}
+}
+
+func TestWithRestoredPods(t *testing.T) {
+	restored := []Pod{
+		newCheckpointPod("foo", EtcdSource),
+		newCheckpointPod("bar", EtcdSource),
+		newCheckpointPod("baz", FileSource),
+	}
+	configPods := []Pod{newCheckpointPod("bar", EtcdSource), newCheckpointPod("qux", FileSource)}
+
+	pods, remaining := withRestoredPods(configPods, restored, util.NewStringSet(EtcdSource))
+	if names := podFullNames(pods); !reflect.DeepEqual(names, []string{"bar.etcd", "foo.etcd", "qux.file"}) {
+		t.Errorf("Unexpected pods: %v", names)
+	}
+	if names := podFullNames(remaining); !reflect.DeepEqual(names, []string{"bar.etcd", "foo.etcd"}) {
+		t.Errorf("Unexpected remaining restored pods: %v", names)
+	}
+
+	pods, remaining = withRestoredPods(configPods, restored, nil)
+	if !reflect.DeepEqual(pods, configPods) || remaining != nil {
+		t.Errorf("Expected only the config pods, got %v %v", pods, remaining)
+	}
+}
+
+func TestSyncLoopRestoresCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	kubelet := &Kubelet{
+		rootDirectory:  dir,
+		resyncInterval: 10 * time.Millisecond,
+	}
+	if err := kubelet.writeCheckpoint([]Pod{newCheckpointPod("foo", EtcdSource), newCheckpointPod("bar", FileSource)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updates := make(chan PodUpdate)
+	handler := &fakeSyncHandler{synced: make(chan []Pod)}
+	go kubelet.syncLoop(updates, nil, handler)
+
+	// The checkpointed pods are synced before any source reports in.
+	expectSyncedPods(t, handler, "bar.file", "foo.etcd")
+
+	// The file source reported in, but the pods of the etcd source are kept.
+	updates <- PodUpdate{Pods: []Pod{newCheckpointPod("baz", FileSource)}, Op: SET, PendingSources: util.NewStringSet(EtcdSource)}
+	expectSyncedPods(t, handler, "baz.file", "foo.etcd")
+	pods, err := kubelet.readCheckpoint()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := podFullNames(pods); !reflect.DeepEqual(names, []string{"baz.file", "foo.etcd"}) {
+		t.Errorf("Unexpected checkpoint: %v", names)
+	}
+
+	// Once the etcd source reports in, its restored pods are no longer desired.
+	updates <- PodUpdate{Pods: []Pod{newCheckpointPod("baz", FileSource)}, Op: SET}
+	expectSyncedPods(t, handler, "baz.file")
+	pods, err = kubelet.readCheckpoint()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := podFullNames(pods); !reflect.DeepEqual(names, []string{"baz.file"}) {
+		t.Errorf("Unexpected checkpoint: %v", names)
+	}
+}
+
+func TestSyncLoopForgetsRestoredPodsOfPendingSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	kubelet := &Kubelet{
+		rootDirectory:         dir,
+		resyncInterval:        time.Hour,
+		pendingSourcesTimeout: 500 * time.Millisecond,
+	}
+	if err := kubelet.writeCheckpoint([]Pod{newCheckpointPod("foo", EtcdSource), newCheckpointPod("bar", FileSource)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	updates := make(chan PodUpdate)
+	handler := &fakeSyncHandler{synced: make(chan []Pod)}
+	go kubelet.syncLoop(updates, nil, handler)
+
+	updates <- PodUpdate{Pods: []Pod{newCheckpointPod("baz", FileSource)}, Op: SET, PendingSources: util.NewStringSet(EtcdSource)}
+	expectSyncedPods(t, handler, "baz.file", "foo.etcd")
+
+	// The etcd source never reports in, so its restored pods are dropped once the timeout passes.
+	expectSyncedPods(t, handler, "baz.file")
+	pods, err := kubelet.readCheckpoint()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := podFullNames(pods); !reflect.DeepEqual(names, []string{"baz.file"}) {
+		t.Errorf("Unexpected checkpoint: %v", names)
+	}
+}
+
+func TestSyncLoopAppliesUpdates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	kubelet := &Kubelet{
+		rootDirectory:  dir,
+		resyncInterval: time.Hour,
+	}
+
+	updates := make(chan PodUpdate)
+	handler := &fakeSyncHandler{synced: make(chan []Pod)}
+	go kubelet.syncLoop(updates, nil, handler)
+
+	updates <- PodUpdate{Pods: []Pod{newCheckpointPod("foo", EtcdSource), newCheckpointPod("bar", EtcdSource)}, Op: SET}
+	expectSyncedPods(t, handler, "bar.etcd", "foo.etcd")
+
+	// Only the pods which are already desired are updated.
+	updated := newCheckpointPod("foo", EtcdSource)
+	updated.Manifest.Labels = map[string]string{"tier": "backend"}
+	updates <- PodUpdate{Pods: []Pod{updated, newCheckpointPod("baz", EtcdSource)}, Op: UPDATE}
+	select {
+	case pods := <-handler.synced:
+		if names := podFullNames(pods); !reflect.DeepEqual(names, []string{"bar.etcd", "foo.etcd"}) {
+			t.Errorf("Unexpected pods: %v", names)
+		}
+		for _, pod := range pods {
+			if pod.Name == "foo" && pod.Manifest.Labels["tier"] != "backend" {
+				t.Errorf("Expected the labels of foo to be updated, got %v", pod.Manifest.Labels)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for the update to be synced")
+	}
+	pods, err := kubelet.readCheckpoint()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, pod := range pods {
+		if pod.Name == "foo" && pod.Manifest.Labels["tier"] != "backend" {
+			t.Errorf("Expected the checkpoint to have the labels of foo, got %v", pod.Manifest.Labels)
+		}
+	}
+}
@@ -69,6 +69,14 @@ func NewPodConfig(mode PodConfigNotificationMode) *PodConfig {
 // Channel creates or returns a config source channel.  The channel
 // only accepts PodUpdates
 func (c *PodConfig) Channel(source string) chan<- interface{} {
+	c.pods.addSource(source)
+	return c.mux.Channel(source)
+}
+
+// OptionalChannel creates or returns a config source channel like Channel, for a source which
+// may never send any change. The source is not reported as pending until its first change,
+// unless it is also created with Channel.
+func (c *PodConfig) OptionalChannel(source string) chan<- interface{} {
 	return c.mux.Channel(source)
 }
 
@@ -91,6 +99,8 @@ type podStorage struct {
 	// map of source name to pod name to pod reference
 	pods map[string]map[string]*kubelet.Pod
 	mode PodConfigNotificationMode
+	// the sources created with Channel
+	sources util.StringSet
 
 	// ensures that updates are delivered in strict order
 	// on the updates channel
@@ -105,6 +115,7 @@ func newPodStorage(updates chan<- kubelet.PodUpdate, mode PodConfigNotificationM
 	return &podStorage{
 		pods:    make(map[string]map[string]*kubelet.Pod),
 		mode:    mode,
+		sources: util.StringSet{},
 		updates: updates,
 	}
 }
@@ -116,6 +127,9 @@ func (s *podStorage) Merge(source string, change interface{}) error {
 	s.updateLock.Lock()
 	defer s.updateLock.Unlock()
 
+	// The first change of a source is always delivered in the snapshot modes, even if it's empty,
+	// so that listeners know the source is ready.
+	firstChange := !s.seenSource(source)
 	adds, updates, deletes := s.merge(source, change)
 
 	// deliver update notifications
@@ -135,13 +149,13 @@ func (s *podStorage) Merge(source string, change interface{}) error {
 		if len(updates.Pods) > 0 {
 			s.updates <- *updates
 		}
-		if len(deletes.Pods) > 0 || len(adds.Pods) > 0 {
-			s.updates <- kubelet.PodUpdate{s.MergedState().([]kubelet.Pod), kubelet.SET}
+		if len(deletes.Pods) > 0 || len(adds.Pods) > 0 || firstChange {
+			s.updates <- s.snapshot()
 		}
 
 	case PodConfigNotificationSnapshot:
-		if len(updates.Pods) > 0 || len(deletes.Pods) > 0 || len(adds.Pods) > 0 {
-			s.updates <- kubelet.PodUpdate{s.MergedState().([]kubelet.Pod), kubelet.SET}
+		if len(updates.Pods) > 0 || len(deletes.Pods) > 0 || len(adds.Pods) > 0 || firstChange {
+			s.updates <- s.snapshot()
 		}
 
 	default:
@@ -246,6 +260,38 @@ func (s *podStorage) merge(source string, change interface{}) (adds, updates, de
 	return adds, updates, deletes
 }
 
+// seenSource returns true if a change from source has been merged.
+func (s *podStorage) seenSource(source string) bool {
+	s.podLock.RLock()
+	defer s.podLock.RUnlock()
+	_, found := s.pods[source]
+	return found
+}
+
+// addSource records a source created with Channel, which is pending until its first change.
+func (s *podStorage) addSource(source string) {
+	s.podLock.Lock()
+	defer s.podLock.Unlock()
+	s.sources.Insert(source)
+}
+
+// snapshot returns a SET of the merged state, along with the sources which are still pending.
+// The caller must hold updateLock, so that the pending sources match the merged state.
+func (s *podStorage) snapshot() kubelet.PodUpdate {
+	update := kubelet.PodUpdate{Pods: s.MergedState().([]kubelet.Pod), Op: kubelet.SET}
+	s.podLock.RLock()
+	defer s.podLock.RUnlock()
+	for source := range s.sources {
+		if _, found := s.pods[source]; !found {
+			if update.PendingSources == nil {
+				update.PendingSources = util.StringSet{}
+			}
+			update.PendingSources.Insert(source)
+		}
+	}
+	return update
+}
+
 func filterInvalidPods(pods []kubelet.Pod, source string) (filtered []*kubelet.Pod) {
 	names := util.StringSet{}
 	for i := range pods {
@@ -271,7 +317,7 @@ func filterInvalidPods(pods []kubelet.Pod, source string) (filtered []*kubelet.P
 func (s *podStorage) Sync() {
 	s.updateLock.Lock()
 	defer s.updateLock.Unlock()
-	s.updates <- kubelet.PodUpdate{s.MergedState().([]kubelet.Pod), kubelet.SET}
+	s.updates <- s.snapshot()
 }
 
 // Object implements config.Accessor
@@ -22,6 +22,7 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 )
 
 func expectEmptyChannel(t *testing.T, ch <-chan interface{}) {
@@ -63,7 +64,7 @@ func CreatePodUpdate(op kubelet.PodOperation, pods ...kubelet.Pod) kubelet.PodUp
 	for i := range pods {
 		newPods[i] = pods[i]
 	}
-	return kubelet.PodUpdate{newPods, op}
+	return kubelet.PodUpdate{Pods: newPods, Op: op}
 }
 
 func createPodConfigTester(mode PodConfigNotificationMode) (chan<- interface{}, <-chan kubelet.PodUpdate, *PodConfig) {
@@ -197,3 +198,26 @@ func TestNewPodAddedUpdatedSet(t *testing.T) {
 		CreatePodUpdate(kubelet.ADD, CreateValidPod("foo4", "test")),
 		CreatePodUpdate(kubelet.UPDATE, pod))
 }
+
+func TestPendingSources(t *testing.T) {
+	channel, ch, config := createPodConfigTester(PodConfigNotificationSnapshotAndUpdates)
+	other := config.Channel("other")
+
+	// The first change of a source is delivered even if it's empty.
+	channel <- CreatePodUpdate(kubelet.SET)
+	expectPodUpdate(t, ch, kubelet.PodUpdate{Pods: []kubelet.Pod{}, Op: kubelet.SET, PendingSources: util.NewStringSet("other")})
+
+	other <- CreatePodUpdate(kubelet.SET)
+	expectPodUpdate(t, ch, kubelet.PodUpdate{Pods: []kubelet.Pod{}, Op: kubelet.SET})
+
+	channel <- CreatePodUpdate(kubelet.SET)
+	expectNoPodUpdate(t, ch)
+}
+
+func TestOptionalSourcesAreNotPending(t *testing.T) {
+	channel, ch, config := createPodConfigTester(PodConfigNotificationSnapshotAndUpdates)
+	config.OptionalChannel("other")
+
+	channel <- CreatePodUpdate(kubelet.SET)
+	expectPodUpdate(t, ch, kubelet.PodUpdate{Pods: []kubelet.Pod{}, Op: kubelet.SET})
+}
@@ -38,24 +38,76 @@ import (
 type SourceFile struct {
 	path    string
 	updates chan<- interface{}
+	// The name of the pod read from each file, so that the pod of a removed file can be removed.
+	podNames map[string]string
 }
 
+// NewSourceFile creates a config source that watches a file or a directory of files, and sends
+// the changes to their pods as soon as they are made where inotify is supported. Otherwise, or
+// while the path doesn't exist, the path is read every period.
 func NewSourceFile(path string, period time.Duration, updates chan<- interface{}) *SourceFile {
+	// The files are recorded under the cleaned path, which is how the watch names them.
+	path = filepath.Clean(path)
 	config := &SourceFile{
-		path:    path,
-		updates: updates,
+		path:     path,
+		updates:  updates,
+		podNames: make(map[string]string),
 	}
 	glog.V(1).Infof("Watching file %s", path)
 	go util.Forever(config.run, period)
 	return config
 }
 
+// run watches the path until the watch fails, then reads the whole path once. It is run again
+// every period, which makes it poll the path when it can't be watched.
 func (s *SourceFile) run() {
+	if err := s.watch(); err != nil {
+		glog.V(4).Infof("Unable to watch %s, polling it instead: %v", s.path, err)
+	}
 	if err := s.extractFromPath(); err != nil {
 		glog.Errorf("Unable to read config file: %s", err)
 	}
 }
 
+// watchedDir returns the directory to watch for changes to the path, and whether a file in that
+// directory belongs to the path. A single file is watched through its directory, since editors
+// often replace a file rather than write to it.
+func (s *SourceFile) watchedDir() (string, func(name string) bool, error) {
+	statInfo, err := os.Stat(s.path)
+	if err != nil {
+		return "", nil, err
+	}
+	if statInfo.Mode().IsDir() {
+		return s.path, func(name string) bool { return !strings.HasPrefix(name, ".") }, nil
+	}
+	base := filepath.Base(s.path)
+	return filepath.Dir(s.path), func(name string) bool { return name == base }, nil
+}
+
+// fileChanged sends the pod of a file which was written to or moved into the path.
+func (s *SourceFile) fileChanged(file string) {
+	pod, err := extractFromFile(file)
+	if err != nil {
+		glog.Errorf("Unable to read config file %s: %v", file, err)
+		return
+	}
+	if name, found := s.podNames[file]; found && name != pod.Name {
+		s.updates <- kubelet.PodUpdate{Pods: []kubelet.Pod{{Name: name}}, Op: kubelet.REMOVE}
+	}
+	s.podNames[file] = pod.Name
+	s.updates <- kubelet.PodUpdate{Pods: []kubelet.Pod{pod}, Op: kubelet.ADD}
+}
+
+// fileRemoved removes the pod of a file which was removed from or moved out of the path.
+func (s *SourceFile) fileRemoved(file string) {
+	name, found := s.podNames[file]
+	if !found {
+		return
+	}
+	delete(s.podNames, file)
+	s.updates <- kubelet.PodUpdate{Pods: []kubelet.Pod{{Name: name}}, Op: kubelet.REMOVE}
+}
+
 func (s *SourceFile) extractFromPath() error {
 	path := s.path
 	statInfo, err := os.Stat(path)
@@ -68,18 +120,23 @@ func (s *SourceFile) extractFromPath() error {
 
 	switch {
 	case statInfo.Mode().IsDir():
-		pods, err := extractFromDir(path)
+		files, pods, err := extractFromDir(path)
 		if err != nil {
 			return err
 		}
-		s.updates <- kubelet.PodUpdate{pods, kubelet.SET}
+		s.podNames = make(map[string]string)
+		for i, file := range files {
+			s.podNames[file] = pods[i].Name
+		}
+		s.updates <- kubelet.PodUpdate{Pods: pods, Op: kubelet.SET}
 
 	case statInfo.Mode().IsRegular():
 		pod, err := extractFromFile(path)
 		if err != nil {
 			return err
 		}
-		s.updates <- kubelet.PodUpdate{[]kubelet.Pod{pod}, kubelet.SET}
+		s.podNames = map[string]string{path: pod.Name}
+		s.updates <- kubelet.PodUpdate{Pods: []kubelet.Pod{pod}, Op: kubelet.SET}
 
 	default:
 		return fmt.Errorf("path is not a directory or file")
@@ -88,12 +145,13 @@ func (s *SourceFile) extractFromPath() error {
 	return nil
 }
 
-func extractFromDir(name string) ([]kubelet.Pod, error) {
+// extractFromDir returns the pods of the files in a directory, along with the files.
+func extractFromDir(name string) ([]string, []kubelet.Pod, error) {
 	pods := []kubelet.Pod{}
 
 	files, err := filepath.Glob(filepath.Join(name, "[^.]*"))
 	if err != nil {
-		return pods, err
+		return nil, pods, err
 	}
 
 	sort.Strings(files)
@@ -101,11 +159,11 @@ func extractFromDir(name string) ([]kubelet.Pod, error) {
 	for _, file := range files {
 		pod, err := extractFromFile(file)
 		if err != nil {
-			return []kubelet.Pod{}, err
+			return nil, []kubelet.Pod{}, err
 		}
 		pods = append(pods, pod)
 	}
-	return pods, nil
+	return files, pods, nil
 }
 
 func extractFromFile(name string) (kubelet.Pod, error) {
@@ -0,0 +1,101 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"github.com/golang/glog"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// watch reads the path, then sends the changes to its files as inotify reports them. It returns
+// when the path can't be watched anymore, e.g. because it was removed.
+func (s *SourceFile) watch() error {
+	dir, watched, err := s.watchedDir()
+	if err != nil {
+		return err
+	}
+	fd, err := syscall.InotifyInit()
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+	if _, err := syscall.InotifyAddWatch(fd, dir, inotifyMask); err != nil {
+		return err
+	}
+	// Read the path once the watch is set up, so that no change is missed.
+	if err := s.extractFromPath(); err != nil {
+		return err
+	}
+
+	var buf [syscall.SizeofInotifyEvent * 4096]byte
+	for {
+		n, err := syscall.Read(fd, buf[:])
+		if err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			return err
+		}
+		if n < syscall.SizeofInotifyEvent {
+			return fmt.Errorf("short inotify read of %d bytes", n)
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			switch {
+			case event.Mask&syscall.IN_Q_OVERFLOW != 0:
+				glog.V(1).Infof("Events for %s were lost, reading it again", s.path)
+				if err := s.extractFromPath(); err != nil {
+					return err
+				}
+				continue
+			case event.Mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF|syscall.IN_IGNORED) != 0:
+				return fmt.Errorf("%s was removed", dir)
+			}
+
+			name := string(nameBytes)
+			for i, b := range nameBytes {
+				if b == 0 {
+					name = string(nameBytes[:i])
+					break
+				}
+			}
+			if name == "" || !watched(name) {
+				continue
+			}
+			file := filepath.Join(dir, name)
+			if event.Mask&(syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO) != 0 {
+				if statInfo, err := os.Stat(file); err != nil || !statInfo.Mode().IsRegular() {
+					continue
+				}
+				s.fileChanged(file)
+			} else if event.Mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0 {
+				s.fileRemoved(file)
+			}
+		}
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet"
+)
+
+func expectFileUpdate(t *testing.T, ch <-chan interface{}, expected kubelet.PodUpdate) {
+	select {
+	case got := <-ch:
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("Expected %#v, Got %#v", expected, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected %#v, Got no update", expected)
+	}
+}
+
+func TestWatchDir(t *testing.T) {
+	dirName, err := ioutil.TempDir("", "foo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dirName)
+
+	ch := make(chan interface{})
+	// The period is long enough that only the watch can report the changes.
+	NewSourceFile(dirName, time.Hour, ch)
+	expectFileUpdate(t, ch, CreatePodUpdate(kubelet.SET))
+
+	manifest := api.ContainerManifest{Version: "v1beta1", ID: "foo", Containers: []api.Container{{Name: "1", Image: "foo"}}}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Files are written to a dotfile and moved into place, like editors do.
+	tmpName := filepath.Join(dirName, ".foo")
+	if err := ioutil.WriteFile(tmpName, data, 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	name := filepath.Join(dirName, "foo")
+	if err := os.Rename(tmpName, name); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectFileUpdate(t, ch, CreatePodUpdate(kubelet.ADD, kubelet.Pod{Name: "foo", Manifest: manifest}))
+
+	manifest.ID = "bar"
+	data, err = json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(name, data, 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectFileUpdate(t, ch, CreatePodUpdate(kubelet.REMOVE, kubelet.Pod{Name: "foo"}))
+	expectFileUpdate(t, ch, CreatePodUpdate(kubelet.ADD, kubelet.Pod{Name: "bar", Manifest: manifest}))
+
+	if err := os.Remove(name); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectFileUpdate(t, ch, CreatePodUpdate(kubelet.REMOVE, kubelet.Pod{Name: "bar"}))
+}
+
+func TestWatchFileWithUncleanPath(t *testing.T) {
+	dirName, err := ioutil.TempDir("", "foo")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dirName)
+
+	manifest := api.ContainerManifest{Version: "v1beta1", ID: "foo", Containers: []api.Container{{Name: "1", Image: "foo"}}}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	name := filepath.Join(dirName, "foo")
+	if err := ioutil.WriteFile(name, data, 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ch := make(chan interface{})
+	NewSourceFile(dirName+"//./foo", time.Hour, ch)
+	expectFileUpdate(t, ch, CreatePodUpdate(kubelet.SET, kubelet.Pod{Name: "foo", Manifest: manifest}))
+
+	if err := os.Remove(name); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectFileUpdate(t, ch, CreatePodUpdate(kubelet.REMOVE, kubelet.Pod{Name: "foo"}))
+}
@@ -32,7 +32,7 @@ import (
 
 func TestExtractFromNonExistentFile(t *testing.T) {
 	ch := make(chan interface{}, 1)
-	c := SourceFile{"/some/fake/file", ch}
+	c := SourceFile{path: "/some/fake/file", updates: ch}
 	err := c.extractFromPath()
 	if err == nil {
 		t.Errorf("Expected error")
@@ -92,7 +92,7 @@ func TestExtractFromBadDataFile(t *testing.T) {
 	defer os.Remove(file.Name())
 
 	ch := make(chan interface{}, 1)
-	c := SourceFile{file.Name(), ch}
+	c := SourceFile{path: file.Name(), updates: ch}
 	err := c.extractFromPath()
 	if err == nil {
 		t.Errorf("Expected error")
@@ -111,7 +111,7 @@ func TestExtractFromValidDataFile(t *testing.T) {
 	defer os.Remove(file.Name())
 
 	ch := make(chan interface{}, 1)
-	c := SourceFile{file.Name(), ch}
+	c := SourceFile{path: file.Name(), updates: ch}
 	err = c.extractFromPath()
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
@@ -131,7 +131,7 @@ func TestExtractFromEmptyDir(t *testing.T) {
 	defer os.RemoveAll(dirName)
 
 	ch := make(chan interface{}, 1)
-	c := SourceFile{dirName, ch}
+	c := SourceFile{path: dirName, updates: ch}
 	err = c.extractFromPath()
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
@@ -174,7 +174,7 @@ func TestExtractFromDir(t *testing.T) {
 	}
 
 	ch := make(chan interface{}, 1)
-	c := SourceFile{dirName, ch}
+	c := SourceFile{path: dirName, updates: ch}
 	err = c.extractFromPath()
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
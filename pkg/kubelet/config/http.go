@@ -90,7 +90,7 @@ func (s *SourceURL) extractFromURL() error {
 		if pod.Name == "" {
 			pod.Name = "1"
 		}
-		s.updates <- kubelet.PodUpdate{[]kubelet.Pod{pod}, kubelet.SET}
+		s.updates <- kubelet.PodUpdate{Pods: []kubelet.Pod{pod}, Op: kubelet.SET}
 		return nil
 	}
 
@@ -124,7 +124,7 @@ func (s *SourceURL) extractFromURL() error {
 			}
 			pods = append(pods, pod)
 		}
-		s.updates <- kubelet.PodUpdate{pods, kubelet.SET}
+		s.updates <- kubelet.PodUpdate{Pods: pods, Op: kubelet.SET}
 		return nil
 	}
 
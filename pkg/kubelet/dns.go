@@ -0,0 +1,140 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/golang/glog"
+)
+
+// The resolver configuration of the node, whose search paths pods using the cluster DNS keep.
+const defaultResolverConfig = "/etc/resolv.conf"
+
+// parseResolvConf returns the name servers and the search paths of a resolv.conf file.
+func parseResolvConf(reader io.Reader) (nameservers []string, searches []string, err error) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "nameserver":
+			nameservers = append(nameservers, fields[1:]...)
+		case "search":
+			// The last search line wins.
+			searches = fields[1:]
+		}
+	}
+	return nameservers, searches, scanner.Err()
+}
+
+func formatResolvConf(nameservers, searches []string) []byte {
+	var buf bytes.Buffer
+	for _, nameserver := range nameservers {
+		fmt.Fprintf(&buf, "nameserver %s\n", nameserver)
+	}
+	if len(searches) > 0 {
+		fmt.Fprintf(&buf, "search %s\n", strings.Join(searches, " "))
+	}
+	return buf.Bytes()
+}
+
+// podDNS returns the name servers and the search paths of the containers of a pod, and false if
+// the pod uses the resolver of the node.
+func (kl *Kubelet) podDNS(pod *Pod) ([]string, []string, bool, error) {
+	switch pod.Manifest.DNSPolicy {
+	case api.DNSNone:
+		if pod.Manifest.DNSConfig == nil {
+			return nil, nil, false, fmt.Errorf("no DNS configuration for the %q DNS policy", api.DNSNone)
+		}
+		return pod.Manifest.DNSConfig.Nameservers, pod.Manifest.DNSConfig.Searches, true, nil
+	case api.DNSDefault:
+		return nil, nil, false, nil
+	}
+	if kl.clusterDNS == nil {
+		return nil, nil, false, nil
+	}
+	var searches []string
+	if kl.clusterDomain != "" {
+		searches = append(searches, kl.clusterDomain)
+	}
+	resolverConfig := kl.resolverConfig
+	if resolverConfig == "" {
+		resolverConfig = defaultResolverConfig
+	}
+	file, err := os.Open(resolverConfig)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, false, err
+	}
+	if err == nil {
+		defer file.Close()
+		_, hostSearches, err := parseResolvConf(file)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		searches = append(searches, hostSearches...)
+	}
+	return []string{kl.clusterDNS.String()}, searches, true, nil
+}
+
+// resolvConf writes the resolv.conf of the containers of a pod to the directory of the pod,
+// and returns its path, or "" if the pod uses the resolver of the node.
+func (kl *Kubelet) resolvConf(pod *Pod) (string, error) {
+	nameservers, searches, found, err := kl.podDNS(pod)
+	if err != nil || !found {
+		return "", err
+	}
+	dir := path.Join(kl.rootDirectory, pod.Manifest.ID)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", err
+	}
+	resolvConf := path.Join(dir, "resolv.conf")
+	if err := ioutil.WriteFile(resolvConf, formatResolvConf(nameservers, searches), 0644); err != nil {
+		return "", err
+	}
+	return resolvConf, nil
+}
+
+// cleanupResolvConfs removes the resolv.conf files of the pods which are not in pods.
+func (kl *Kubelet) cleanupResolvConfs(pods []Pod) {
+	desired := make(map[string]empty)
+	for i := range pods {
+		desired[pods[i].Manifest.ID] = empty{}
+	}
+	dirs, err := ioutil.ReadDir(kl.rootDirectory)
+	if err != nil {
+		return
+	}
+	for _, dir := range dirs {
+		if _, found := desired[dir.Name()]; found || !dir.IsDir() {
+			continue
+		}
+		if err := os.Remove(path.Join(kl.rootDirectory, dir.Name(), "resolv.conf")); err != nil && !os.IsNotExist(err) {
+			glog.Errorf("Unable to remove the resolv.conf of pod %s: %v", dir.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestParseResolvConf(t *testing.T) {
+	nameservers, searches, err := parseResolvConf(strings.NewReader(`# comment
+nameserver 8.8.8.8
+nameserver  8.8.4.4
+search foo.com
+domain bar.com
+search corp.example.com example.com
+options ndots:2
+`))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(nameservers, []string{"8.8.8.8", "8.8.4.4"}) {
+		t.Errorf("unexpected nameservers: %v", nameservers)
+	}
+	if !reflect.DeepEqual(searches, []string{"corp.example.com", "example.com"}) {
+		t.Errorf("unexpected searches: %v", searches)
+	}
+}
+
+func TestResolvConf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubelet")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	resolverConfig := path.Join(dir, "host-resolv.conf")
+	if err := ioutil.WriteFile(resolverConfig, []byte("nameserver 8.8.8.8\nsearch example.com\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubelet := &Kubelet{
+		rootDirectory:  dir,
+		resolverConfig: resolverConfig,
+		clusterDNS:     net.ParseIP("10.0.0.10"),
+		clusterDomain:  "kubernetes.local",
+	}
+
+	tests := []struct {
+		manifest api.ContainerManifest
+		expected string
+	}{
+		{
+			api.ContainerManifest{ID: "cluster"},
+			"nameserver 10.0.0.10\nsearch kubernetes.local example.com\n",
+		},
+		{
+			api.ContainerManifest{ID: "default", DNSPolicy: api.DNSDefault},
+			"",
+		},
+		{
+			api.ContainerManifest{
+				ID:        "none",
+				DNSPolicy: api.DNSNone,
+				DNSConfig: &api.DNSConfig{Nameservers: []string{"1.2.3.4"}, Searches: []string{"corp.example.com"}},
+			},
+			"nameserver 1.2.3.4\nsearch corp.example.com\n",
+		},
+	}
+	for _, test := range tests {
+		resolvConf, err := kubelet.resolvConf(&Pod{Name: test.manifest.ID, Manifest: test.manifest})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.manifest.ID, err)
+			continue
+		}
+		if test.expected == "" {
+			if resolvConf != "" {
+				t.Errorf("%s: expected the resolver of the node, got %s", test.manifest.ID, resolvConf)
+			}
+			continue
+		}
+		if resolvConf != path.Join(dir, test.manifest.ID, "resolv.conf") {
+			t.Errorf("%s: unexpected path %s", test.manifest.ID, resolvConf)
+			continue
+		}
+		data, err := ioutil.ReadFile(resolvConf)
+		if err != nil || string(data) != test.expected {
+			t.Errorf("%s: expected %q, got %q (%v)", test.manifest.ID, test.expected, data, err)
+		}
+	}
+
+	kubelet.cleanupResolvConfs([]Pod{{Name: "none", Manifest: api.ContainerManifest{ID: "none"}}})
+	if _, err := os.Stat(path.Join(dir, "cluster", "resolv.conf")); !os.IsNotExist(err) {
+		t.Errorf("expected the resolv.conf of a removed pod to be removed, got %v", err)
+	}
+	if _, err := os.Stat(path.Join(dir, "none", "resolv.conf")); err != nil {
+		t.Errorf("expected the resolv.conf of a desired pod to be kept, got %v", err)
+	}
+}
+
+func TestResolvConfWithoutClusterDNS(t *testing.T) {
+	kubelet := &Kubelet{rootDirectory: "/nonexistent"}
+	resolvConf, err := kubelet.resolvConf(&Pod{Name: "foo", Manifest: api.ContainerManifest{ID: "foo"}})
+	if err != nil || resolvConf != "" {
+		t.Errorf("expected the resolver of the node without a cluster DNS server, got %q, %v", resolvConf, err)
+	}
+}
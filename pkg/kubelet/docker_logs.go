@@ -0,0 +1,174 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// getDockerContainerLogs returns logs of specific container
+// By default the function will return snapshot of the container log
+// Log streaming is possible if 'Follow' option is set to true
+// Log tailing is possible when number of tailed lines are set and only if 'Follow' is false
+// Lines written before 'SinceTime' are skipped, and at most 'LimitBytes' bytes are returned when it is positive
+func getDockerContainerLogs(client dockertools.DockerInterface, containerID string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) (err error) {
+	// The limit applies to what the caller gets, so it is enforced after the
+	// lines written before SinceTime have been dropped.
+	if logOpts.LimitBytes > 0 {
+		remaining := logOpts.LimitBytes
+		stdout = newLimitWriter(stdout, &remaining)
+		stderr = newLimitWriter(stderr, &remaining)
+	}
+	// Docker can't filter by time itself, so ask it for timestamps and drop
+	// the lines we don't want on our side.
+	var stdoutFilter, stderrFilter *timestampWriter
+	if !logOpts.SinceTime.IsZero() {
+		stdoutFilter = newTimestampWriter(stdout, logOpts.SinceTime, logOpts.Timestamps)
+		stderrFilter = newTimestampWriter(stderr, logOpts.SinceTime, logOpts.Timestamps)
+		stdout, stderr = stdoutFilter, stderrFilter
+	}
+
+	// Without a TTY, Docker multiplexes stdout and stderr into one stream with a header before
+	// every frame, which is only split back when the raw stream isn't requested.
+	container, err := client.InspectContainer(containerID)
+	if err != nil {
+		return err
+	}
+	opts := docker.LogsOptions{
+		Container:    containerID,
+		Stdout:       true,
+		Stderr:       true,
+		OutputStream: stdout,
+		ErrorStream:  stderr,
+		Timestamps:   logOpts.Timestamps || stdoutFilter != nil,
+		RawTerminal:  container.Config != nil && container.Config.Tty,
+		Follow:       logOpts.Follow,
+	}
+
+	if !logOpts.Follow {
+		opts.Tail = logOpts.Tail
+	}
+
+	err = client.Logs(opts)
+	// Hand on the trailing partial lines, cut at the limit if there is one.
+	if (err == nil || err == errMaximumWrite) && stdoutFilter != nil {
+		for _, filter := range []*timestampWriter{stdoutFilter, stderrFilter} {
+			if flushErr := filter.Flush(); err == nil {
+				err = flushErr
+			}
+		}
+	}
+	if err == errMaximumWrite {
+		err = nil
+	}
+	return
+}
+
+// errMaximumWrite is returned by a limitWriter once its byte limit has been reached.
+var errMaximumWrite = errors.New("maximum write")
+
+// limitWriter stops accepting writes once a fixed number of bytes has been written.
+// The remaining budget is shared by all writers created from the same limitWriter.
+type limitWriter struct {
+	writer    io.Writer
+	remaining *int64
+}
+
+func newLimitWriter(w io.Writer, limit *int64) io.Writer {
+	return &limitWriter{writer: w, remaining: limit}
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	if *w.remaining <= 0 {
+		return 0, errMaximumWrite
+	}
+	truncated := false
+	if int64(len(p)) > *w.remaining {
+		p = p[:*w.remaining]
+		truncated = true
+	}
+	n, err := w.writer.Write(p)
+	*w.remaining -= int64(n)
+	if err == nil && truncated {
+		err = errMaximumWrite
+	}
+	return n, err
+}
+
+// timestampWriter consumes log lines prefixed by Docker with an RFC3339Nano
+// timestamp, drops the lines written before sinceTime and optionally strips
+// the timestamp before handing the line on.
+type timestampWriter struct {
+	writer     io.Writer
+	sinceTime  time.Time
+	timestamps bool
+	buffer     []byte
+}
+
+func newTimestampWriter(w io.Writer, sinceTime time.Time, timestamps bool) *timestampWriter {
+	return &timestampWriter{writer: w, sinceTime: sinceTime, timestamps: timestamps}
+}
+
+// Write buffers p and forwards every complete line in it.
+func (w *timestampWriter) Write(p []byte) (int, error) {
+	w.buffer = append(w.buffer, p...)
+	for {
+		i := bytes.IndexByte(w.buffer, '\n')
+		if i < 0 {
+			break
+		}
+		line := w.buffer[:i+1]
+		w.buffer = w.buffer[i+1:]
+		if err := w.writeLine(line); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush forwards a trailing line that was not terminated by a newline.
+func (w *timestampWriter) Flush() error {
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	line := w.buffer
+	w.buffer = nil
+	return w.writeLine(line)
+}
+
+func (w *timestampWriter) writeLine(line []byte) error {
+	content := line
+	if i := bytes.IndexByte(line, ' '); i > 0 {
+		if ts, err := time.Parse(time.RFC3339Nano, string(line[:i])); err == nil {
+			if !w.sinceTime.IsZero() && ts.Before(w.sinceTime) {
+				return nil
+			}
+			content = line[i+1:]
+		}
+	}
+	if w.timestamps {
+		content = line
+	}
+	_, err := w.writer.Write(content)
+	return err
+}
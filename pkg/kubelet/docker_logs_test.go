@@ -0,0 +1,205 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
+	"github.com/fsouza/go-dockerclient"
+)
+
+const testLog = "2014-11-05T10:00:00.000000001Z first\n" +
+	"2014-11-05T10:00:05.000000000Z second\n" +
+	"2014-11-05T10:00:10.000000000Z third"
+
+func TestTimestampWriter(t *testing.T) {
+	since := time.Date(2014, time.November, 5, 10, 0, 5, 0, time.UTC)
+	tests := []struct {
+		sinceTime  time.Time
+		timestamps bool
+		expected   string
+	}{
+		{time.Time{}, false, "first\nsecond\nthird"},
+		{since, false, "second\nthird"},
+		{since, true, "2014-11-05T10:00:05.000000000Z second\n2014-11-05T10:00:10.000000000Z third"},
+	}
+	for i, test := range tests {
+		var buf bytes.Buffer
+		w := newTimestampWriter(&buf, test.sinceTime, test.timestamps)
+		// Write in small chunks to make sure partial lines are buffered.
+		data := []byte(testLog)
+		for len(data) > 0 {
+			n := 7
+			if n > len(data) {
+				n = len(data)
+			}
+			if _, err := w.Write(data[:n]); err != nil {
+				t.Fatalf("%d: unexpected error: %v", i, err)
+			}
+			data = data[n:]
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		if buf.String() != test.expected {
+			t.Errorf("%d: expected %q, got %q", i, test.expected, buf.String())
+		}
+	}
+}
+
+func TestLimitWriter(t *testing.T) {
+	var buf bytes.Buffer
+	remaining := int64(8)
+	stdout := newLimitWriter(&buf, &remaining)
+	stderr := newLimitWriter(&buf, &remaining)
+	if _, err := stdout.Write([]byte("12345")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := stderr.Write([]byte("67890")); err != errMaximumWrite {
+		t.Errorf("expected %v, got %v", errMaximumWrite, err)
+	}
+	if _, err := stdout.Write([]byte("x")); err != errMaximumWrite {
+		t.Errorf("expected %v, got %v", errMaximumWrite, err)
+	}
+	if buf.String() != "12345678" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+}
+
+func TestGetDockerContainerLogsOptions(t *testing.T) {
+	fakeDocker := &dockertools.FakeDockerClient{Container: &docker.Container{Config: &docker.Config{}}}
+	logOpts := &ContainerLogsOptions{Tail: "10", Timestamps: true}
+	var buf bytes.Buffer
+	if err := getDockerContainerLogs(fakeDocker, "foo", logOpts, &buf, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	verifyCalls(t, fakeDocker, []string{"inspect", "logs"})
+	if fakeDocker.LogsOptions.Tail != "10" || !fakeDocker.LogsOptions.Timestamps || fakeDocker.LogsOptions.Container != "foo" {
+		t.Errorf("unexpected options: %#v", fakeDocker.LogsOptions)
+	}
+	if fakeDocker.LogsOptions.RawTerminal {
+		t.Errorf("expected the log of a container without a TTY to be demultiplexed")
+	}
+
+	fakeDocker.Container.Config.Tty = true
+	logOpts = &ContainerLogsOptions{Tail: "10", Follow: true, SinceTime: time.Now()}
+	if err := getDockerContainerLogs(fakeDocker, "foo", logOpts, &buf, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fakeDocker.LogsOptions.Tail != "" || !fakeDocker.LogsOptions.Follow {
+		t.Errorf("unexpected options: %#v", fakeDocker.LogsOptions)
+	}
+	// Timestamps are needed to filter by time, even if the caller didn't ask for them.
+	if !fakeDocker.LogsOptions.Timestamps {
+		t.Errorf("expected timestamps to be requested from docker")
+	}
+	if !fakeDocker.LogsOptions.RawTerminal {
+		t.Errorf("expected the raw log of a container with a TTY")
+	}
+}
+
+// multiplexLog returns the stream Docker sends for the log of a container without a TTY: each
+// frame has a header with the stream it belongs to, 1 for stdout and 2 for stderr, and its size.
+func multiplexLog(frames ...string) []byte {
+	var buf bytes.Buffer
+	for i, frame := range frames {
+		header := make([]byte, 8)
+		header[0] = byte(1 + i%2)
+		binary.BigEndian.PutUint32(header[4:], uint32(len(frame)))
+		buf.Write(header)
+		buf.WriteString(frame)
+	}
+	return buf.Bytes()
+}
+
+// newLogServer returns a Docker client of a server which serves log as the log of container
+// "foo".
+func newLogServer(t *testing.T, tty bool, log []byte) (*httptest.Server, dockertools.DockerInterface) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/containers/foo/json":
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(docker.Container{ID: "foo", Config: &docker.Config{Tty: tty}})
+		case "/containers/foo/logs":
+			w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+			w.Write(log)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	client, err := docker.NewClient(server.URL)
+	if err != nil {
+		server.Close()
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return server, client
+}
+
+func TestGetDockerContainerLogsDemultiplexes(t *testing.T) {
+	// stdout and stderr alternate.
+	log := multiplexLog(
+		"2014-11-05T10:00:00.000000001Z first\n",
+		"2014-11-05T10:00:01.000000000Z oops\n",
+		"2014-11-05T10:00:05.000000000Z sec",
+		"2014-11-05T10:00:06.000000000Z failed\n",
+		"ond\n2014-11-05T10:00:10.000000000Z third\n",
+	)
+	server, client := newLogServer(t, false, log)
+	defer server.Close()
+	since := time.Date(2014, time.November, 5, 10, 0, 5, 0, time.UTC)
+	tests := []struct {
+		logOpts        ContainerLogsOptions
+		stdout, stderr string
+	}{
+		{ContainerLogsOptions{}, "2014-11-05T10:00:00.000000001Z first\n2014-11-05T10:00:05.000000000Z second\n2014-11-05T10:00:10.000000000Z third\n", "2014-11-05T10:00:01.000000000Z oops\n2014-11-05T10:00:06.000000000Z failed\n"},
+		{ContainerLogsOptions{SinceTime: since}, "second\nthird\n", "failed\n"},
+		{ContainerLogsOptions{SinceTime: since, Timestamps: true}, "2014-11-05T10:00:05.000000000Z second\n2014-11-05T10:00:10.000000000Z third\n", "2014-11-05T10:00:06.000000000Z failed\n"},
+		// The skipped lines, the timestamps and the frame headers don't count towards the limit.
+		// The lines are written as they are completed, so "failed" comes before "second".
+		{ContainerLogsOptions{SinceTime: since, LimitBytes: 9}, "se", "failed\n"},
+	}
+	for i, test := range tests {
+		var stdout, stderr bytes.Buffer
+		if err := getDockerContainerLogs(client, "foo", &test.logOpts, &stdout, &stderr); err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		if stdout.String() != test.stdout || stderr.String() != test.stderr {
+			t.Errorf("%d: expected %q and %q, got %q and %q", i, test.stdout, test.stderr, stdout.String(), stderr.String())
+		}
+	}
+}
+
+func TestGetDockerContainerLogsOfTTY(t *testing.T) {
+	server, client := newLogServer(t, true, []byte(testLog))
+	defer server.Close()
+	since := time.Date(2014, time.November, 5, 10, 0, 5, 0, time.UTC)
+	var stdout, stderr bytes.Buffer
+	logOpts := &ContainerLogsOptions{SinceTime: since}
+	if err := getDockerContainerLogs(client, "foo", logOpts, &stdout, &stderr); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "second\nthird" || stderr.Len() != 0 {
+		t.Errorf("unexpected output %q and %q", stdout.String(), stderr.String())
+	}
+}
@@ -0,0 +1,774 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/capabilities"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/health"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/network"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+)
+
+const (
+	networkContainerName  = "net"
+	networkContainerImage = "kubernetes/pause:latest"
+	// The Docker network mode of the containers of the pods using the network of the node.
+	hostNetworkMode = "host"
+)
+
+const (
+	// defaultTerminationGracePeriod is used for pods which don't set one, and for pods whose spec is unknown.
+	defaultTerminationGracePeriod = api.DefaultTerminationGracePeriodSeconds * time.Second
+	// minimumStopTimeout is the time a container is given to exit after SIGTERM, even if its
+	// PreStop handler used up the grace period.
+	minimumStopTimeout = 2 * time.Second
+)
+
+// dockerRuntime is the Docker implementation of Runtime. The containers of a pod join the
+// network namespace of a "net" container, and their names are built with dockertools.BuildDockerName.
+type dockerRuntime struct {
+	client dockertools.DockerInterface
+	puller dockertools.DockerPuller
+	// Optional, no commands can be run in containers without it
+	runner dockertools.ContainerCommandRunner
+	hooks  runtimeHooks
+	// Optional, the network of pods is left to Docker without it
+	network network.Plugin
+
+	// The networks set up by the plugin, by network container ID, until they are torn down.
+	networksLock sync.Mutex
+	networks     map[string]*podNetwork
+}
+
+// podNetwork is the network the plugin set up for a network container.
+type podNetwork struct {
+	podFullName string
+	// The IP address the plugin reported, "" until it is asked. It is cached, since asking
+	// the plugin may mean running a command.
+	ip      string
+	ipKnown bool
+}
+
+func newDockerRuntime(client dockertools.DockerInterface, puller dockertools.DockerPuller, runner dockertools.ContainerCommandRunner, hooks runtimeHooks) *dockerRuntime {
+	return &dockerRuntime{
+		client:   client,
+		puller:   puller,
+		runner:   runner,
+		hooks:    hooks,
+		networks: make(map[string]*podNetwork),
+	}
+}
+
+// GetPods groups the Docker containers created by the kubelet by pod.
+func (r *dockerRuntime) GetPods(all bool) (RunningPods, error) {
+	containers, err := r.client.ListContainers(docker.ListContainersOptions{All: all})
+	if err != nil {
+		return nil, err
+	}
+	var pods RunningPods
+	index := make(map[string]*RunningPod)
+	for i := range containers {
+		container := &containers[i]
+		podFullName, uuid, containerName, hash := dockertools.ParseDockerName(container.Names[0])
+		// Skip containers that we didn't create to allow users to manually
+		// spin up their own containers if they want.
+		if len(podFullName) == 0 {
+			continue
+		}
+		key := podFullName + "/" + uuid
+		pod, found := index[key]
+		if !found {
+			pod = &RunningPod{FullName: podFullName, UUID: uuid}
+			index[key] = pod
+			pods = append(pods, pod)
+		}
+		pod.Containers = append(pod.Containers, &RunningContainer{
+			ID:      container.ID,
+			Name:    containerName,
+			Hash:    hash,
+			Created: container.Created,
+			Running: !all || strings.HasPrefix(container.Status, "Up"),
+		})
+	}
+	return pods, nil
+}
+
+// Run a single container from a pod. Returns the docker container ID
+func (r *dockerRuntime) runContainer(pod *Pod, container *api.Container, podVolumes volumeMap, netMode string) (id dockertools.DockerID, err error) {
+	// The IP address of the pod is only looked up for the containers which refer to it.
+	var podIP string
+	if usesPodField(container, "podIP") {
+		podIP = r.podIP(GetPodFullName(pod), pod.Manifest.UUID)
+	}
+	envVariables, err := r.hooks.environment(pod, container, podIP)
+	if err != nil {
+		return "", err
+	}
+	binds, err := makeBinds(pod, container, podVolumes, func(index int, volumePath, subPath string) (string, error) {
+		return r.hooks.mountSubPath(pod, container, index, volumePath, subPath)
+	})
+	if err != nil {
+		return "", err
+	}
+	resolvConf, err := r.hooks.resolvConf(pod)
+	if err != nil {
+		return "", fmt.Errorf("unable to write resolv.conf: %v", err)
+	}
+	if resolvConf != "" {
+		binds = append(binds, resolvConf+":/etc/resolv.conf:ro")
+	}
+	exposedPorts, portBindings := makePortsAndBindings(container)
+	hostname := pod.Name
+	if pod.Manifest.HostNetwork {
+		if !capabilities.Get().AllowHostNetwork {
+			return "", fmt.Errorf("Pod requested host networking, but it is disallowed globally.")
+		}
+		// The ports are exposed on the node as they are, and the hostname is the one of the node.
+		portBindings = nil
+		hostname = ""
+	}
+
+	opts := docker.CreateContainerOptions{
+		Name: dockertools.BuildDockerName(pod.Manifest.UUID, GetPodFullName(pod), container),
+		Config: &docker.Config{
+			Cmd:          container.Command,
+			Env:          envVariables,
+			ExposedPorts: exposedPorts,
+			Hostname:     hostname,
+			Image:        container.Image,
+			Memory:       int64(container.Memory),
+			CpuShares:    int64(milliCPUToShares(container.CPU)),
+			WorkingDir:   container.WorkingDir,
+		},
+	}
+	hostConfig := &docker.HostConfig{
+		PortBindings: portBindings,
+		Binds:        binds,
+		NetworkMode:  netMode,
+	}
+	if err := applySecurityContext(container, opts.Config, hostConfig); err != nil {
+		return "", err
+	}
+	dockerContainer, err := r.client.CreateContainer(opts)
+	if err != nil {
+		return "", err
+	}
+	if capabilities.Get().AllowPrivileged {
+		hostConfig.Privileged = container.Privileged
+	} else if container.Privileged {
+		return "", fmt.Errorf("Container requested privileged mode, but it is disallowed globally.")
+	}
+	err = r.client.StartContainer(dockerContainer.ID, hostConfig)
+	if err == nil && container.Lifecycle != nil && container.Lifecycle.PostStart != nil {
+		handlerErr := r.hooks.runHandler(GetPodFullName(pod), pod.Manifest.UUID, container, container.Lifecycle.PostStart)
+		if handlerErr != nil {
+			r.killContainer(nil, &RunningContainer{ID: dockerContainer.ID, Name: container.Name})
+			return dockertools.DockerID(""), fmt.Errorf("failed to call event handler: %v", handlerErr)
+		}
+	}
+	return dockertools.DockerID(dockerContainer.ID), err
+}
+
+// applySecurityContext sets the security settings of a container on its Docker config and host
+// config. The capabilities which aren't allowed on the node are refused.
+func applySecurityContext(container *api.Container, config *docker.Config, hostConfig *docker.HostConfig) error {
+	sc := container.SecurityContext
+	if sc == nil {
+		return nil
+	}
+	if sc.RunAsUser != nil {
+		config.User = strconv.FormatInt(*sc.RunAsUser, 10)
+		if sc.RunAsGroup != nil {
+			config.User += ":" + strconv.FormatInt(*sc.RunAsGroup, 10)
+		}
+	}
+	if sc.Capabilities != nil {
+		for _, capability := range sc.Capabilities.Add {
+			if !capabilities.Get().AllowsCapability(string(capability)) {
+				return fmt.Errorf("Container requested capability %s, but it is disallowed globally.", capability)
+			}
+			hostConfig.CapAdd = append(hostConfig.CapAdd, string(capability))
+		}
+		for _, capability := range sc.Capabilities.Drop {
+			hostConfig.CapDrop = append(hostConfig.CapDrop, string(capability))
+		}
+	}
+	return nil
+}
+
+// terminationGracePeriod returns the time the containers of a pod are given to stop.
+func terminationGracePeriod(pod *Pod) time.Duration {
+	if pod == nil || pod.Manifest.TerminationGracePeriodSeconds == 0 {
+		return defaultTerminationGracePeriod
+	}
+	return time.Duration(pod.Manifest.TerminationGracePeriodSeconds) * time.Second
+}
+
+// getContainerSpec returns the container of the manifest of the pod with the given name, or nil.
+func getContainerSpec(pod *Pod, containerName string) *api.Container {
+	for i := range pod.Manifest.Containers {
+		if pod.Manifest.Containers[i].Name == containerName {
+			return &pod.Manifest.Containers[i]
+		}
+	}
+	return nil
+}
+
+// killContainer stops a container of a pod. If the spec of the pod is known and the container
+// has a PreStop handler, the handler is run first. Docker then sends SIGTERM to the container,
+// and kills it if it is still running once the termination grace period of the pod is over.
+func (r *dockerRuntime) killContainer(pod *Pod, runningContainer *RunningContainer) error {
+	glog.V(2).Infof("Killing: %s", runningContainer.ID)
+	// TODO(lavalamp): restore event logging:
+	// podFullName, uuid, containerName, _ := dockertools.ParseDockerName(name)
+	// kl.LogEvent(&api.Event{})
+	gracePeriod := terminationGracePeriod(pod)
+	start := time.Now()
+	if pod != nil {
+		container := getContainerSpec(pod, runningContainer.Name)
+		if container != nil && container.Lifecycle != nil && container.Lifecycle.PreStop != nil {
+			r.runPreStopHandler(pod, container, gracePeriod)
+		}
+	}
+	timeout := gracePeriod - time.Since(start)
+	if timeout < minimumStopTimeout {
+		timeout = minimumStopTimeout
+	}
+	return r.client.StopContainer(runningContainer.ID, uint(timeout.Seconds()))
+}
+
+// runPreStopHandler runs the PreStop handler of a container, waiting at most gracePeriod for it.
+// Failures are only logged, since the container is stopped regardless.
+func (r *dockerRuntime) runPreStopHandler(pod *Pod, container *api.Container, gracePeriod time.Duration) {
+	podFullName := GetPodFullName(pod)
+	done := make(chan error, 1)
+	go func() {
+		defer util.HandleCrash()
+		done <- r.hooks.runHandler(podFullName, pod.Manifest.UUID, container, container.Lifecycle.PreStop)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			glog.Errorf("PreStop handler of pod %s container %s failed: %v", podFullName, container.Name, err)
+		}
+	case <-time.After(gracePeriod):
+		glog.Warningf("PreStop handler of pod %s container %s didn't finish within %v", podFullName, container.Name, gracePeriod)
+	}
+}
+
+// createNetworkContainer starts the network container for a pod. Returns the docker container ID of the newly created container.
+func (r *dockerRuntime) createNetworkContainer(pod *Pod) (dockertools.DockerID, error) {
+	var ports []api.Port
+	// Docker only exports ports from the network container.  Let's
+	// collect all of the relevant ports and export them.
+	for _, container := range pod.Manifest.Containers {
+		ports = append(ports, container.Ports...)
+	}
+	container := &api.Container{
+		Name:  networkContainerName,
+		Image: networkContainerImage,
+		Ports: ports,
+	}
+	r.puller.Pull(networkContainerImage, nil)
+	id, err := r.runContainer(pod, container, nil, "")
+	if err != nil || r.network == nil {
+		return id, err
+	}
+	podFullName := GetPodFullName(pod)
+	err = r.withNetworkNamespace(string(id), func(netns string) error {
+		return r.network.SetUpPod(podFullName, string(id), netns)
+	})
+	if err != nil {
+		// Kill the network container, so that setting up the network is retried with a new one.
+		if killErr := r.killContainer(pod, &RunningContainer{ID: string(id), Name: networkContainerName}); killErr != nil {
+			glog.Errorf("Error killing the network container of pod %s: %v", podFullName, killErr)
+		}
+		return "", err
+	}
+	r.networksLock.Lock()
+	r.networks[string(id)] = &podNetwork{podFullName: podFullName}
+	r.networksLock.Unlock()
+	return id, nil
+}
+
+// tearDownNetwork tears down the network of a network container, in its network namespace if the
+// container is still running. Networks which fail to be torn down are retried by CleanupPods.
+func (r *dockerRuntime) tearDownNetwork(podFullName, containerID string) {
+	if r.network == nil {
+		return
+	}
+	netns := ""
+	if container, err := r.client.InspectContainer(containerID); err == nil && container.State.Running {
+		netns = networkNamespace(container)
+	}
+	err := r.network.TearDownPod(podFullName, containerID, netns)
+	r.networksLock.Lock()
+	defer r.networksLock.Unlock()
+	if err != nil {
+		glog.Errorf("Error tearing down the network of pod %s: %v", podFullName, err)
+		r.networks[containerID] = &podNetwork{podFullName: podFullName}
+		return
+	}
+	delete(r.networks, containerID)
+}
+
+// CleanupPods tears down the networks of the network containers which are neither in runningPods
+// nor running anymore, e.g. because they exited on their own. The running network containers
+// which were set up before the kubelet started are remembered, so that they are torn down too.
+func (r *dockerRuntime) CleanupPods(runningPods RunningPods) {
+	if r.network == nil {
+		return
+	}
+	running := make(map[string]empty)
+	r.networksLock.Lock()
+	for _, runningPod := range runningPods {
+		if container := runningPod.FindContainer(networkContainerName); container != nil {
+			running[container.ID] = empty{}
+			if _, found := r.networks[container.ID]; !found {
+				r.networks[container.ID] = &podNetwork{podFullName: runningPod.FullName}
+			}
+		}
+	}
+	exited := make(map[string]string)
+	for containerID, tracked := range r.networks {
+		if _, found := running[containerID]; !found {
+			exited[containerID] = tracked.podFullName
+		}
+	}
+	r.networksLock.Unlock()
+
+	for containerID, podFullName := range exited {
+		// The container may have been started after runningPods were listed.
+		if container, err := r.client.InspectContainer(containerID); err == nil && container.State.Running {
+			continue
+		}
+		r.tearDownNetwork(podFullName, containerID)
+	}
+}
+
+// withNetworkNamespace calls f with the path of the network namespace of a running container.
+func (r *dockerRuntime) withNetworkNamespace(containerID string, f func(netns string) error) error {
+	inspectResult, err := r.client.InspectContainer(containerID)
+	if err != nil {
+		return err
+	}
+	return f(networkNamespace(inspectResult))
+}
+
+// networkNamespace returns the path of the network namespace of a running container.
+func networkNamespace(container *docker.Container) string {
+	return fmt.Sprintf("/proc/%d/ns/net", container.State.Pid)
+}
+
+// Delete all containers in a pod (except the network container) returns the number of containers deleted
+// and an error if one occurs.
+func (r *dockerRuntime) deleteAllContainers(pod *Pod, runningPod RunningPod) (int, error) {
+	count := 0
+	errs := make(chan error, len(pod.Manifest.Containers))
+	wg := sync.WaitGroup{}
+	for _, container := range pod.Manifest.Containers {
+		if runningContainer := runningPod.FindContainer(container.Name); runningContainer != nil {
+			count++
+			wg.Add(1)
+			go func() {
+				err := r.killContainer(pod, runningContainer)
+				if err != nil {
+					glog.Errorf("Failed to delete container. (%v)  Skipping pod %s", err, runningPod.FullName)
+					errs <- err
+				}
+				wg.Done()
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	if len(errs) > 0 {
+		errList := []error{}
+		for err := range errs {
+			errList = append(errList, err)
+		}
+		return -1, fmt.Errorf("failed to delete containers (%v)", errList)
+	}
+	return count, nil
+}
+
+// SyncPod starts the network container and the containers of the pod which are missing,
+// and kills the ones which are unhealthy, outdated or duplicated.
+func (r *dockerRuntime) SyncPod(pod *Pod, runningPod RunningPod, podVolumes volumeMap) error {
+	podFullName := GetPodFullName(pod)
+	uuid := pod.Manifest.UUID
+	containersToKeep := make(map[string]empty)
+	killedContainers := make(map[string]empty)
+
+	// Make sure we have a network container, unless the pod uses the network of the node.
+	var netID, netMode string
+	if pod.Manifest.HostNetwork {
+		netMode = hostNetworkMode
+	} else if networkContainer := runningPod.FindContainer(networkContainerName); networkContainer != nil {
+		netID = networkContainer.ID
+	} else {
+		glog.V(3).Infof("Network container doesn't exist, creating")
+		count, err := r.deleteAllContainers(pod, runningPod)
+		if err != nil {
+			return err
+		}
+		dockerNetworkID, err := r.createNetworkContainer(pod)
+		if err != nil {
+			glog.Errorf("Failed to introspect network container. (%v)  Skipping pod %s", err, podFullName)
+			return err
+		}
+		netID = string(dockerNetworkID)
+		if count > 0 {
+			// relist everything, otherwise we'll think we're ok
+			runningPods, err := r.GetPods(false)
+			if err != nil {
+				glog.Errorf("Error listing containers %#v", runningPods)
+				return err
+			}
+			runningPod = runningPods.FindPod(podFullName, uuid)
+		}
+	}
+	if netID != "" {
+		containersToKeep[netID] = empty{}
+		netMode = "container:" + netID
+	}
+
+	podState := api.PodState{Manifest: api.ContainerManifest{UUID: uuid}}
+	podState.PodIP = r.podIP(podFullName, uuid)
+
+	// The image pull credentials of the pod, read before the first pull.
+	var dockercfgs [][]byte
+	initialized, err := r.syncInitContainers(pod, runningPod, podVolumes, netMode, &dockercfgs, containersToKeep)
+	if err != nil {
+		return err
+	}
+	containers := pod.Manifest.Containers
+	if !initialized {
+		containers = nil
+	}
+	for _, container := range containers {
+		expectedHash := dockertools.HashContainer(&container)
+		if runningContainer := runningPod.FindContainer(container.Name); runningContainer != nil {
+			containerID := runningContainer.ID
+			hash := runningContainer.Hash
+			glog.V(3).Infof("pod %s container %s exists as %v", podFullName, container.Name, containerID)
+
+			// look for changes in the container.
+			if hash == 0 || hash == expectedHash {
+				r.hooks.startProbes(podFullName, podState, container, runningContainer)
+				healthy := r.hooks.healthy(containerID)
+				if healthy == health.Healthy {
+					containersToKeep[containerID] = empty{}
+					continue
+				}
+				glog.V(1).Infof("pod %s container %s is unhealthy: %v.", podFullName, container.Name, healthy)
+			} else {
+				glog.V(3).Infof("container hash changed %d vs %d.", hash, expectedHash)
+			}
+			if err := r.killContainer(pod, runningContainer); err != nil {
+				glog.V(1).Infof("Failed to kill container %s: %v", containerID, err)
+				continue
+			}
+			killedContainers[containerID] = empty{}
+		}
+
+		// Check RestartPolicy for container
+		recentContainers, err := dockertools.GetRecentDockerContainersWithNameAndUUID(r.client, podFullName, uuid, container.Name)
+		if err != nil {
+			glog.Errorf("Error listing recent containers with name and uuid:%s--%s--%s", podFullName, uuid, container.Name)
+			// TODO(dawnchen): error handling here?
+		}
+
+		if len(recentContainers) > 0 && pod.Manifest.RestartPolicy.Always == nil {
+			if pod.Manifest.RestartPolicy.Never != nil {
+				glog.V(3).Infof("Already ran container with name %s--%s--%s, do nothing",
+					podFullName, uuid, container.Name)
+				continue
+			}
+			if pod.Manifest.RestartPolicy.OnFailure != nil {
+				// Check the exit code of last run
+				if recentContainers[0].State.ExitCode == 0 {
+					glog.V(3).Infof("Already successfully ran container with name %s--%s--%s, do nothing",
+						podFullName, uuid, container.Name)
+					continue
+				}
+			}
+		}
+
+		glog.V(3).Infof("Container with name %s--%s--%s doesn't exist, creating %#v", podFullName, uuid, container.Name, container)
+		// TODO(dawnchen): Check RestartPolicy.DelaySeconds before restart a container
+		containerID, err := r.pullAndRunContainer(pod, &container, podVolumes, netMode, &dockercfgs)
+		if err != nil {
+			// TODO(bburns) : Perhaps blacklist a container after N failures?
+			glog.Errorf("Error running pod %s container %s: %v", podFullName, container.Name, err)
+			continue
+		}
+		r.hooks.startProbes(podFullName, podState, container, &RunningContainer{
+			ID:      string(containerID),
+			Name:    container.Name,
+			Created: time.Now().Unix(),
+			Running: true,
+		})
+		containersToKeep[string(containerID)] = empty{}
+	}
+
+	// Kill any containers in this pod which were not identified above (guards against duplicates).
+	for _, container := range runningPod.Containers {
+		// Don't kill containers we want to keep or those we already killed.
+		_, keep := containersToKeep[container.ID]
+		_, killed := killedContainers[container.ID]
+		if !keep && !killed {
+			if container.Name == networkContainerName {
+				r.tearDownNetwork(podFullName, container.ID)
+			}
+			err = r.killContainer(pod, container)
+			if err != nil {
+				glog.Errorf("Error killing container: %v", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// podIP returns the IP address of the network container of a pod, or "" if it isn't known.
+func (r *dockerRuntime) podIP(podFullName, uuid string) string {
+	info, err := r.GetPodInfo(podFullName, uuid)
+	if err != nil {
+		glog.Errorf("Unable to get pod with name %s and uuid %s info, its IP address is unknown: %v",
+			podFullName, uuid, err)
+	}
+	netInfo, found := info[networkContainerName]
+	if found && netInfo.DetailInfo.NetworkSettings != nil {
+		return netInfo.DetailInfo.NetworkSettings.IPAddress
+	}
+	return ""
+}
+
+// pullAndRunContainer pulls the image of a container with the image pull credentials of its pod,
+// which are read into dockercfgs by the first pull, and runs the container with the Docker
+// network mode netMode.
+func (r *dockerRuntime) pullAndRunContainer(pod *Pod, container *api.Container, podVolumes volumeMap, netMode string, dockercfgs *[][]byte) (dockertools.DockerID, error) {
+	if *dockercfgs == nil {
+		credentials, err := r.hooks.pullCredentials(pod)
+		if err != nil {
+			return "", fmt.Errorf("unable to get the image pull credentials: %v", err)
+		}
+		*dockercfgs = credentials
+	}
+	if err := r.puller.Pull(container.Image, *dockercfgs); err != nil {
+		return "", fmt.Errorf("failed to pull image %s: %v", container.Image, err)
+	}
+	return r.runContainer(pod, container, podVolumes, netMode)
+}
+
+// syncInitContainers runs the init containers of a pod one after the other, and returns
+// whether they all completed successfully. An init container which failed is run again
+// unless the restart policy of the pod is Never, in which case the pod never gets past it.
+// The init containers are not run again once the other containers of the pod are running.
+func (r *dockerRuntime) syncInitContainers(pod *Pod, runningPod RunningPod, podVolumes volumeMap, netMode string, dockercfgs *[][]byte, containersToKeep map[string]empty) (bool, error) {
+	if len(pod.Manifest.InitContainers) == 0 {
+		return true, nil
+	}
+	for _, container := range pod.Manifest.Containers {
+		if runningPod.FindContainer(container.Name) != nil {
+			return true, nil
+		}
+	}
+	podFullName := GetPodFullName(pod)
+	uuid := pod.Manifest.UUID
+	for _, container := range pod.Manifest.InitContainers {
+		if runningContainer := runningPod.FindContainer(container.Name); runningContainer != nil {
+			glog.V(3).Infof("Waiting for init container %s of pod %s to complete", container.Name, podFullName)
+			containersToKeep[runningContainer.ID] = empty{}
+			return false, nil
+		}
+		recentContainers, err := dockertools.GetRecentDockerContainersWithNameAndUUID(r.client, podFullName, uuid, container.Name)
+		if err != nil {
+			return false, err
+		}
+		if len(recentContainers) > 0 {
+			if recentContainers[0].State.ExitCode == 0 {
+				continue
+			}
+			if pod.Manifest.RestartPolicy.Never != nil {
+				glog.V(3).Infof("Init container %s of pod %s failed, not restarting it", container.Name, podFullName)
+				return false, nil
+			}
+		}
+		glog.V(3).Infof("Running init container %s of pod %s", container.Name, podFullName)
+		containerID, err := r.pullAndRunContainer(pod, &container, podVolumes, netMode, dockercfgs)
+		if err != nil {
+			glog.Errorf("Error running pod %s init container %s: %v", podFullName, container.Name, err)
+			return false, nil
+		}
+		containersToKeep[string(containerID)] = empty{}
+		return false, nil
+	}
+	return true, nil
+}
+
+// KillPod stops the containers of the pod in parallel, and then its network container, so
+// that the PreStop handlers can still use the network of the pod.
+func (r *dockerRuntime) KillPod(pod *Pod, runningPod RunningPod) error {
+	var lock sync.Mutex
+	var errList []error
+	var networkContainer *RunningContainer
+	wg := sync.WaitGroup{}
+	for _, container := range runningPod.Containers {
+		if container.Name == networkContainerName {
+			networkContainer = container
+			continue
+		}
+		wg.Add(1)
+		go func(container *RunningContainer) {
+			defer wg.Done()
+			if err := r.killContainer(pod, container); err != nil {
+				lock.Lock()
+				defer lock.Unlock()
+				errList = append(errList, err)
+			}
+		}(container)
+	}
+	wg.Wait()
+	if networkContainer != nil {
+		r.tearDownNetwork(runningPod.FullName, networkContainer.ID)
+		if err := r.killContainer(pod, networkContainer); err != nil {
+			errList = append(errList, err)
+		}
+	}
+	if len(errList) > 0 {
+		return fmt.Errorf("failed to kill containers of pod %s (%v)", runningPod.FullName, errList)
+	}
+	return nil
+}
+
+// GetPodInfo returns information from Docker about the containers in a pod
+// The IP address of the network container is the one the network plugin reports, if any. The
+// plugin is only asked once per network container.
+func (r *dockerRuntime) GetPodInfo(podFullName, uuid string) (api.PodInfo, error) {
+	info, err := dockertools.GetDockerPodInfo(r.client, podFullName, uuid)
+	if err != nil || r.network == nil {
+		return info, err
+	}
+	netInfo, found := info[networkContainerName]
+	if !found || netInfo.State.Running == nil {
+		return info, nil
+	}
+	ip, err := r.networkIP(podFullName, &netInfo.DetailInfo)
+	if err != nil {
+		glog.Errorf("Unable to get the IP address of pod %s: %v", podFullName, err)
+		return info, nil
+	}
+	if ip != "" {
+		settings := docker.NetworkSettings{}
+		if netInfo.DetailInfo.NetworkSettings != nil {
+			settings = *netInfo.DetailInfo.NetworkSettings
+		}
+		settings.IPAddress = ip
+		netInfo.DetailInfo.NetworkSettings = &settings
+		info[networkContainerName] = netInfo
+	}
+	return info, nil
+}
+
+// networkIP returns the IP address the network plugin reports for a running network container.
+func (r *dockerRuntime) networkIP(podFullName string, container *docker.Container) (string, error) {
+	r.networksLock.Lock()
+	tracked, found := r.networks[container.ID]
+	if found && tracked.ipKnown {
+		defer r.networksLock.Unlock()
+		return tracked.ip, nil
+	}
+	r.networksLock.Unlock()
+	ip, err := r.network.PodIP(podFullName, container.ID, networkNamespace(container))
+	if err != nil {
+		return "", err
+	}
+	r.networksLock.Lock()
+	defer r.networksLock.Unlock()
+	// Network containers set up before the kubelet started are remembered from now on.
+	if _, found := r.networks[container.ID]; !found {
+		r.networks[container.ID] = &podNetwork{podFullName: podFullName}
+	}
+	r.networks[container.ID].ip = ip
+	r.networks[container.ID].ipKnown = true
+	return ip, nil
+}
+
+// GetContainerLogs returns the logs of the current container with the given name, or the
+// ones of the last terminated instance of it if logOpts.Previous is set.
+func (r *dockerRuntime) GetContainerLogs(podFullName, containerName string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) error {
+	var containerID string
+	if logOpts.Previous {
+		previous, err := dockertools.GetPreviousDockerContainer(r.client, podFullName, "", containerName)
+		if err != nil {
+			return err
+		}
+		containerID = previous.ID
+	} else {
+		dockerContainers, err := dockertools.GetKubeletDockerContainers(r.client)
+		if err != nil {
+			return err
+		}
+		dockerContainer, found, _ := dockerContainers.FindPodContainer(podFullName, "", containerName)
+		if !found {
+			return fmt.Errorf("Container not found (%s)\n", containerName)
+		}
+		containerID = dockerContainer.ID
+	}
+	return getDockerContainerLogs(r.client, containerID, logOpts, stdout, stderr)
+}
+
+// RunInContainer runs a command in a container, returns the combined stdout, stderr as an array of bytes
+func (r *dockerRuntime) RunInContainer(containerID string, cmd []string) ([]byte, error) {
+	if r.runner == nil {
+		return nil, fmt.Errorf("no runner specified.")
+	}
+	return r.runner.RunInContainer(containerID, cmd)
+}
+
+// PullImage pulls an image with the credentials of the node's Docker keyring, merged with
+// the credentials of dockercfgs.
+func (r *dockerRuntime) PullImage(image string, dockercfgs [][]byte) error {
+	return r.puller.Pull(image, dockercfgs)
+}
+
+// ContainerDiskUsage returns the size of the writable layer of each running container, as
+// computed by Docker.
+func (r *dockerRuntime) ContainerDiskUsage() (map[string]int64, error) {
+	containers, err := r.client.ListContainers(docker.ListContainersOptions{Size: true})
+	if err != nil {
+		return nil, err
+	}
+	usage := make(map[string]int64)
+	for _, container := range containers {
+		usage[container.ID] = container.SizeRw
+	}
+	return usage, nil
+}
@@ -38,6 +38,12 @@ func readDockerConfigFile() (cfg dockerConfig, err error) {
 	return
 }
 
+// parseDockerConfig parses the contents of a .dockercfg file.
+func parseDockerConfig(contents []byte) (cfg dockerConfig, err error) {
+	err = json.Unmarshal(contents, &cfg)
+	return
+}
+
 // dockerConfig represents the config file used by the docker CLI.
 // This config that represents the credentials that should be used
 // when pulling images from specific image repositories.
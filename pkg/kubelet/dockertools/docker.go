@@ -20,7 +20,6 @@ import (
 	"errors"
 	"fmt"
 	"hash/adler32"
-	"io"
 	"math/rand"
 	"os/exec"
 	"sort"
@@ -55,7 +54,10 @@ type DockerID string
 
 // DockerPuller is an abstract interface for testability.  It abstracts image pull operations.
 type DockerPuller interface {
-	Pull(image string) error
+	// Pull pulls an image with the credentials of the node, merged with the credentials of
+	// dockercfgs, the contents of additional .dockercfg files. The credentials of dockercfgs
+	// take precedence over the ones of the node for the same registry.
+	Pull(image string, dockercfgs [][]byte) error
 }
 
 // dockerPuller is the default implementation of DockerPuller.
@@ -109,7 +111,7 @@ func NewDockerContainerCommandRunner() ContainerCommandRunner {
 	return &dockerContainerCommandRunner{}
 }
 
-func (p dockerPuller) Pull(image string) error {
+func (p dockerPuller) Pull(image string, dockercfgs [][]byte) error {
 	image, tag := parseImageName(image)
 
 	// If no tag was specified, use the default "latest".
@@ -122,7 +124,20 @@ func (p dockerPuller) Pull(image string) error {
 		Tag:        tag,
 	}
 
-	creds, ok := p.keyring.lookup(image)
+	keyring := p.keyring
+	if len(dockercfgs) > 0 {
+		// The additional credentials are only kept in memory for the duration of the pull.
+		keyring = p.keyring.copy()
+		for _, data := range dockercfgs {
+			cfg, err := parseDockerConfig(data)
+			if err != nil {
+				return fmt.Errorf("unable to parse image pull credentials: %v", err)
+			}
+			cfg.addToKeyring(keyring)
+		}
+	}
+
+	creds, ok := keyring.lookup(image)
 	if !ok {
 		glog.V(1).Infof("Pulling image %s without credentials", image)
 	}
@@ -204,28 +219,18 @@ func GetRecentDockerContainersWithNameAndUUID(client DockerInterface, podFullNam
 	return result, nil
 }
 
-// GetKubeletDockerContainerLogs returns logs of specific container
-// By default the function will return snapshot of the container log
-// Log streaming is possible if 'follow' param is set to true
-// Log tailing is possible when number of tailed lines are set and only if 'follow' is false
-func GetKubeletDockerContainerLogs(client DockerInterface, containerID, tail string, follow bool, stdout, stderr io.Writer) (err error) {
-	opts := docker.LogsOptions{
-		Container:    containerID,
-		Stdout:       true,
-		Stderr:       true,
-		OutputStream: stdout,
-		ErrorStream:  stderr,
-		Timestamps:   true,
-		RawTerminal:  true,
-		Follow:       follow,
+// GetPreviousDockerContainer returns the most recently terminated docker container with the given
+// pod full name and container name, that is the instance that ran before the current one.
+func GetPreviousDockerContainer(client DockerInterface, podFullName, uuid, containerName string) (*docker.Container, error) {
+	recentContainers, err := GetRecentDockerContainersWithNameAndUUID(client, podFullName, uuid, containerName)
+	if err != nil {
+		return nil, err
 	}
-
-	if !follow {
-		opts.Tail = tail
+	if len(recentContainers) == 0 {
+		return nil, fmt.Errorf("previous terminated container %s not found", containerName)
 	}
-
-	err = client.Logs(opts)
-	return
+	// Docker returns containers newest first.
+	return recentContainers[0], nil
 }
 
 func generateContainerStatus(inspectResult *docker.Container) api.ContainerStatus {
@@ -408,10 +413,21 @@ func newDockerKeyring() *dockerKeyring {
 }
 
 func (dk *dockerKeyring) add(registry string, creds docker.AuthConfiguration) {
+	if _, found := dk.creds[registry]; !found {
+		dk.index = append(dk.index, registry)
+		dk.reindex()
+	}
 	dk.creds[registry] = creds
+}
 
-	dk.index = append(dk.index, registry)
-	dk.reindex()
+// copy returns a keyring with the same credentials, which can be added to without changing dk.
+func (dk *dockerKeyring) copy() *dockerKeyring {
+	keyring := newDockerKeyring()
+	keyring.index = append(keyring.index, dk.index...)
+	for registry, creds := range dk.creds {
+		keyring.creds[registry] = creds
+	}
+	return keyring
 }
 
 // reindex updates the index used to identify which credentials to use for
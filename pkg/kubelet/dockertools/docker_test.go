@@ -207,3 +207,33 @@ func TestDockerKeyringLookup(t *testing.T) {
 		}
 	}
 }
+
+func TestPullWithDockerConfigs(t *testing.T) {
+	node := docker.AuthConfiguration{Username: "node", Password: "pass"}
+	fakeDocker := &FakeDockerClient{}
+	puller := dockerPuller{client: fakeDocker, keyring: newDockerKeyring()}
+	puller.keyring.add("foo.example.com", node)
+	puller.keyring.add("bar.example.com", node)
+
+	dockercfg := []byte(`{"https://bar.example.com": {"username": "pod", "password": "secret"}}`)
+	for _, image := range []string{"foo.example.com/foo", "bar.example.com/bar"} {
+		if err := puller.Pull(image, [][]byte{dockercfg}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if err := puller.Pull("bar.example.com/bar", nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	pod := docker.AuthConfiguration{Username: "pod", Password: "secret"}
+	expected := []docker.AuthConfiguration{node, pod, node}
+	if !reflect.DeepEqual(expected, fakeDocker.pulledAuths) {
+		t.Errorf("expected %#v, got %#v", expected, fakeDocker.pulledAuths)
+	}
+	if puller.keyring.creds["bar.example.com"] != node {
+		t.Errorf("expected the credentials of the node not to change, got %#v", puller.keyring.creds)
+	}
+
+	if err := puller.Pull("bar.example.com/bar", [][]byte{[]byte("{")}); err == nil {
+		t.Errorf("expected an error for invalid credentials")
+	}
+}
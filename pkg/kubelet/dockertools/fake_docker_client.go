@@ -18,6 +18,7 @@ package dockertools
 
 import (
 	"fmt"
+	"io"
 	"reflect"
 	"sync"
 
@@ -33,7 +34,15 @@ type FakeDockerClient struct {
 	called        []string
 	Stopped       []string
 	pulled        []string
+	pulledAuths   []docker.AuthConfiguration
 	Created       []string
+	// The configs the containers were created with, in order.
+	Configs []*docker.Config
+	// The host configs the containers were started with, in order.
+	HostConfigs []*docker.HostConfig
+	LogsOptions docker.LogsOptions
+	// The output and error Logs writes to the streams of the caller.
+	Stdout, Stderr string
 }
 
 func (f *FakeDockerClient) clearCalls() {
@@ -78,6 +87,7 @@ func (f *FakeDockerClient) CreateContainer(c docker.CreateContainerOptions) (*do
 	defer f.Unlock()
 	f.called = append(f.called, "create")
 	f.Created = append(f.Created, c.Name)
+	f.Configs = append(f.Configs, c.Config)
 	// This is not a very good fake. We'll just add this container's name to the list.
 	// Docker likes to add a '/', so copy that behavior.
 	name := "/" + c.Name
@@ -91,6 +101,7 @@ func (f *FakeDockerClient) StartContainer(id string, hostConfig *docker.HostConf
 	f.Lock()
 	defer f.Unlock()
 	f.called = append(f.called, "start")
+	f.HostConfigs = append(f.HostConfigs, hostConfig)
 	return f.Err
 }
 
@@ -117,7 +128,21 @@ func (f *FakeDockerClient) Logs(opts docker.LogsOptions) error {
 	f.Lock()
 	defer f.Unlock()
 	f.called = append(f.called, "logs")
-	return f.Err
+	f.LogsOptions = opts
+	if f.Err != nil {
+		return f.Err
+	}
+	if f.Stdout != "" {
+		if _, err := io.WriteString(opts.OutputStream, f.Stdout); err != nil {
+			return err
+		}
+	}
+	if f.Stderr != "" {
+		if _, err := io.WriteString(opts.ErrorStream, f.Stderr); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // PullImage is a test-spy implementation of DockerInterface.StopContainer.
@@ -127,6 +152,7 @@ func (f *FakeDockerClient) PullImage(opts docker.PullImageOptions, auth docker.A
 	defer f.Unlock()
 	f.called = append(f.called, "pull")
 	f.pulled = append(f.pulled, fmt.Sprintf("%s/%s:%s", opts.Repository, opts.Registry, opts.Tag))
+	f.pulledAuths = append(f.pulledAuths, auth)
 	return f.Err
 }
 
@@ -135,6 +161,8 @@ type FakeDockerPuller struct {
 	sync.Mutex
 
 	ImagesPulled []string
+	// The .dockercfg contents passed with each pull.
+	DockerConfigs [][][]byte
 
 	// Every pull will return the first error here, and then reslice
 	// to remove it. Will give nil errors if this slice is empty.
@@ -142,10 +170,11 @@ type FakeDockerPuller struct {
 }
 
 // Pull records the image pull attempt, and optionally injects an error.
-func (f *FakeDockerPuller) Pull(image string) (err error) {
+func (f *FakeDockerPuller) Pull(image string, dockercfgs [][]byte) (err error) {
 	f.Lock()
 	defer f.Unlock()
 	f.ImagesPulled = append(f.ImagesPulled, image)
+	f.DockerConfigs = append(f.DockerConfigs, dockercfgs)
 
 	if len(f.ErrorsToInject) > 0 {
 		err = f.ErrorsToInject[0]
@@ -0,0 +1,366 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/golang/glog"
+	"github.com/google/cadvisor/info"
+)
+
+// The filesystem Docker keeps its images and containers on.
+const defaultDockerRoot = "/var/lib/docker"
+
+// evictionPeriod is how often the memory and disk usage is observed for eviction. The usage is
+// observed outside of the sync of the pods, since it means querying cAdvisor.
+const evictionPeriod = 10 * time.Second
+
+// EvictionThresholds configures when the kubelet evicts pods to reclaim the resources of the node.
+// A zero threshold disables eviction for its resource.
+type EvictionThresholds struct {
+	// The amount of memory which must stay available on the node, in bytes.
+	MemoryAvailable int64
+	// The amount of disk space which must stay available on the filesystems of the root directory
+	// of the kubelet and of Docker, in bytes.
+	DiskAvailable int64
+}
+
+// evictionManager observes the memory and disk usage of the node, and keeps track of the
+// resulting node conditions and of the pods evicted because of them. It is safe for concurrent use.
+type evictionManager struct {
+	thresholds EvictionThresholds
+	cadvisor   CadvisorInterface
+	// The filesystems checked against the disk threshold.
+	paths []string
+	// Returns the space available to unprivileged users on the filesystem of a path. For testability.
+	availableBytes func(path string) (int64, error)
+
+	lock       sync.Mutex
+	conditions []api.NodeCondition
+	// The memory working set of each running pod, as of the last observation.
+	podMemory map[podKey]uint64
+	// The reason each evicted pod was evicted for.
+	evicted map[podKey]string
+}
+
+func newEvictionManager(thresholds EvictionThresholds, cadvisor CadvisorInterface, paths ...string) *evictionManager {
+	return &evictionManager{
+		thresholds:     thresholds,
+		cadvisor:       cadvisor,
+		paths:          paths,
+		availableBytes: statfsAvailableBytes,
+		evicted:        make(map[podKey]string),
+	}
+}
+
+func statfsAvailableBytes(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
+
+// observe measures the memory and disk usage of the node, and records the conditions for
+// the thresholds that are crossed.
+// The cAdvisor this kubelet is built against reports no filesystem stats, so the disk usage
+// is measured with statfs.
+func (m *evictionManager) observe() []api.NodeCondition {
+	var conditions []api.NodeCondition
+	if m.thresholds.MemoryAvailable > 0 && m.cadvisor != nil {
+		available, err := m.memoryAvailable()
+		if err != nil {
+			glog.Errorf("Unable to get the memory usage of the node: %v", err)
+		} else if available < m.thresholds.MemoryAvailable {
+			conditions = append(conditions, api.NodeCondition{
+				Kind:   api.NodeMemoryPressure,
+				Reason: fmt.Sprintf("%d bytes of memory available, below the threshold of %d", available, m.thresholds.MemoryAvailable),
+			})
+		}
+	}
+	if m.thresholds.DiskAvailable > 0 {
+		for _, path := range m.paths {
+			available, err := m.availableBytes(path)
+			if err != nil {
+				glog.Errorf("Unable to get the disk usage of %s: %v", path, err)
+				continue
+			}
+			if available < m.thresholds.DiskAvailable {
+				conditions = append(conditions, api.NodeCondition{
+					Kind:   api.NodeDiskPressure,
+					Reason: fmt.Sprintf("%d bytes available on the filesystem of %s, below the threshold of %d", available, path, m.thresholds.DiskAvailable),
+				})
+				break
+			}
+		}
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.conditions = conditions
+	return conditions
+}
+
+func (m *evictionManager) memoryAvailable() (int64, error) {
+	machineInfo, err := m.cadvisor.MachineInfo()
+	if err != nil {
+		return 0, err
+	}
+	rootInfo, err := m.cadvisor.ContainerInfo("/", &info.ContainerInfoRequest{NumStats: 1})
+	if err != nil {
+		return 0, err
+	}
+	if len(rootInfo.Stats) == 0 || rootInfo.Stats[len(rootInfo.Stats)-1].Memory == nil {
+		return 0, fmt.Errorf("no memory stats for the root container")
+	}
+	return machineInfo.MemoryCapacity - int64(rootInfo.Stats[len(rootInfo.Stats)-1].Memory.WorkingSet), nil
+}
+
+// setPodMemory records the memory usage of the running pods.
+func (m *evictionManager) setPodMemory(podMemory map[podKey]uint64) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.podMemory = podMemory
+}
+
+// memoryUsage returns the memory usage of a pod as of the last observation.
+func (m *evictionManager) memoryUsage(key podKey) uint64 {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.podMemory[key]
+}
+
+// Conditions returns the node conditions found by the last observation.
+func (m *evictionManager) Conditions() []api.NodeCondition {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.conditions
+}
+
+func (m *evictionManager) evict(key podKey, reason string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.evicted[key] = reason
+}
+
+// evictionReason returns the reason a pod was evicted for, and whether it was evicted at all.
+// A blank uuid matches any pod with the same full name.
+func (m *evictionManager) evictionReason(key podKey) (string, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if key.uuid == "" {
+		for evictedKey, reason := range m.evicted {
+			if evictedKey.podFullName == key.podFullName {
+				return reason, true
+			}
+		}
+		return "", false
+	}
+	reason, found := m.evicted[key]
+	return reason, found
+}
+
+// retain forgets the evicted pods which are not in keys, e.g. because they were deleted.
+func (m *evictionManager) retain(keys map[podKey]empty) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for key := range m.evicted {
+		if _, found := keys[key]; !found {
+			delete(m.evicted, key)
+		}
+	}
+}
+
+// isBestEffort returns true if none of the containers of a pod requests memory or CPU.
+func isBestEffort(pod *Pod) bool {
+	for _, container := range pod.Manifest.Containers {
+		if container.Memory != 0 || container.CPU != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type evictionCandidate struct {
+	pod        *Pod
+	runningPod RunningPod
+	bestEffort bool
+	usage      uint64
+}
+
+// byEvictionOrder sorts the best-effort pods first, then the pods using the most of the
+// resource the node is under pressure for.
+type byEvictionOrder []evictionCandidate
+
+func (c byEvictionOrder) Len() int      { return len(c) }
+func (c byEvictionOrder) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c byEvictionOrder) Less(i, j int) bool {
+	if c[i].bestEffort != c[j].bestEffort {
+		return c[i].bestEffort
+	}
+	return c[i].usage > c[j].usage
+}
+
+// observeEviction observes the memory and disk usage of the node, and the memory usage of the
+// running pods, which the next syncs evict pods by. Run calls it every evictionPeriod.
+func (kl *Kubelet) observeEviction() {
+	kl.eviction.observe()
+	if kl.eviction.thresholds.MemoryAvailable == 0 || kl.cadvisorClient == nil {
+		return
+	}
+	runningPods, err := kl.runtime.GetPods(false)
+	if err != nil {
+		glog.Errorf("Unable to list the pods to observe their memory usage: %v", err)
+		return
+	}
+	podMemory := make(map[podKey]uint64)
+	for _, runningPod := range runningPods {
+		podMemory[podKey{runningPod.FullName, runningPod.UUID}] = kl.podMemoryUsage(*runningPod)
+	}
+	kl.eviction.setPodMemory(podMemory)
+}
+
+// podMemoryUsage returns the sum of the working sets of the running containers of a pod.
+func (kl *Kubelet) podMemoryUsage(runningPod RunningPod) uint64 {
+	var usage uint64
+	for _, container := range runningPod.Containers {
+		cinfo, err := kl.statsFromContainerPath(fmt.Sprintf("/docker/%s", container.ID), &info.ContainerInfoRequest{NumStats: 1})
+		if err != nil {
+			glog.V(1).Infof("Unable to get the stats of container %s: %v", container.ID, err)
+			continue
+		}
+		if len(cinfo.Stats) > 0 && cinfo.Stats[len(cinfo.Stats)-1].Memory != nil {
+			usage += cinfo.Stats[len(cinfo.Stats)-1].Memory.WorkingSet
+		}
+	}
+	return usage
+}
+
+// podDiskUsage returns the disk space used by the writable layers of the containers of a pod
+// and by its volumes, as of the last updateDiskUsage.
+func (kl *Kubelet) podDiskUsage(runningPod RunningPod) uint64 {
+	kl.cachedDiskUsage.lock.RLock()
+	defer kl.cachedDiskUsage.lock.RUnlock()
+	var usage uint64
+	for _, container := range runningPod.Containers {
+		if used := kl.cachedDiskUsage.containers[container.ID]; used > 0 {
+			usage += uint64(used)
+		}
+	}
+	for _, volume := range kl.cachedDiskUsage.volumes[podKey{runningPod.FullName, runningPod.UUID}] {
+		usage += volume.FilesystemUsedBytes
+	}
+	return usage
+}
+
+// volumeOverLimit returns the reason to evict a pod if one of its disk-backed EmptyDirectory
+// volumes used more space than its size limit as of the last updateDiskUsage. Memory-backed
+// volumes are limited by the size of their tmpfs instead.
+func (kl *Kubelet) volumeOverLimit(pod *Pod) (string, bool) {
+	kl.cachedDiskUsage.lock.RLock()
+	defer kl.cachedDiskUsage.lock.RUnlock()
+	used := make(map[string]uint64)
+	for _, volume := range kl.cachedDiskUsage.volumes[podKey{GetPodFullName(pod), pod.Manifest.UUID}] {
+		used[volume.Name] = volume.FilesystemUsedBytes
+	}
+	for _, vol := range pod.Manifest.Volumes {
+		if vol.Source == nil || vol.Source.EmptyDirectory == nil {
+			continue
+		}
+		emptyDir := vol.Source.EmptyDirectory
+		if emptyDir.SizeLimit == 0 || emptyDir.Medium == api.StorageMediumMemory {
+			continue
+		}
+		if used[vol.Name] > uint64(emptyDir.SizeLimit) {
+			return fmt.Sprintf("Evicted: volume %s uses %d bytes, above its size limit of %d", vol.Name, used[vol.Name], emptyDir.SizeLimit), true
+		}
+	}
+	return "", false
+}
+
+// evictPods kills the pods whose volumes grew beyond their size limits, and a pod if the node
+// is under memory or disk pressure, according to the last observations. The evicted pods are
+// not restarted until they are removed from the config of the kubelet. One pod is evicted per
+// sync because of pressure, so that the effect of an eviction is observed before evicting
+// another pod.
+func (kl *Kubelet) evictPods(pods []Pod, runningPods RunningPods) {
+	for i := range pods {
+		pod := &pods[i]
+		key := podKey{GetPodFullName(pod), pod.Manifest.UUID}
+		if _, evicted := kl.eviction.evictionReason(key); evicted {
+			continue
+		}
+		runningPod := runningPods.FindPod(key.podFullName, key.uuid)
+		if len(runningPod.Containers) == 0 {
+			continue
+		}
+		if reason, over := kl.volumeOverLimit(pod); over {
+			kl.evictPod(pod, runningPod, reason)
+		}
+	}
+
+	conditions := kl.eviction.Conditions()
+	if len(conditions) == 0 {
+		return
+	}
+	memoryPressure := conditions[0].Kind == api.NodeMemoryPressure
+	var candidates []evictionCandidate
+	for i := range pods {
+		pod := &pods[i]
+		key := podKey{GetPodFullName(pod), pod.Manifest.UUID}
+		if _, evicted := kl.eviction.evictionReason(key); evicted {
+			continue
+		}
+		runningPod := runningPods.FindPod(key.podFullName, key.uuid)
+		if len(runningPod.Containers) == 0 {
+			continue
+		}
+		candidate := evictionCandidate{
+			pod:        pod,
+			runningPod: runningPod,
+			bestEffort: isBestEffort(pod),
+		}
+		if memoryPressure {
+			candidate.usage = kl.eviction.memoryUsage(key)
+		} else {
+			candidate.usage = kl.podDiskUsage(runningPod)
+		}
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 {
+		glog.Warningf("The node is under pressure (%v), but there are no pods left to evict", conditions)
+		return
+	}
+	sort.Sort(byEvictionOrder(candidates))
+	victim := candidates[0]
+	kl.evictPod(victim.pod, victim.runningPod, fmt.Sprintf("Evicted: %s", conditions[0].Reason))
+}
+
+// evictPod records the eviction of a pod and kills it.
+func (kl *Kubelet) evictPod(pod *Pod, runningPod RunningPod, reason string) {
+	key := podKey{runningPod.FullName, runningPod.UUID}
+	glog.Warningf("Evicting pod %s: %s", key.podFullName, reason)
+	kl.eviction.evict(key, reason)
+	kl.podWorkers.Run(key.podFullName, key.uuid, runningPod, func(runningPod RunningPod) {
+		kl.killPod(pod, runningPod)
+	})
+}
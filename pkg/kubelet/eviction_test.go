@@ -0,0 +1,282 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/google/cadvisor/info"
+)
+
+func newTestEvictionManager(thresholds EvictionThresholds, diskAvailable int64) *evictionManager {
+	m := newEvictionManager(thresholds, nil, "/tmp/kubelet")
+	m.availableBytes = func(path string) (int64, error) {
+		return diskAvailable, nil
+	}
+	return m
+}
+
+func TestObserveMemoryPressure(t *testing.T) {
+	mockCadvisor := &mockCadvisorClient{}
+	mockCadvisor.On("MachineInfo").Return(&info.MachineInfo{MemoryCapacity: 1000}, nil)
+	mockCadvisor.On("ContainerInfo", "/", &info.ContainerInfoRequest{NumStats: 1}).Return(&info.ContainerInfo{
+		Stats: []*info.ContainerStats{{Memory: &info.MemoryStats{WorkingSet: 950}}},
+	}, nil)
+	m := newEvictionManager(EvictionThresholds{MemoryAvailable: 100}, mockCadvisor)
+
+	conditions := m.observe()
+	if len(conditions) != 1 || conditions[0].Kind != api.NodeMemoryPressure {
+		t.Errorf("expected memory pressure, got %#v", conditions)
+	}
+	if !reflect.DeepEqual(m.Conditions(), conditions) {
+		t.Errorf("expected the conditions to be recorded, got %#v", m.Conditions())
+	}
+	mockCadvisor.AssertExpectations(t)
+}
+
+func TestObserveDiskPressure(t *testing.T) {
+	tests := []struct {
+		thresholds EvictionThresholds
+		available  int64
+		pressure   bool
+	}{
+		{EvictionThresholds{}, 10, false},
+		{EvictionThresholds{DiskAvailable: 100}, 1000, false},
+		{EvictionThresholds{DiskAvailable: 100}, 10, true},
+	}
+	for i, test := range tests {
+		conditions := newTestEvictionManager(test.thresholds, test.available).observe()
+		if pressure := len(conditions) == 1 && conditions[0].Kind == api.NodeDiskPressure; pressure != test.pressure {
+			t.Errorf("%d: expected pressure %v, got %#v", i, test.pressure, conditions)
+		}
+	}
+}
+
+func TestEvictionOrder(t *testing.T) {
+	candidates := []evictionCandidate{
+		{runningPod: RunningPod{FullName: "small.test"}, bestEffort: false, usage: 10},
+		{runningPod: RunningPod{FullName: "big.test"}, bestEffort: false, usage: 100},
+		{runningPod: RunningPod{FullName: "besteffort.test"}, bestEffort: true, usage: 1},
+	}
+	sort.Sort(byEvictionOrder(candidates))
+	var order []string
+	for _, candidate := range candidates {
+		order = append(order, candidate.runningPod.FullName)
+	}
+	if !reflect.DeepEqual(order, []string{"besteffort.test", "big.test", "small.test"}) {
+		t.Errorf("unexpected eviction order: %v", order)
+	}
+}
+
+func TestSyncPodsEvictsBestEffortPods(t *testing.T) {
+	fakeRuntime := &FakeRuntime{
+		PodList: RunningPods{
+			{FullName: "guaranteed.test", Containers: []*RunningContainer{{ID: "1234", Name: "bar", Running: true}}},
+			{FullName: "besteffort.test", Containers: []*RunningContainer{{ID: "5678", Name: "bar", Running: true}}},
+		},
+	}
+	kubelet := &Kubelet{
+		runtime:       fakeRuntime,
+		readiness:     newReadinessStates(),
+		eviction:      newTestEvictionManager(EvictionThresholds{DiskAvailable: 100}, 10),
+		rootDirectory: "/tmp/kubelet",
+	}
+	kubelet.podWorkers = newPodWorkers(kubelet.listPod)
+	pods := []Pod{
+		{Name: "guaranteed", Namespace: "test", Manifest: api.ContainerManifest{ID: "guaranteed", Containers: []api.Container{{Name: "bar", Memory: 1024}}}},
+		{Name: "besteffort", Namespace: "test", Manifest: api.ContainerManifest{ID: "besteffort", Containers: []api.Container{{Name: "bar"}}}},
+	}
+	kubelet.observeEviction()
+	if err := kubelet.SyncPods(pods); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+
+	if !reflect.DeepEqual(fakeRuntime.KilledPods, []string{"besteffort.test"}) {
+		t.Errorf("unexpected killed pods: %v", fakeRuntime.KilledPods)
+	}
+	if !reflect.DeepEqual(fakeRuntime.SyncedPods, []string{"guaranteed.test"}) {
+		t.Errorf("expected the evicted pod not to be synced, got %v", fakeRuntime.SyncedPods)
+	}
+	conditions := kubelet.GetNodeConditions()
+	if len(conditions) != 1 || conditions[0].Kind != api.NodeDiskPressure {
+		t.Errorf("expected disk pressure, got %#v", conditions)
+	}
+
+	// The evicted pod is forgotten once it is removed from the config.
+	kubelet.eviction.availableBytes = func(path string) (int64, error) { return 1000, nil }
+	kubelet.observeEviction()
+	if err := kubelet.SyncPods(pods[:1]); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+	if _, evicted := kubelet.eviction.evictionReason(podKey{"besteffort.test", ""}); evicted {
+		t.Errorf("expected the eviction of the deleted pod to be forgotten")
+	}
+	if len(kubelet.GetNodeConditions()) != 0 {
+		t.Errorf("expected no conditions, got %#v", kubelet.GetNodeConditions())
+	}
+}
+
+func TestSyncPodsEvictsPodsOverVolumeLimits(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "VolumeLimits")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(rootDir)
+	fakeRuntime := &FakeRuntime{
+		PodList: RunningPods{
+			{FullName: "small.test", Containers: []*RunningContainer{{ID: "1234", Name: "bar", Running: true}}},
+			{FullName: "big.test", Containers: []*RunningContainer{{ID: "5678", Name: "bar", Running: true}}},
+			{FullName: "memory.test", Containers: []*RunningContainer{{ID: "9012", Name: "bar", Running: true}}},
+		},
+	}
+	kubelet := &Kubelet{
+		runtime:       fakeRuntime,
+		readiness:     newReadinessStates(),
+		eviction:      newTestEvictionManager(EvictionThresholds{}, 0),
+		rootDirectory: rootDir,
+	}
+	kubelet.podWorkers = newPodWorkers(kubelet.listPod)
+	var pods []Pod
+	for _, name := range []string{"small", "big", "memory"} {
+		source := &api.EmptyDirectory{SizeLimit: 100}
+		if name == "memory" {
+			source.Medium = api.StorageMediumMemory
+		}
+		pods = append(pods, Pod{Name: name, Namespace: "test", Manifest: api.ContainerManifest{
+			ID:         name,
+			Containers: []api.Container{{Name: "bar"}},
+			Volumes:    []api.Volume{{Name: "scratch", Source: &api.VolumeSource{EmptyDirectory: source}}},
+		}})
+	}
+	sizes := map[string]int{"small": 10, "big": 1000, "memory": 1000}
+	for name, size := range sizes {
+		dir := path.Join(rootDir, name, "volumes", "empty", "scratch")
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := ioutil.WriteFile(path.Join(dir, "file"), make([]byte, size), 0640); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// The usage of the volumes is measured once the pods are known.
+	if err := kubelet.SyncPods(pods); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+	kubelet.updateDiskUsage()
+	if err := kubelet.SyncPods(pods); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+
+	if !reflect.DeepEqual(fakeRuntime.KilledPods, []string{"big.test"}) {
+		t.Errorf("unexpected killed pods: %v", fakeRuntime.KilledPods)
+	}
+	reason, evicted := kubelet.eviction.evictionReason(podKey{"big.test", ""})
+	if !evicted || !strings.Contains(reason, "scratch") {
+		t.Errorf("expected the pod to be evicted because of its volume, got %q", reason)
+	}
+	if len(kubelet.GetNodeConditions()) != 0 {
+		t.Errorf("expected no conditions, got %#v", kubelet.GetNodeConditions())
+	}
+}
+
+func TestSyncPodsEvictsByTheResourceUnderPressure(t *testing.T) {
+	mockCadvisor := &mockCadvisorClient{}
+	mockCadvisor.On("MachineInfo").Return(&info.MachineInfo{MemoryCapacity: 1000}, nil)
+	mockCadvisor.On("ContainerInfo", "/", &info.ContainerInfoRequest{NumStats: 1}).Return(&info.ContainerInfo{
+		Stats: []*info.ContainerStats{{Memory: &info.MemoryStats{WorkingSet: 500}}},
+	}, nil)
+	for id, workingSet := range map[string]uint64{"1234": 400, "5678": 10} {
+		mockCadvisor.On("ContainerInfo", "/docker/"+id, &info.ContainerInfoRequest{NumStats: 1}).Return(&info.ContainerInfo{
+			Stats: []*info.ContainerStats{{Memory: &info.MemoryStats{WorkingSet: workingSet}}},
+		}, nil)
+	}
+	pods := []Pod{
+		{Name: "memory", Namespace: "test", Manifest: api.ContainerManifest{ID: "memory", Containers: []api.Container{{Name: "bar", Memory: 1024}}}},
+		{Name: "disk", Namespace: "test", Manifest: api.ContainerManifest{ID: "disk", Containers: []api.Container{{Name: "bar", Memory: 1024}}}},
+	}
+	tests := []struct {
+		thresholds    EvictionThresholds
+		diskAvailable int64
+		evicted       string
+	}{
+		{EvictionThresholds{MemoryAvailable: 600}, 1000, "memory.test"},
+		{EvictionThresholds{DiskAvailable: 100}, 10, "disk.test"},
+	}
+	for i, test := range tests {
+		// The pod using the most memory uses the least disk space.
+		fakeRuntime := &FakeRuntime{
+			PodList: RunningPods{
+				{FullName: "memory.test", Containers: []*RunningContainer{{ID: "1234", Name: "bar", Running: true}}},
+				{FullName: "disk.test", Containers: []*RunningContainer{{ID: "5678", Name: "bar", Running: true}}},
+			},
+			DiskUsage: map[string]int64{"1234": 10, "5678": 400},
+		}
+		kubelet := &Kubelet{
+			runtime:        fakeRuntime,
+			cadvisorClient: mockCadvisor,
+			readiness:      newReadinessStates(),
+			eviction:       newTestEvictionManager(test.thresholds, test.diskAvailable),
+			rootDirectory:  "/tmp/kubelet",
+		}
+		kubelet.eviction.cadvisor = mockCadvisor
+		kubelet.podWorkers = newPodWorkers(kubelet.listPod)
+		kubelet.updateDiskUsage()
+		kubelet.observeEviction()
+		if err := kubelet.SyncPods(pods); err != nil {
+			t.Errorf("%d: unexpected error: %v", i, err)
+		}
+		kubelet.drainWorkers()
+		if !reflect.DeepEqual(fakeRuntime.KilledPods, []string{test.evicted}) {
+			t.Errorf("%d: expected %s to be evicted, got %v", i, test.evicted, fakeRuntime.KilledPods)
+		}
+	}
+}
+
+func TestGetPodInfoReportsEviction(t *testing.T) {
+	fakeRuntime := &FakeRuntime{
+		PodInfo: map[string]api.PodInfo{
+			"foo.test": {
+				"bar": {State: api.ContainerState{Termination: &api.ContainerStateTerminated{ExitCode: 137}}},
+			},
+		},
+	}
+	kubelet := &Kubelet{
+		runtime:   fakeRuntime,
+		readiness: newReadinessStates(),
+		eviction:  newTestEvictionManager(EvictionThresholds{}, 0),
+	}
+	kubelet.eviction.evict(podKey{"foo.test", "1234"}, "Evicted: low on disk")
+
+	podInfo, err := kubelet.GetPodInfo("foo.test", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if termination := podInfo["bar"].State.Termination; termination == nil || termination.Reason != "Evicted: low on disk" {
+		t.Errorf("expected the eviction reason to be reported, got %#v", podInfo)
+	}
+}
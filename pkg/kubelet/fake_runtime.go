@@ -0,0 +1,183 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
+)
+
+// FakeRuntime is an in-memory implementation of Runtime, so that the kubelet can be tested
+// without containers. Synced pods are added to PodList, killed pods are removed from it.
+type FakeRuntime struct {
+	sync.Mutex
+	PodList    RunningPods
+	PodInfo    map[string]api.PodInfo
+	Logs       string
+	Err        error
+	called     []string
+	SyncedPods []string
+	// The running pods SyncPod was called with, in order.
+	SyncedRunningPods []RunningPod
+	KilledPods        []string
+	KilledSpecs       []*Pod
+	ImagesPulled      []string
+	Commands          [][]string
+	DiskUsage         map[string]int64
+}
+
+// AssertCalls checks that the runtime methods were called in the given order.
+func (f *FakeRuntime) AssertCalls(calls []string) error {
+	f.Lock()
+	defer f.Unlock()
+	if !reflect.DeepEqual(calls, f.called) {
+		return fmt.Errorf("expected %#v, got %#v", calls, f.called)
+	}
+	return nil
+}
+
+// GetPods is a test-spy implementation of Runtime.GetPods.
+// It adds an entry "GetPods" to the internal method call record.
+func (f *FakeRuntime) GetPods(all bool) (RunningPods, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.called = append(f.called, "GetPods")
+	return append(RunningPods{}, f.PodList...), f.Err
+}
+
+// SyncPod is a test-spy implementation of Runtime.SyncPod.
+// It adds an entry "SyncPod" to the internal method call record.
+func (f *FakeRuntime) SyncPod(pod *Pod, runningPod RunningPod, podVolumes volumeMap) error {
+	f.Lock()
+	defer f.Unlock()
+	f.called = append(f.called, "SyncPod")
+	podFullName := GetPodFullName(pod)
+	f.SyncedPods = append(f.SyncedPods, podFullName)
+	f.SyncedRunningPods = append(f.SyncedRunningPods, runningPod)
+	if f.Err != nil {
+		return f.Err
+	}
+	synced := &RunningPod{FullName: podFullName, UUID: pod.Manifest.UUID}
+	for _, container := range pod.Manifest.Containers {
+		synced.Containers = append(synced.Containers, &RunningContainer{
+			ID:      podFullName + "/" + container.Name,
+			Name:    container.Name,
+			Hash:    dockertools.HashContainer(&container),
+			Running: true,
+		})
+	}
+	for i, existing := range f.PodList {
+		if existing.FullName == synced.FullName && existing.UUID == synced.UUID {
+			f.PodList[i] = synced
+			return nil
+		}
+	}
+	f.PodList = append(f.PodList, synced)
+	return nil
+}
+
+// KillPod is a test-spy implementation of Runtime.KillPod.
+// It adds an entry "KillPod" to the internal method call record.
+func (f *FakeRuntime) KillPod(pod *Pod, runningPod RunningPod) error {
+	f.Lock()
+	defer f.Unlock()
+	f.called = append(f.called, "KillPod")
+	f.KilledPods = append(f.KilledPods, runningPod.FullName)
+	f.KilledSpecs = append(f.KilledSpecs, pod)
+	if f.Err != nil {
+		return f.Err
+	}
+	var remaining RunningPods
+	for _, pod := range f.PodList {
+		if pod.FullName != runningPod.FullName || pod.UUID != runningPod.UUID {
+			remaining = append(remaining, pod)
+		}
+	}
+	f.PodList = remaining
+	return nil
+}
+
+// GetPodInfo is a test-spy implementation of Runtime.GetPodInfo.
+// It adds an entry "GetPodInfo" to the internal method call record.
+func (f *FakeRuntime) GetPodInfo(podFullName, uuid string) (api.PodInfo, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.called = append(f.called, "GetPodInfo")
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	info, found := f.PodInfo[podFullName]
+	if !found {
+		return nil, dockertools.ErrNoContainersInPod
+	}
+	return info, nil
+}
+
+// GetContainerLogs is a test-spy implementation of Runtime.GetContainerLogs.
+// It adds an entry "GetContainerLogs" to the internal method call record, and writes Logs to stdout.
+func (f *FakeRuntime) GetContainerLogs(podFullName, containerName string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) error {
+	f.Lock()
+	defer f.Unlock()
+	f.called = append(f.called, "GetContainerLogs")
+	if f.Err != nil {
+		return f.Err
+	}
+	_, err := io.WriteString(stdout, f.Logs)
+	return err
+}
+
+// RunInContainer is a test-spy implementation of Runtime.RunInContainer.
+// It adds an entry "RunInContainer" to the internal method call record.
+func (f *FakeRuntime) RunInContainer(containerID string, cmd []string) ([]byte, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.called = append(f.called, "RunInContainer")
+	f.Commands = append(f.Commands, cmd)
+	return []byte{}, f.Err
+}
+
+// PullImage is a test-spy implementation of Runtime.PullImage.
+// It adds an entry "PullImage" to the internal method call record.
+func (f *FakeRuntime) PullImage(image string, dockercfgs [][]byte) error {
+	f.Lock()
+	defer f.Unlock()
+	f.called = append(f.called, "PullImage")
+	f.ImagesPulled = append(f.ImagesPulled, image)
+	return f.Err
+}
+
+// CleanupPods is a test-spy implementation of Runtime.CleanupPods.
+// It adds an entry "CleanupPods" to the internal method call record.
+func (f *FakeRuntime) CleanupPods(runningPods RunningPods) {
+	f.Lock()
+	defer f.Unlock()
+	f.called = append(f.called, "CleanupPods")
+}
+
+// ContainerDiskUsage is a test-spy implementation of Runtime.ContainerDiskUsage.
+// It adds an entry "ContainerDiskUsage" to the internal method call record, and returns DiskUsage.
+func (f *FakeRuntime) ContainerDiskUsage() (map[string]int64, error) {
+	f.Lock()
+	defer f.Unlock()
+	f.called = append(f.called, "ContainerDiskUsage")
+	return f.DiskUsage, f.Err
+}
@@ -17,11 +17,17 @@ limitations under the License.
 package kubelet
 
 import (
+	"bytes"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"path"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -29,9 +35,9 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/validation"
-	"github.com/GoogleCloudPlatform/kubernetes/pkg/capabilities"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/health"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/network"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/volume"
@@ -42,6 +48,10 @@ import (
 
 const defaultChanSize = 1024
 
+// The restored pods of the config sources which haven't delivered their pods by then are
+// no longer desired.
+const defaultPendingSourcesTimeout = 5 * time.Minute
+
 // taken from lmctfy https://github.com/google/lmctfy/blob/master/lmctfy/controllers/cpu_controller.cc
 const minShares = 2
 const sharesPerCPU = 1024
@@ -56,6 +66,8 @@ type CadvisorInterface interface {
 // SyncHandler is an interface implemented by Kubelet, for testability
 type SyncHandler interface {
 	SyncPods([]Pod) error
+	// SyncChangedPods is like SyncPods, but only syncs the pods whose full names are in changed.
+	SyncChangedPods(pods []Pod, changed util.StringSet) error
 }
 
 type volumeMap map[string]volume.Interface
@@ -67,30 +79,52 @@ func NewMainKubelet(
 	cc CadvisorInterface,
 	ec tools.EtcdClient,
 	rd string,
-	ri time.Duration) *Kubelet {
-	return &Kubelet{
+	ri time.Duration,
+	rp time.Duration,
+	et EvictionThresholds,
+	np network.Plugin,
+	clusterDNS net.IP,
+	clusterDomain string,
+	volumePlugins *volume.PluginRegistry) *Kubelet {
+	kl := &Kubelet{
 		hostname:       hn,
-		dockerClient:   dc,
 		cadvisorClient: cc,
 		etcdClient:     ec,
 		rootDirectory:  rd,
 		resyncInterval: ri,
-		podWorkers:     newPodWorkers(),
-		runner:         dockertools.NewDockerContainerCommandRunner(),
+		readiness:      newReadinessStates(),
+		eviction:       newEvictionManager(et, cc, rd, defaultDockerRoot),
 		httpClient:     &http.Client{},
+		clusterDNS:     clusterDNS,
+		clusterDomain:  clusterDomain,
+		volumePlugins:  volumePlugins,
+	}
+	kl.podWorkers = newPodWorkers(kl.listPod)
+	kl.pendingSourcesTimeout = defaultPendingSourcesTimeout
+	if ec != nil {
+		kl.mirrorPods = newMirrorPods(hn, ec)
+		kl.secrets = newEtcdSecrets(ec)
 	}
+	runtime := newDockerRuntime(dc, dockertools.NewDockerPuller(dc), dockertools.NewDockerContainerCommandRunner(), kl)
+	runtime.network = np
+	kl.runtime = runtime
+	kl.pleg = newPodLifecycleEventGenerator(kl.runtime, rp)
+	return kl
 }
 
 // NewIntegrationTestKubelet creates a new Kubelet for use in integration tests.
 // TODO: add more integration tests, and expand parameter list as needed.
 func NewIntegrationTestKubelet(hn string, dc dockertools.DockerInterface) *Kubelet {
-	return &Kubelet{
+	kl := &Kubelet{
 		hostname:       hn,
-		dockerClient:   dc,
-		dockerPuller:   &dockertools.FakeDockerPuller{},
 		resyncInterval: 3 * time.Second,
-		podWorkers:     newPodWorkers(),
+		readiness:      newReadinessStates(),
 	}
+	kl.podWorkers = newPodWorkers(kl.listPod)
+	kl.volumePlugins, _ = volume.NewPluginRegistry(volume.DefaultPlugins()...)
+	kl.runtime = newDockerRuntime(dc, &dockertools.FakeDockerPuller{}, nil, kl)
+	kl.pleg = newPodLifecycleEventGenerator(kl.runtime, time.Second)
+	return kl
 }
 
 type httpGetInterface interface {
@@ -100,23 +134,51 @@ type httpGetInterface interface {
 // Kubelet is the main kubelet implementation.
 type Kubelet struct {
 	hostname       string
-	dockerClient   dockertools.DockerInterface
+	runtime        Runtime
 	rootDirectory  string
 	podWorkers     podWorkers
 	resyncInterval time.Duration
+	// How long the pods restored from the checkpoint are kept for the config sources which
+	// haven't delivered their pods. Optional, they are kept until the sources deliver them without it.
+	pendingSourcesTimeout time.Duration
+	// Generates the container events which trigger the sync of a single pod.
+	pleg *podLifecycleEventGenerator
+	// The last known spec of each pod, kept until the pod is killed so that the PreStop handlers
+	// of its containers can run. Only replaced by syncPods, the lock guards the replacement.
+	podSpecsLock sync.RWMutex
+	podSpecs     map[podKey]*Pod
+	// The keys of the pods in podSpecs which are desired.
+	desiredPods map[podKey]empty
+	// The results of the readiness probes of the containers.
+	readiness *readinessStates
+	// Runs the probes of the containers. Optional, containers are never probed without it.
+	prober *prober
+	// Optional, pods are never evicted without it.
+	eviction *evictionManager
+
+	// Creates the volumes of the pods. Optional, pods can't have volumes with a source without it.
+	volumePlugins *volume.PluginRegistry
 
 	// Optional, no events will be sent without it
 	etcdClient tools.EtcdClient
+	// Optional, static pods are not mirrored to the apiserver without it.
+	mirrorPods *mirrorPods
+	// Optional, pods with image pull secrets can't pull their images without it.
+	secrets *etcdSecrets
+	// Optional, pods use the resolver of the node without it.
+	clusterDNS net.IP
+	// The domain searched first by the pods using the cluster DNS.
+	clusterDomain string
+	// Optional, defaults to /etc/resolv.conf.
+	resolverConfig string
 	// Optional, no statistics will be available if omitted
 	cadvisorClient CadvisorInterface
+	// The disk usage reported by GetSummary, refreshed every diskUsagePeriod by Run.
+	cachedDiskUsage diskUsageCache
 	// Optional, defaults to simple implementaiton
 	healthChecker health.HealthChecker
-	// Optional, defaults to simple Docker implementation
-	dockerPuller dockertools.DockerPuller
 	// Optional, defaults to /logs/ from /var/log
 	logServer http.Handler
-	// Optional, defaults to simple Docker implementation
-	runner dockertools.ContainerCommandRunner
 	// Optional, client for http requests, defaults to empty client
 	httpClient httpGetInterface
 }
@@ -126,37 +188,64 @@ func (kl *Kubelet) Run(updates <-chan PodUpdate) {
 	if kl.logServer == nil {
 		kl.logServer = http.StripPrefix("/logs/", http.FileServer(http.Dir("/var/log/")))
 	}
-	if kl.dockerPuller == nil {
-		kl.dockerPuller = dockertools.NewDockerPuller(kl.dockerClient)
-	}
 	if kl.healthChecker == nil {
 		kl.healthChecker = health.NewHealthChecker()
 	}
-	kl.syncLoop(updates, kl)
+	kl.prober = newProber(kl.healthChecker, kl.readiness)
+	go util.Forever(kl.updateDiskUsage, diskUsagePeriod)
+	if kl.eviction != nil {
+		go util.Forever(kl.observeEviction, evictionPeriod)
+	}
+	kl.pleg.Start()
+	kl.syncLoop(updates, kl.pleg.Watch(), kl)
 }
 
-// Per-pod workers.
+// Per-pod workers. The actions of the pods which share a full name are serialized, since the
+// containers of the new instance of a pod need the ports of the old one.
 type podWorkers struct {
 	lock sync.Mutex
 
 	// Set of pods with existing workers.
 	workers util.StringSet
+	// The actions requested for the pods while their workers were running, in order.
+	pending map[string][]pendingAction
+	// listPod lists the containers of a pod again for a pending action, since the actions
+	// which ran before it may have changed them.
+	listPod func(podFullName, uuid string) (RunningPod, error)
 }
 
-func newPodWorkers() podWorkers {
+// pendingAction is an action requested for the instance of a pod with the given UUID.
+type pendingAction struct {
+	uuid   string
+	action func(runningPod RunningPod)
+}
+
+func newPodWorkers(listPod func(podFullName, uuid string) (RunningPod, error)) podWorkers {
 	return podWorkers{
 		workers: util.NewStringSet(),
+		pending: make(map[string][]pendingAction),
+		listPod: listPod,
 	}
 }
 
-// Runs a worker for "podFullName" asynchronously with the specified "action".
-// If the worker for the "podFullName" is already running, functions as a no-op.
-func (self *podWorkers) Run(podFullName string, action func()) {
+// Runs a worker for "podFullName" asynchronously with the specified "action", given the
+// containers of the instance of the pod with the given UUID. If the worker for "podFullName"
+// is already running, the action is queued, replacing any other pending action for the same
+// instance, and is run once the worker finishes the actions before it.
+func (self *podWorkers) Run(podFullName, uuid string, runningPod RunningPod, action func(runningPod RunningPod)) {
 	self.lock.Lock()
 	defer self.lock.Unlock()
 
-	// This worker is already running, let it finish.
+	// This worker is already running, let it finish first.
 	if self.workers.Has(podFullName) {
+		queue := self.pending[podFullName]
+		for i := range queue {
+			if queue[i].uuid == uuid {
+				queue[i].action = action
+				return
+			}
+		}
+		self.pending[podFullName] = append(queue, pendingAction{uuid, action})
 		return
 	}
 	self.workers.Insert(podFullName)
@@ -164,12 +253,35 @@ func (self *podWorkers) Run(podFullName string, action func()) {
 	// Run worker async.
 	go func() {
 		defer util.HandleCrash()
-		action()
+		for action != nil {
+			action(runningPod)
+			action, runningPod = self.next(podFullName)
+		}
+	}()
+}
 
+// next returns the next pending action for "podFullName" and the containers of its pod, or nil
+// once no action is pending and the worker is done.
+func (self *podWorkers) next(podFullName string) (func(runningPod RunningPod), RunningPod) {
+	for {
 		self.lock.Lock()
-		defer self.lock.Unlock()
-		self.workers.Delete(podFullName)
-	}()
+		queue := self.pending[podFullName]
+		if len(queue) == 0 {
+			delete(self.pending, podFullName)
+			self.workers.Delete(podFullName)
+			self.lock.Unlock()
+			return nil, RunningPod{}
+		}
+		next := queue[0]
+		self.pending[podFullName] = queue[1:]
+		self.lock.Unlock()
+
+		runningPod, err := self.listPod(podFullName, next.uuid)
+		if err == nil {
+			return next.action, runningPod
+		}
+		glog.Errorf("Error listing containers: %v", err)
+	}
 }
 
 // LogEvent reports an event.
@@ -177,28 +289,146 @@ func (kl *Kubelet) LogEvent(event *api.Event) error {
 	return nil
 }
 
-func makeEnvironmentVariables(container *api.Container) []string {
+// makeEnvironmentVariables returns the environment of a container of a pod running on host, with
+// the values of the variables which refer to the fields of the pod resolved. podIP is "" unless
+// the network container of the pod is running, or the pod is on the host network.
+func makeEnvironmentVariables(pod *Pod, container *api.Container, host, podIP string) ([]string, error) {
 	var result []string
 	for _, value := range container.Env {
+		if value.ValueFrom != nil {
+			fieldValue, err := podFieldValue(pod, host, podIP, value.ValueFrom.FieldPath)
+			if err != nil {
+				return nil, fmt.Errorf("unable to set environment variable %s: %v", value.Name, err)
+			}
+			value.Value = fieldValue
+		}
 		result = append(result, fmt.Sprintf("%s=%s", value.Name, value.Value))
 	}
-	return result
+	return result, nil
+}
+
+// usesPodField returns true if an environment variable of a container refers to a field of its pod.
+func usesPodField(container *api.Container, fieldPath string) bool {
+	for _, value := range container.Env {
+		if value.ValueFrom != nil && value.ValueFrom.FieldPath == fieldPath {
+			return true
+		}
+	}
+	return false
+}
+
+// podFieldValue returns the value of a field of a pod running on host, as exposed to its
+// containers by the downward API: "id", "host", "podIP", "labels" or "labels.<KEY>".
+func podFieldValue(pod *Pod, host, podIP, fieldPath string) (string, error) {
+	switch {
+	case fieldPath == "id":
+		return pod.Manifest.ID, nil
+	case fieldPath == "host":
+		return host, nil
+	case fieldPath == "podIP":
+		return podIP, nil
+	case fieldPath == "labels":
+		return formatLabels(pod.Manifest.Labels), nil
+	case strings.HasPrefix(fieldPath, "labels."):
+		return pod.Manifest.Labels[strings.TrimPrefix(fieldPath, "labels.")], nil
+	}
+	return "", fmt.Errorf("unsupported field path %q", fieldPath)
 }
 
-func makeBinds(pod *Pod, container *api.Container, podVolumes volumeMap) []string {
+// formatLabels returns the labels as key="value" lines, sorted by key.
+func formatLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buffer bytes.Buffer
+	for _, key := range keys {
+		fmt.Fprintf(&buffer, "%s=%s\n", key, strconv.Quote(labels[key]))
+	}
+	return buffer.String()
+}
+
+// makeBinds returns the Docker binds of the volume mounts of a container. The volumes mounted
+// with a subPath are mounted by mountSubPath, which returns the path to bind instead of the
+// volume, given the index of the mount.
+func makeBinds(pod *Pod, container *api.Container, podVolumes volumeMap, mountSubPath func(index int, volumePath, subPath string) (string, error)) ([]string, error) {
 	binds := []string{}
-	for _, mount := range container.VolumeMounts {
+	for i, mount := range container.VolumeMounts {
 		vol, ok := podVolumes[mount.Name]
 		if !ok {
 			continue
 		}
-		b := fmt.Sprintf("%s:%s", vol.GetPath(), mount.MountPath)
-		if mount.ReadOnly {
-			b += ":ro"
+		hostPath := vol.GetPath()
+		if mount.SubPath != "" {
+			// The kubelet replaces the files of these volumes, subdirectories included.
+			if volume.IsReadOnly(vol) {
+				return nil, fmt.Errorf("unable to mount volume %s: its files are written by the kubelet, it can't be mounted with a subPath", mount.Name)
+			}
+			var err error
+			hostPath, err = mountSubPath(i, hostPath, mount.SubPath)
+			if err != nil {
+				return nil, fmt.Errorf("unable to mount volume %s: %v", mount.Name, err)
+			}
+		}
+		var options []string
+		// The files of some volumes are written by the kubelet, whatever the mount says.
+		if mount.ReadOnly || volume.IsReadOnly(vol) {
+			options = append(options, "ro")
+		}
+		switch mount.MountPropagation {
+		case api.MountPropagationHostToContainer:
+			options = append(options, "rslave")
+		case api.MountPropagationBidirectional:
+			options = append(options, "rshared")
+		}
+		b := fmt.Sprintf("%s:%s", hostPath, mount.MountPath)
+		if len(options) > 0 {
+			b += ":" + strings.Join(options, ",")
 		}
 		binds = append(binds, b)
 	}
-	return binds
+	return binds, nil
+}
+
+// subPathsDir returns the directory of the subPath mounts of the volumes of the pod with the
+// given ID, which the containers of the pod can't write to.
+func (kl *Kubelet) subPathsDir(podID string) string {
+	return path.Join(kl.rootDirectory, podID, "volume-subpaths")
+}
+
+// mountSubPath mounts the directory subPath of the volume at volumePath on
+// (ROOT)/(POD_ID)/volume-subpaths/(CONTAINER)/(MOUNT INDEX), and returns the path of the mount.
+// Docker resolves the path it binds when the container starts, so the container must not be
+// able to replace any directory along it.
+func (kl *Kubelet) mountSubPath(pod *Pod, container *api.Container, index int, volumePath, subPath string) (string, error) {
+	target := path.Join(kl.subPathsDir(pod.Manifest.ID), container.Name, strconv.Itoa(index))
+	if err := volume.MountSubPath(volumePath, subPath, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// unmountSubPaths unmounts the subPath mounts of the pods which are no longer desired, before
+// their volumes are torn down.
+func (kl *Kubelet) unmountSubPaths(pods []Pod) {
+	desired := util.StringSet{}
+	for i := range pods {
+		desired.Insert(pods[i].Manifest.ID)
+	}
+	podIDDirs, err := ioutil.ReadDir(kl.rootDirectory)
+	if err != nil {
+		glog.Errorf("Could not read directory: %s, (%s)", kl.rootDirectory, err)
+		return
+	}
+	for _, podIDDir := range podIDDirs {
+		if !podIDDir.IsDir() || desired.Has(podIDDir.Name()) {
+			continue
+		}
+		if err := volume.UnmountSubPaths(kl.subPathsDir(podIDDir.Name())); err != nil {
+			glog.Errorf("Could not unmount the subPaths of pod %s (%s)", podIDDir.Name(), err)
+		}
+	}
 }
 
 func makePortsAndBindings(container *api.Container) (map[docker.Port]struct{}, map[docker.Port][]docker.PortBinding) {
@@ -251,7 +481,7 @@ func milliCPUToShares(milliCPU int) int {
 func (kl *Kubelet) mountExternalVolumes(manifest *api.ContainerManifest) (volumeMap, error) {
 	podVolumes := make(volumeMap)
 	for _, vol := range manifest.Volumes {
-		extVolume, err := volume.CreateVolumeBuilder(&vol, manifest.ID, kl.rootDirectory)
+		extVolume, err := kl.volumePlugins.CreateVolumeBuilder(&vol, manifest.ID, kl.rootDirectory)
 		if err != nil {
 			return nil, err
 		}
@@ -294,267 +524,37 @@ func (kl *Kubelet) runHandler(podFullName, uuid string, container *api.Container
 	return actionHandler.Run(podFullName, uuid, container, handler)
 }
 
-// Run a single container from a pod. Returns the docker container ID
-func (kl *Kubelet) runContainer(pod *Pod, container *api.Container, podVolumes volumeMap, netMode string) (id dockertools.DockerID, err error) {
-	envVariables := makeEnvironmentVariables(container)
-	binds := makeBinds(pod, container, podVolumes)
-	exposedPorts, portBindings := makePortsAndBindings(container)
-
-	opts := docker.CreateContainerOptions{
-		Name: dockertools.BuildDockerName(pod.Manifest.UUID, GetPodFullName(pod), container),
-		Config: &docker.Config{
-			Cmd:          container.Command,
-			Env:          envVariables,
-			ExposedPorts: exposedPorts,
-			Hostname:     pod.Name,
-			Image:        container.Image,
-			Memory:       int64(container.Memory),
-			CpuShares:    int64(milliCPUToShares(container.CPU)),
-			WorkingDir:   container.WorkingDir,
-		},
-	}
-	dockerContainer, err := kl.dockerClient.CreateContainer(opts)
-	if err != nil {
-		return "", err
-	}
-	privileged := false
-	if capabilities.Get().AllowPrivileged {
-		privileged = container.Privileged
-	} else if container.Privileged {
-		return "", fmt.Errorf("Container requested privileged mode, but it is disallowed globally.")
-	}
-	err = kl.dockerClient.StartContainer(dockerContainer.ID, &docker.HostConfig{
-		PortBindings: portBindings,
-		Binds:        binds,
-		NetworkMode:  netMode,
-		Privileged:   privileged,
-	})
-	if err == nil && container.Lifecycle != nil && container.Lifecycle.PostStart != nil {
-		handlerErr := kl.runHandler(GetPodFullName(pod), pod.Manifest.UUID, container, container.Lifecycle.PostStart)
-		if handlerErr != nil {
-			kl.killContainerByID(dockerContainer.ID, "")
-			return dockertools.DockerID(""), fmt.Errorf("failed to call event handler: %v", handlerErr)
-		}
-	}
-	return dockertools.DockerID(dockerContainer.ID), err
-}
-
-// Kill a docker container
-func (kl *Kubelet) killContainer(dockerContainer *docker.APIContainers) error {
-	return kl.killContainerByID(dockerContainer.ID, dockerContainer.Names[0])
-}
-
-func (kl *Kubelet) killContainerByID(ID, name string) error {
-	glog.V(2).Infof("Killing: %s", ID)
-	err := kl.dockerClient.StopContainer(ID, 10)
-	if len(name) == 0 {
-		return err
-	}
-
-	// TODO(lavalamp): restore event logging:
-	// podFullName, uuid, containerName, _ := dockertools.ParseDockerName(name)
-	// kl.LogEvent(&api.Event{})
-
-	return err
-}
-
-const (
-	networkContainerName  = "net"
-	networkContainerImage = "kubernetes/pause:latest"
-)
-
-// createNetworkContainer starts the network container for a pod. Returns the docker container ID of the newly created container.
-func (kl *Kubelet) createNetworkContainer(pod *Pod) (dockertools.DockerID, error) {
-	var ports []api.Port
-	// Docker only exports ports from the network container.  Let's
-	// collect all of the relevant ports and export them.
-	for _, container := range pod.Manifest.Containers {
-		ports = append(ports, container.Ports...)
-	}
-	container := &api.Container{
-		Name:  networkContainerName,
-		Image: networkContainerImage,
-		Ports: ports,
-	}
-	kl.dockerPuller.Pull(networkContainerImage)
-	return kl.runContainer(pod, container, nil, "")
-}
-
-// Delete all containers in a pod (except the network container) returns the number of containers deleted
-// and an error if one occurs.
-func (kl *Kubelet) deleteAllContainers(pod *Pod, podFullName string, dockerContainers dockertools.DockerContainers) (int, error) {
-	count := 0
-	errs := make(chan error, len(pod.Manifest.Containers))
-	wg := sync.WaitGroup{}
-	for _, container := range pod.Manifest.Containers {
-		if dockerContainer, found, _ := dockerContainers.FindPodContainer(podFullName, pod.Manifest.UUID, container.Name); found {
-			count++
-			wg.Add(1)
-			go func() {
-				err := kl.killContainer(dockerContainer)
-				if err != nil {
-					glog.Errorf("Failed to delete container. (%v)  Skipping pod %s", err, podFullName)
-					errs <- err
-				}
-				wg.Done()
-			}()
-		}
-	}
-	wg.Wait()
-	close(errs)
-	if len(errs) > 0 {
-		errList := []error{}
-		for err := range errs {
-			errList = append(errList, err)
-		}
-		return -1, fmt.Errorf("failed to delete containers (%v)", errList)
-	}
-	return count, nil
-}
-
 type empty struct{}
 
-func (kl *Kubelet) syncPod(pod *Pod, dockerContainers dockertools.DockerContainers) error {
-	podFullName := GetPodFullName(pod)
-	uuid := pod.Manifest.UUID
-	containersToKeep := make(map[dockertools.DockerID]empty)
-	killedContainers := make(map[dockertools.DockerID]empty)
-
-	// Make sure we have a network container
-	var netID dockertools.DockerID
-	if networkDockerContainer, found, _ := dockerContainers.FindPodContainer(podFullName, uuid, networkContainerName); found {
-		netID = dockertools.DockerID(networkDockerContainer.ID)
-	} else {
-		glog.V(3).Infof("Network container doesn't exist, creating")
-		count, err := kl.deleteAllContainers(pod, podFullName, dockerContainers)
-		if err != nil {
-			return err
-		}
-		dockerNetworkID, err := kl.createNetworkContainer(pod)
-		if err != nil {
-			glog.Errorf("Failed to introspect network container. (%v)  Skipping pod %s", err, podFullName)
-			return err
-		}
-		netID = dockerNetworkID
-		if count > 0 {
-			// relist everything, otherwise we'll think we're ok
-			dockerContainers, err = dockertools.GetKubeletDockerContainers(kl.dockerClient)
-			if err != nil {
-				glog.Errorf("Error listing containers %#v", dockerContainers)
-				return err
-			}
-		}
-	}
-	containersToKeep[netID] = empty{}
-
+// syncPod brings the containers of a pod, runningPod, in line with its manifest.
+func (kl *Kubelet) syncPod(pod *Pod, runningPod RunningPod) error {
 	podVolumes, err := kl.mountExternalVolumes(&pod.Manifest)
 	if err != nil {
-		glog.Errorf("Unable to mount volumes for pod %s: (%v) Skipping pod.", podFullName, err)
+		glog.Errorf("Unable to mount volumes for pod %s: (%v) Skipping pod.", GetPodFullName(pod), err)
 		return err
 	}
+	return kl.runtime.SyncPod(pod, runningPod, podVolumes)
+}
 
-	podState := api.PodState{Manifest: api.ContainerManifest{UUID: uuid}}
-	info, err := kl.GetPodInfo(podFullName, uuid)
+// listPod lists the containers of the instance of a pod with the given UUID.
+func (kl *Kubelet) listPod(podFullName, uuid string) (RunningPod, error) {
+	runningPods, err := kl.runtime.GetPods(false)
 	if err != nil {
-		glog.Errorf("Unable to get pod with name %s and uuid %s info, health checks may be invalid.",
-			podFullName, uuid)
-	}
-	netInfo, found := info[networkContainerName]
-	if found && netInfo.DetailInfo.NetworkSettings != nil {
-		podState.PodIP = netInfo.DetailInfo.NetworkSettings.IPAddress
-	}
-
-	for _, container := range pod.Manifest.Containers {
-		expectedHash := dockertools.HashContainer(&container)
-		if dockerContainer, found, hash := dockerContainers.FindPodContainer(podFullName, uuid, container.Name); found {
-			containerID := dockertools.DockerID(dockerContainer.ID)
-			glog.V(3).Infof("pod %s container %s exists as %v", podFullName, container.Name, containerID)
-
-			// look for changes in the container.
-			if hash == 0 || hash == expectedHash {
-				// TODO: This should probably be separated out into a separate goroutine.
-				healthy, err := kl.healthy(podFullName, podState, container, dockerContainer)
-				if err != nil {
-					glog.V(1).Infof("health check errored: %v", err)
-					containersToKeep[containerID] = empty{}
-					continue
-				}
-				if healthy == health.Healthy {
-					containersToKeep[containerID] = empty{}
-					continue
-				}
-				glog.V(1).Infof("pod %s container %s is unhealthy.", podFullName, container.Name, healthy)
-			} else {
-				glog.V(3).Infof("container hash changed %d vs %d.", hash, expectedHash)
-			}
-			if err := kl.killContainer(dockerContainer); err != nil {
-				glog.V(1).Infof("Failed to kill container %s: %v", dockerContainer.ID, err)
-				continue
-			}
-			killedContainers[containerID] = empty{}
-		}
-
-		// Check RestartPolicy for container
-		recentContainers, err := dockertools.GetRecentDockerContainersWithNameAndUUID(kl.dockerClient, podFullName, uuid, container.Name)
-		if err != nil {
-			glog.Errorf("Error listing recent containers with name and uuid:%s--%s--%s", podFullName, uuid, container.Name)
-			// TODO(dawnchen): error handling here?
-		}
-
-		if len(recentContainers) > 0 && pod.Manifest.RestartPolicy.Always == nil {
-			if pod.Manifest.RestartPolicy.Never != nil {
-				glog.V(3).Infof("Already ran container with name %s--%s--%s, do nothing",
-					podFullName, uuid, container.Name)
-				continue
-			}
-			if pod.Manifest.RestartPolicy.OnFailure != nil {
-				// Check the exit code of last run
-				if recentContainers[0].State.ExitCode == 0 {
-					glog.V(3).Infof("Already successfully ran container with name %s--%s--%s, do nothing",
-						podFullName, uuid, container.Name)
-					continue
-				}
-			}
-		}
-
-		glog.V(3).Infof("Container with name %s--%s--%s doesn't exist, creating %#v", podFullName, uuid, container.Name, container)
-		if err := kl.dockerPuller.Pull(container.Image); err != nil {
-			glog.Errorf("Failed to pull image %s: %v skipping pod %s container %s.", container.Image, err, podFullName, container.Name)
-			continue
-		}
-		// TODO(dawnchen): Check RestartPolicy.DelaySeconds before restart a container
-		containerID, err := kl.runContainer(pod, &container, podVolumes, "container:"+string(netID))
-		if err != nil {
-			// TODO(bburns) : Perhaps blacklist a container after N failures?
-			glog.Errorf("Error running pod %s container %s: %v", podFullName, container.Name, err)
-			continue
-		}
-		containersToKeep[containerID] = empty{}
-	}
-
-	// Kill any containers in this pod which were not identified above (guards against duplicates).
-	for id, container := range dockerContainers {
-		curPodFullName, curUUID, _, _ := dockertools.ParseDockerName(container.Names[0])
-		if curPodFullName == podFullName && curUUID == uuid {
-			// Don't kill containers we want to keep or those we already killed.
-			_, keep := containersToKeep[id]
-			_, killed := killedContainers[id]
-			if !keep && !killed {
-				err = kl.killContainer(container)
-				if err != nil {
-					glog.Errorf("Error killing container: %v", err)
-				}
-			}
-		}
+		return RunningPod{}, err
 	}
+	return runningPods.FindPod(podFullName, uuid), nil
+}
 
-	return nil
+type podKey struct {
+	podFullName string
+	uuid        string
 }
 
-type podContainer struct {
-	podFullName   string
-	uuid          string
-	containerName string
+// getPodSpecs returns the last known specs of the pods. The map must not be modified.
+func (kl *Kubelet) getPodSpecs() map[podKey]*Pod {
+	kl.podSpecsLock.RLock()
+	defer kl.podSpecsLock.RUnlock()
+	return kl.podSpecs
 }
 
 // Stores all volumes defined by the set of pods into a map.
@@ -573,8 +573,9 @@ func getDesiredVolumes(pods []Pod) map[string]api.Volume {
 // Compares the map of current volumes to the map of desired volumes.
 // If an active volume does not have a respective desired volume, clean it up.
 func (kl *Kubelet) reconcileVolumes(pods []Pod) error {
+	kl.unmountSubPaths(pods)
 	desiredVolumes := getDesiredVolumes(pods)
-	currentVolumes := volume.GetCurrentVolumes(kl.rootDirectory)
+	currentVolumes := kl.volumePlugins.GetCurrentVolumes(kl.rootDirectory)
 	for name, vol := range currentVolumes {
 		if _, ok := desiredVolumes[name]; !ok {
 			//TODO (jonesdl) We should somehow differentiate between volumes that are supposed
@@ -592,60 +593,112 @@ func (kl *Kubelet) reconcileVolumes(pods []Pod) error {
 
 // SyncPods synchronizes the configured list of pods (desired state) with the host current state.
 func (kl *Kubelet) SyncPods(pods []Pod) error {
+	return kl.syncPods(pods, nil)
+}
+
+// SyncChangedPods synchronizes the pods whose full names are in changed, and kills the
+// pods which are no longer desired. Unlike SyncPods, it leaves orphaned volumes alone.
+func (kl *Kubelet) SyncChangedPods(pods []Pod, changed util.StringSet) error {
+	return kl.syncPods(pods, changed)
+}
+
+// syncPods syncs the pods in changed, or all the pods if changed is nil.
+func (kl *Kubelet) syncPods(pods []Pod, changed util.StringSet) error {
 	glog.V(4).Infof("Desired [%s]: %+v", kl.hostname, pods)
 	var err error
-	desiredContainers := make(map[podContainer]empty)
+	desiredPods := make(map[podKey]empty)
+	podSpecs := make(map[podKey]*Pod)
 
-	dockerContainers, err := dockertools.GetKubeletDockerContainers(kl.dockerClient)
+	runningPods, err := kl.runtime.GetPods(false)
 	if err != nil {
-		glog.Errorf("Error listing containers %#v", dockerContainers)
+		glog.Errorf("Error listing containers %#v", runningPods)
 		return err
 	}
 
+	if changed == nil && kl.eviction != nil {
+		kl.evictPods(pods, runningPods)
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		key := podKey{GetPodFullName(pod), pod.Manifest.UUID}
+		desiredPods[key] = empty{}
+		podSpecs[key] = pod
+	}
+	// The specs of the pods which are no longer desired are kept until they are killed.
+	for _, runningPod := range runningPods {
+		key := podKey{runningPod.FullName, runningPod.UUID}
+		if _, ok := desiredPods[key]; !ok {
+			podSpecs[key] = kl.podSpecs[key]
+		}
+	}
+	// The specs are updated before the pods are synced, since their volumes read them.
+	kl.podSpecsLock.Lock()
+	kl.podSpecs = podSpecs
+	kl.desiredPods = desiredPods
+	kl.podSpecsLock.Unlock()
+
 	// Check for any containers that need starting
 	for i := range pods {
 		pod := &pods[i]
 		podFullName := GetPodFullName(pod)
 		uuid := pod.Manifest.UUID
-
-		// Add all containers (including net) to the map.
-		desiredContainers[podContainer{podFullName, uuid, networkContainerName}] = empty{}
-		for _, cont := range pod.Manifest.Containers {
-			desiredContainers[podContainer{podFullName, uuid, cont.Name}] = empty{}
+		if changed != nil && !changed.Has(podFullName) {
+			continue
+		}
+		if kl.eviction != nil {
+			if _, evicted := kl.eviction.evictionReason(podKey{podFullName, uuid}); evicted {
+				continue
+			}
 		}
 
 		// Run the sync in an async manifest worker.
-		kl.podWorkers.Run(podFullName, func() {
-			err := kl.syncPod(pod, dockerContainers)
+		kl.podWorkers.Run(podFullName, uuid, runningPods.FindPod(podFullName, uuid), func(runningPod RunningPod) {
+			err := kl.syncPod(pod, runningPod)
 			if err != nil {
 				glog.Errorf("Error syncing pod: %v skipping.", err)
 			}
 		})
 	}
 
-	// Kill any containers we don't need
-	existingContainers, err := dockertools.GetKubeletDockerContainers(kl.dockerClient)
-	if err != nil {
-		glog.Errorf("Error listing containers: %v", err)
-		return err
-	}
-	for _, container := range existingContainers {
-		// Don't kill containers that are in the desired pods.
-		podFullName, uuid, containerName, _ := dockertools.ParseDockerName(container.Names[0])
-		if _, ok := desiredContainers[podContainer{podFullName, uuid, containerName}]; !ok {
-			err = kl.killContainer(container)
-			if err != nil {
-				glog.Errorf("Error killing container: %v", err)
-			}
+	// Kill any pods we don't need
+	for _, runningPod := range runningPods {
+		key := podKey{runningPod.FullName, runningPod.UUID}
+		if _, ok := desiredPods[key]; !ok {
+			spec := podSpecs[key]
+			// Stopping the containers may take up to the grace period of the pod, so don't block.
+			kl.podWorkers.Run(key.podFullName, key.uuid, *runningPod, func(runningPod RunningPod) {
+				kl.killPod(spec, runningPod)
+			})
 		}
 	}
+	if kl.mirrorPods != nil {
+		kl.mirrorPods.sync(pods)
+	}
+	if kl.eviction != nil {
+		kl.eviction.retain(desiredPods)
+	}
 
-	// Remove any orphaned volumes.
-	kl.reconcileVolumes(pods)
+	if changed == nil {
+		// Remove any orphaned volumes and networks.
+		kl.reconcileVolumes(pods)
+		kl.cleanupResolvConfs(pods)
+		kl.runtime.CleanupPods(runningPods)
+	}
 
 	return err
 }
 
+// killPod kills the containers of a pod which is no longer desired, runningPod.
+func (kl *Kubelet) killPod(pod *Pod, runningPod RunningPod) {
+	if len(runningPod.Containers) == 0 {
+		return
+	}
+	if err := kl.runtime.KillPod(pod, runningPod); err != nil {
+		glog.Errorf("Error killing pod: %v", err)
+	}
+}
+
 // filterHostPortConflicts removes pods that conflict on Port.HostPort values
 func filterHostPortConflicts(pods []Pod) []Pod {
 	filtered := []Pod{}
@@ -663,37 +716,151 @@ func filterHostPortConflicts(pods []Pod) []Pod {
 	return filtered
 }
 
+// changedPods returns the full names of the pods in pods which are new or whose manifest
+// differs from the one in oldPods.
+func changedPods(oldPods, pods []Pod) util.StringSet {
+	old := make(map[string]*api.ContainerManifest)
+	for i := range oldPods {
+		old[GetPodFullName(&oldPods[i])] = &oldPods[i].Manifest
+	}
+	changed := util.NewStringSet()
+	for i := range pods {
+		podFullName := GetPodFullName(&pods[i])
+		if manifest, found := old[podFullName]; !found || !reflect.DeepEqual(*manifest, pods[i].Manifest) {
+			changed.Insert(podFullName)
+		}
+	}
+	return changed
+}
+
+// withUpdatedPods returns a copy of pods in which the pods with the same full names as the
+// updated pods are replaced by them. The updated pods which aren't in pods are ignored, the
+// snapshot which adds them follows.
+func withUpdatedPods(pods, updated []Pod) []Pod {
+	byName := make(map[string]*Pod)
+	for i := range updated {
+		byName[GetPodFullName(&updated[i])] = &updated[i]
+	}
+	result := make([]Pod, len(pods))
+	for i := range pods {
+		if pod, found := byName[GetPodFullName(&pods[i])]; found {
+			result[i] = *pod
+		} else {
+			result[i] = pods[i]
+		}
+	}
+	return result
+}
+
+// handleContainerEvent stops the probes and forgets the results of a container which died or
+// was removed.
+func (kl *Kubelet) handleContainerEvent(e *PodLifecycleEvent) {
+	if e.Type == ContainerStarted {
+		return
+	}
+	if kl.prober != nil {
+		kl.prober.forget(e.ContainerID)
+	} else if kl.readiness != nil {
+		kl.readiness.forget(e.ContainerID)
+	}
+}
+
 // syncLoop is the main loop for processing changes. It watches for changes from
-// four channels (file, etcd, server, and http) and creates a union of them. For
-// any new change seen, will run a sync against desired state and running state. If
-// no changes are seen to the configuration, will synchronize the last known desired
-// state every sync_frequency seconds. Never returns.
-func (kl *Kubelet) syncLoop(updates <-chan PodUpdate, handler SyncHandler) {
+// four channels (file, etcd, server, and http) and creates a union of them, and for
+// container lifecycle events. The desired pods are checkpointed under the root directory
+// whenever the configuration changes, and only the new and modified pods are synced.
+// When a container event is seen, only the pods of the containers that changed are
+// synced. All the pods are synchronized every sync_frequency seconds, so that health
+// checks run and orphans are cleaned up. Never returns.
+func (kl *Kubelet) syncLoop(updates <-chan PodUpdate, events <-chan *PodLifecycleEvent, handler SyncHandler) {
+	// Until the config sources deliver their pods, the pods from the checkpoint are desired, so
+	// that a restart while a source is unreachable doesn't kill its pods. A source which never
+	// delivers its pods doesn't keep them forever.
+	restored, err := kl.readCheckpoint()
+	if err != nil {
+		glog.Errorf("Couldn't read the checkpoint of the pods: %v", err)
+	}
 	var pods []Pod
+	var pendingSourcesTimeout <-chan time.Time
+	if len(restored) > 0 {
+		glog.Infof("Restored %d pods from the checkpoint [%s]", len(restored), kl.hostname)
+		pods = filterHostPortConflicts(restored)
+		if kl.pendingSourcesTimeout > 0 {
+			pendingSourcesTimeout = time.After(kl.pendingSourcesTimeout)
+		}
+	}
+	// Container events must not postpone the periodic sync, so the timer is only reset when it fires.
+	resync := time.After(kl.resyncInterval)
 	for {
+		var changed util.StringSet
 		select {
 		case u := <-updates:
 			switch u.Op {
 			case SET:
 				glog.V(3).Infof("Containers changed [%s]", kl.hostname)
-				pods = u.Pods
-				pods = filterHostPortConflicts(pods)
+				var newPods []Pod
+				newPods, restored = withRestoredPods(u.Pods, restored, u.PendingSources)
+				newPods = filterHostPortConflicts(newPods)
+				changed = changedPods(pods, newPods)
+				pods = newPods
+				if err := kl.writeCheckpoint(pods); err != nil {
+					glog.Errorf("Couldn't checkpoint the pods: %v", err)
+				}
 
 			case UPDATE:
-				//TODO: implement updates of containers
-				glog.Warningf("Containers updated, not implemented [%s]", kl.hostname)
-				continue
+				glog.V(3).Infof("Containers updated [%s]", kl.hostname)
+				newPods := filterHostPortConflicts(withUpdatedPods(pods, u.Pods))
+				changed = changedPods(pods, newPods)
+				pods = newPods
+				if err := kl.writeCheckpoint(pods); err != nil {
+					glog.Errorf("Couldn't checkpoint the pods: %v", err)
+				}
 
 			default:
 				panic("syncLoop does not support incremental changes")
 			}
-		case <-time.After(kl.resyncInterval):
+		case e := <-events:
+			kl.handleContainerEvent(e)
+			changed = util.NewStringSet(e.PodFullName)
+			// Batch up the events which are already queued.
+			for more := true; more; {
+				select {
+				case e := <-events:
+					kl.handleContainerEvent(e)
+					changed.Insert(e.PodFullName)
+				default:
+					more = false
+				}
+			}
+			if pods == nil {
+				continue
+			}
+		case <-resync:
+			resync = time.After(kl.resyncInterval)
 			if pods == nil {
 				continue
 			}
+		case <-pendingSourcesTimeout:
+			pendingSourcesTimeout = nil
+			if len(restored) == 0 {
+				continue
+			}
+			glog.Warningf("Config sources didn't deliver their pods within %v, no longer using the checkpointed pods", kl.pendingSourcesTimeout)
+			newPods := withoutRestoredPods(pods, restored)
+			restored = nil
+			changed = changedPods(pods, newPods)
+			pods = newPods
+			if err := kl.writeCheckpoint(pods); err != nil {
+				glog.Errorf("Couldn't checkpoint the pods: %v", err)
+			}
 		}
 
-		err := handler.SyncPods(pods)
+		var err error
+		if changed == nil {
+			err = handler.SyncPods(pods)
+		} else {
+			err = handler.SyncChangedPods(pods, changed)
+		}
 		if err != nil {
 			glog.Errorf("Couldn't sync containers : %v", err)
 		}
@@ -720,26 +887,56 @@ func (kl *Kubelet) statsFromContainerPath(containerPath string, req *info.Contai
 }
 
 // GetKubeletContainerLogs returns logs from the container
-// The second parameter of GetPodInfo and FindPodContainer methods represents pod UUID, which is allowed to be blank
-func (kl *Kubelet) GetKubeletContainerLogs(podFullName, containerName, tail string, follow bool, stdout, stderr io.Writer) error {
+// The second parameter of GetPodInfo method represents pod UUID, which is allowed to be blank
+func (kl *Kubelet) GetKubeletContainerLogs(podFullName, containerName string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) error {
 	_, err := kl.GetPodInfo(podFullName, "")
 	if err == dockertools.ErrNoContainersInPod {
 		return fmt.Errorf("Pod not found (%s)\n", podFullName)
 	}
-	dockerContainers, err := dockertools.GetKubeletDockerContainers(kl.dockerClient)
+	return kl.runtime.GetContainerLogs(podFullName, containerName, logOpts, stdout, stderr)
+}
+
+// GetPodInfo returns information from the container runtime about the containers in a pod
+func (kl *Kubelet) GetPodInfo(podFullName, uuid string) (api.PodInfo, error) {
+	if kl.mirrorPods != nil {
+		podFullName = kl.mirrorPods.staticPodFullName(podFullName)
+	}
+	info, err := kl.runtime.GetPodInfo(podFullName, uuid)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	dockerContainer, found, _ := dockerContainers.FindPodContainer(podFullName, "", containerName)
-	if !found {
-		return fmt.Errorf("Container not found (%s)\n", containerName)
+	var evictionReason string
+	if kl.eviction != nil {
+		evictionReason, _ = kl.eviction.evictionReason(podKey{podFullName, uuid})
+	}
+	for name, status := range info {
+		status.Ready = status.State.Running != nil && kl.readiness.IsReady(status.DetailInfo.ID)
+		if evictionReason != "" && status.State.Termination != nil {
+			termination := *status.State.Termination
+			termination.Reason = evictionReason
+			status.State.Termination = &termination
+		}
+		info[name] = status
 	}
-	return dockertools.GetKubeletDockerContainerLogs(kl.dockerClient, dockerContainer.ID, tail, follow, stdout, stderr)
+	return info, nil
 }
 
-// GetPodInfo returns information from Docker about the containers in a pod
-func (kl *Kubelet) GetPodInfo(podFullName, uuid string) (api.PodInfo, error) {
-	return dockertools.GetDockerPodInfo(kl.dockerClient, podFullName, uuid)
+// GetNodeConditions returns the conditions which currently hold on the node.
+func (kl *Kubelet) GetNodeConditions() []api.NodeCondition {
+	if kl.eviction == nil {
+		return nil
+	}
+	return kl.eviction.Conditions()
+}
+
+// findContainer returns the running container with the given name in a pod.
+func (kl *Kubelet) findContainer(podFullName, uuid, containerName string) (*RunningContainer, error) {
+	runningPods, err := kl.runtime.GetPods(false)
+	if err != nil {
+		return nil, err
+	}
+	runningPod := runningPods.FindPod(podFullName, uuid)
+	return runningPod.FindContainer(containerName), nil
 }
 
 // GetContainerInfo returns stats (from Cadvisor) for a container.
@@ -747,15 +944,14 @@ func (kl *Kubelet) GetContainerInfo(podFullName, uuid, containerName string, req
 	if kl.cadvisorClient == nil {
 		return nil, nil
 	}
-	dockerContainers, err := dockertools.GetKubeletDockerContainers(kl.dockerClient)
+	container, err := kl.findContainer(podFullName, uuid, containerName)
 	if err != nil {
 		return nil, err
 	}
-	dockerContainer, found, _ := dockerContainers.FindPodContainer(podFullName, uuid, containerName)
-	if !found {
+	if container == nil {
 		return nil, errors.New("couldn't find container")
 	}
-	return kl.statsFromContainerPath(fmt.Sprintf("/docker/%s", dockerContainer.ID), req)
+	return kl.statsFromContainerPath(fmt.Sprintf("/docker/%s", container.ID), req)
 }
 
 // GetRootInfo returns stats (from Cadvisor) of current machine (root container).
@@ -767,18 +963,122 @@ func (kl *Kubelet) GetMachineInfo() (*info.MachineInfo, error) {
 	return kl.cadvisorClient.MachineInfo()
 }
 
-func (kl *Kubelet) healthy(podFullName string, currentState api.PodState, container api.Container, dockerContainer *docker.APIContainers) (health.Status, error) {
-	// Give the container 60 seconds to start up.
-	if container.LivenessProbe == nil {
-		return health.Healthy, nil
+func (kl *Kubelet) startProbes(podFullName string, currentState api.PodState, container api.Container, runningContainer *RunningContainer) {
+	if kl.prober == nil {
+		return
 	}
-	if time.Now().Unix()-dockerContainer.Created < container.LivenessProbe.InitialDelaySeconds {
-		return health.Healthy, nil
+	kl.prober.start(podFullName, currentState, container, runningContainer)
+}
+
+func (kl *Kubelet) healthy(containerID string) health.Status {
+	if kl.prober == nil {
+		return health.Healthy
 	}
-	if kl.healthChecker == nil {
-		return health.Healthy, nil
+	return kl.prober.healthy(containerID)
+}
+
+// GetSecret reads the secret with the given ID from the apiserver's registry.
+func (kl *Kubelet) GetSecret(id string) (*api.Secret, error) {
+	if kl.secrets == nil {
+		return nil, fmt.Errorf("no source to read secret %q from", id)
 	}
-	return kl.healthChecker.HealthCheck(podFullName, currentState, container)
+	return kl.secrets.get(id)
+}
+
+// GetPodField returns the value of a field of the desired pod with the given ID, for its
+// downward API volumes. The IP address of the pod isn't known yet when its volumes are set up.
+// Since the volumes of pods are stored under their IDs, the pods of different sources which
+// share an ID also share their volumes, and no value is returned for them.
+func (kl *Kubelet) GetPodField(podID, fieldPath string) (string, error) {
+	if fieldPath == "podIP" {
+		return "", fmt.Errorf("the IP address of pod %s isn't known before its volumes are set up", podID)
+	}
+	var found []*Pod
+	kl.podSpecsLock.RLock()
+	for key := range kl.desiredPods {
+		if pod := kl.podSpecs[key]; pod != nil && pod.Manifest.ID == podID {
+			found = append(found, pod)
+		}
+	}
+	kl.podSpecsLock.RUnlock()
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("pod %s not found", podID)
+	case 1:
+		return podFieldValue(found[0], kl.hostname, "", fieldPath)
+	}
+	names := make([]string, 0, len(found))
+	for _, pod := range found {
+		names = append(names, GetPodFullName(pod))
+	}
+	sort.Strings(names)
+	return "", fmt.Errorf("pods %s share the ID %s", strings.Join(names, ", "), podID)
+}
+
+// environment returns the environment variables of a container of a pod. The pods on the host
+// network have the IP address of the node.
+func (kl *Kubelet) environment(pod *Pod, container *api.Container, podIP string) ([]string, error) {
+	if pod.Manifest.HostNetwork && usesPodField(container, "podIP") {
+		ip, err := kl.nodeIP()
+		if err != nil {
+			return nil, err
+		}
+		podIP = ip.String()
+	}
+	return makeEnvironmentVariables(pod, container, kl.hostname, podIP)
+}
+
+// nodeIP returns the address of the node: the first address its hostname resolves to which
+// isn't a loopback address, or else the first such address of its interfaces.
+func (kl *Kubelet) nodeIP() (net.IP, error) {
+	if ip := net.ParseIP(kl.hostname); ip != nil {
+		return ip, nil
+	}
+	if addrs, err := net.LookupIP(kl.hostname); err == nil {
+		for _, ip := range addrs {
+			if !ip.IsLoopback() {
+				return ip, nil
+			}
+		}
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+			return ipNet.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("no address found for host %s", kl.hostname)
+}
+
+// pullCredentials reads the image pull secrets of a pod every time its images are pulled, so
+// that the credentials are never written to disk.
+func (kl *Kubelet) pullCredentials(pod *Pod) ([][]byte, error) {
+	dockercfgs := [][]byte{}
+	if len(pod.Manifest.ImagePullSecrets) == 0 {
+		return dockercfgs, nil
+	}
+	if kl.secrets == nil {
+		return nil, fmt.Errorf("no source to read the image pull secrets %v from", pod.Manifest.ImagePullSecrets)
+	}
+	for _, id := range pod.Manifest.ImagePullSecrets {
+		secret, err := kl.secrets.get(id)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read image pull secret %q: %v", id, err)
+		}
+		data, found := secret.Data[api.SecretDockerConfigKey]
+		if !found {
+			return nil, fmt.Errorf("image pull secret %q has no %q key", id, api.SecretDockerConfigKey)
+		}
+		dockercfg, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode image pull secret %q: %v", id, err)
+		}
+		dockercfgs = append(dockercfgs, dockercfg)
+	}
+	return dockercfgs, nil
 }
 
 // Returns logs of current machine.
@@ -789,16 +1089,12 @@ func (kl *Kubelet) ServeLogs(w http.ResponseWriter, req *http.Request) {
 
 // Run a command in a container, returns the combined stdout, stderr as an array of bytes
 func (kl *Kubelet) RunInContainer(podFullName, uuid, container string, cmd []string) ([]byte, error) {
-	if kl.runner == nil {
-		return nil, fmt.Errorf("no runner specified.")
-	}
-	dockerContainers, err := dockertools.GetKubeletDockerContainers(kl.dockerClient)
+	runningContainer, err := kl.findContainer(podFullName, uuid, container)
 	if err != nil {
 		return nil, err
 	}
-	dockerContainer, found, _ := dockerContainers.FindPodContainer(podFullName, uuid, container)
-	if !found {
+	if runningContainer == nil {
 		return nil, fmt.Errorf("container not found (%s)", container)
 	}
-	return kl.runner.RunInContainer(dockerContainer.ID, cmd)
+	return kl.runtime.RunInContainer(runningContainer.ID, cmd)
 }
@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/capabilities"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/health"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
@@ -42,14 +43,24 @@ func newTestKubelet(t *testing.T) (*Kubelet, *tools.FakeEtcdClient, *dockertools
 	fakeDocker := &dockertools.FakeDockerClient{}
 
 	kubelet := &Kubelet{}
-	kubelet.dockerClient = fakeDocker
-	kubelet.dockerPuller = &dockertools.FakeDockerPuller{}
+	kubelet.runtime = newDockerRuntime(fakeDocker, &dockertools.FakeDockerPuller{}, nil, kubelet)
 	kubelet.etcdClient = fakeEtcdClient
 	kubelet.rootDirectory = "/tmp/kubelet"
-	kubelet.podWorkers = newPodWorkers()
+	kubelet.volumePlugins, _ = volume.NewPluginRegistry(volume.DefaultPlugins()...)
+	kubelet.podWorkers = newPodWorkers(kubelet.listPod)
+	kubelet.readiness = newReadinessStates()
 	return kubelet, fakeEtcdClient, fakeDocker
 }
 
+// getRunningPod lists the containers of the test kubelet and returns the ones of the given pod.
+func getRunningPod(t *testing.T, kubelet *Kubelet, podFullName string) RunningPod {
+	runningPods, err := kubelet.runtime.GetPods(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return runningPods.FindPod(podFullName, "")
+}
+
 func verifyCalls(t *testing.T, fakeDocker *dockertools.FakeDockerClient, calls []string) {
 	err := fakeDocker.AssertCalls(calls)
 	if err != nil {
@@ -91,9 +102,8 @@ func TestKillContainerWithError(t *testing.T) {
 			},
 		},
 	}
-	kubelet, _, _ := newTestKubelet(t)
-	kubelet.dockerClient = fakeDocker
-	err := kubelet.killContainer(&fakeDocker.ContainerList[0])
+	runtime := newDockerRuntime(fakeDocker, &dockertools.FakeDockerPuller{}, nil, nil)
+	err := runtime.killContainer(nil, &RunningContainer{ID: fakeDocker.ContainerList[0].ID})
 	if err == nil {
 		t.Errorf("expected error, found nil")
 	}
@@ -116,7 +126,7 @@ func TestKillContainer(t *testing.T) {
 		ID: "foobar",
 	}
 
-	err := kubelet.killContainer(&fakeDocker.ContainerList[0])
+	err := kubelet.runtime.(*dockerRuntime).killContainer(nil, &RunningContainer{ID: fakeDocker.ContainerList[0].ID})
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -179,8 +189,9 @@ func TestSyncPodsDoesNothing(t *testing.T) {
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+	kubelet.drainWorkers()
 
-	verifyCalls(t, fakeDocker, []string{"list", "list"})
+	verifyCalls(t, fakeDocker, []string{"list", "list", "inspect", "inspect"})
 }
 
 // drainWorkers waits until all workers are done.  Should only used for testing.
@@ -225,7 +236,7 @@ func TestSyncPodsCreatesNetAndContainer(t *testing.T) {
 	kubelet.drainWorkers()
 
 	verifyCalls(t, fakeDocker, []string{
-		"list", "list", "create", "start", "list", "inspect", "list", "create", "start"})
+		"list", "create", "start", "list", "inspect", "list", "create", "start"})
 
 	fakeDocker.Lock()
 	if len(fakeDocker.Created) != 2 ||
@@ -236,6 +247,139 @@ func TestSyncPodsCreatesNetAndContainer(t *testing.T) {
 	fakeDocker.Unlock()
 }
 
+func TestSyncPodsHostNetwork(t *testing.T) {
+	capabilities.SetForTests(capabilities.Capabilities{
+		AllowHostNetwork: true,
+	})
+	defer capabilities.SetForTests(capabilities.Capabilities{})
+	kubelet, _, fakeDocker := newTestKubelet(t)
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
+			// network container from before the pod used the network of the host
+			Names: []string{"/k8s--net--foo.test--"},
+			ID:    "9876",
+		},
+	}
+	err := kubelet.SyncPods([]Pod{
+		{
+			Name:      "foo",
+			Namespace: "test",
+			Manifest: api.ContainerManifest{
+				ID:          "foo",
+				HostNetwork: true,
+				Containers: []api.Container{
+					{Name: "bar", Ports: []api.Port{{ContainerPort: 80, HostPort: 80}}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+
+	verifyCalls(t, fakeDocker, []string{
+		"list", "list", "inspect", "list", "create", "start", "stop"})
+
+	fakeDocker.Lock()
+	if len(fakeDocker.Created) != 1 ||
+		!matchString(t, "k8s--bar\\.[a-f0-9]+--foo.test--", fakeDocker.Created[0]) {
+		t.Errorf("Unexpected containers created %v", fakeDocker.Created)
+	}
+	if hostConfig := fakeDocker.HostConfigs[0]; hostConfig.NetworkMode != "host" || hostConfig.PortBindings != nil {
+		t.Errorf("Unexpected host config %#v", hostConfig)
+	}
+	if len(fakeDocker.Stopped) != 1 || fakeDocker.Stopped[0] != "9876" {
+		t.Errorf("Expected the network container to be stopped, got %v", fakeDocker.Stopped)
+	}
+	fakeDocker.Unlock()
+}
+
+func TestSyncPodsHostNetworkDisallowed(t *testing.T) {
+	kubelet, _, fakeDocker := newTestKubelet(t)
+	err := kubelet.SyncPods([]Pod{
+		{
+			Name:      "foo",
+			Namespace: "test",
+			Manifest: api.ContainerManifest{
+				ID:          "foo",
+				HostNetwork: true,
+				Containers:  []api.Container{{Name: "bar"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+
+	fakeDocker.Lock()
+	if len(fakeDocker.HostConfigs) != 0 {
+		t.Errorf("Expected no container to be started, got %v", fakeDocker.HostConfigs)
+	}
+	fakeDocker.Unlock()
+}
+
+func TestSyncPodsAppliesSecurityContext(t *testing.T) {
+	kubelet, _, fakeDocker := newTestKubelet(t)
+	capabilities.SetForTests(capabilities.Capabilities{AllowedCapabilities: []string{"NET_ADMIN"}})
+	defer capabilities.SetForTests(capabilities.Capabilities{})
+	uid, gid := int64(1000), int64(2000)
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
+			// network container
+			Names: []string{"/k8s--net--foo.test--"},
+			ID:    "9876",
+		},
+	}
+	err := kubelet.SyncPods([]Pod{
+		{
+			Name:      "foo",
+			Namespace: "test",
+			Manifest: api.ContainerManifest{
+				ID: "foo",
+				Containers: []api.Container{
+					{
+						Name: "bar",
+						SecurityContext: &api.SecurityContext{
+							RunAsUser:    &uid,
+							RunAsGroup:   &gid,
+							Capabilities: &api.Capabilities{Add: []api.Capability{"NET_ADMIN"}, Drop: []api.Capability{"ALL"}},
+						},
+					},
+					{
+						Name:            "baz",
+						SecurityContext: &api.SecurityContext{Capabilities: &api.Capabilities{Add: []api.Capability{"SYS_ADMIN"}}},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+
+	fakeDocker.Lock()
+	defer fakeDocker.Unlock()
+	if len(fakeDocker.Configs) != 1 || fakeDocker.Configs[0].User != "1000:2000" {
+		t.Errorf("Expected one container running as 1000:2000, got %#v", fakeDocker.Configs)
+	}
+	if len(fakeDocker.HostConfigs) != 1 {
+		t.Fatalf("Expected the container with a disallowed capability not to be started, got %#v", fakeDocker.HostConfigs)
+	}
+	expected := &docker.HostConfig{
+		NetworkMode:  "container:9876",
+		Binds:        []string{},
+		PortBindings: map[docker.Port][]docker.PortBinding{},
+		CapAdd:       []string{"NET_ADMIN"},
+		CapDrop:      []string{"ALL"},
+	}
+	if !reflect.DeepEqual(fakeDocker.HostConfigs[0], expected) {
+		t.Errorf("Expected host config %#v, got %#v", expected, fakeDocker.HostConfigs[0])
+	}
+}
+
 func TestSyncPodsWithNetCreatesContainer(t *testing.T) {
 	kubelet, _, fakeDocker := newTestKubelet(t)
 	fakeDocker.ContainerList = []docker.APIContainers{
@@ -263,7 +407,7 @@ func TestSyncPodsWithNetCreatesContainer(t *testing.T) {
 	kubelet.drainWorkers()
 
 	verifyCalls(t, fakeDocker, []string{
-		"list", "list", "list", "inspect", "list", "create", "start"})
+		"list", "list", "inspect", "list", "create", "start"})
 
 	fakeDocker.Lock()
 	if len(fakeDocker.Created) != 1 ||
@@ -273,6 +417,111 @@ func TestSyncPodsWithNetCreatesContainer(t *testing.T) {
 	fakeDocker.Unlock()
 }
 
+func newInitContainersPod(restartPolicy api.RestartPolicy) *Pod {
+	return &Pod{
+		Name:      "foo",
+		Namespace: "test",
+		Manifest: api.ContainerManifest{
+			ID:             "foo",
+			InitContainers: []api.Container{{Name: "migrate"}, {Name: "fetch"}},
+			Containers:     []api.Container{{Name: "bar"}},
+			RestartPolicy:  restartPolicy,
+		},
+	}
+}
+
+func TestSyncPodRunsInitContainersInOrder(t *testing.T) {
+	kubelet, _, fakeDocker := newTestKubelet(t)
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
+			// network container
+			Names: []string{"/k8s--net--foo.test--"},
+			ID:    "9876",
+		},
+	}
+	// The containers the fake creates are listed, and have exited successfully.
+	fakeDocker.Container = &docker.Container{State: docker.State{ExitCode: 0}}
+	pod := newInitContainersPod(api.RestartPolicy{Always: &api.RestartPolicyAlways{}})
+	runningPod := RunningPod{FullName: "foo.test", Containers: []*RunningContainer{{ID: "9876", Name: networkContainerName}}}
+
+	expected := []string{"k8s--migrate\\.", "k8s--fetch\\.", "k8s--bar\\."}
+	for i := range expected {
+		if err := kubelet.runtime.SyncPod(pod, runningPod, volumeMap{}); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		fakeDocker.Lock()
+		if len(fakeDocker.Created) != i+1 || !matchString(t, expected[i], fakeDocker.Created[i]) {
+			t.Errorf("sync %d: unexpected containers created %v", i, fakeDocker.Created)
+		}
+		fakeDocker.Unlock()
+	}
+}
+
+func TestSyncPodWaitsForRunningInitContainer(t *testing.T) {
+	kubelet, _, fakeDocker := newTestKubelet(t)
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
+			// network container
+			Names: []string{"/k8s--net--foo.test--"},
+			ID:    "9876",
+		},
+		{
+			Names: []string{"/k8s--migrate--foo.test--"},
+			ID:    "1234",
+		},
+	}
+	runningPod := RunningPod{FullName: "foo.test", Containers: []*RunningContainer{
+		{ID: "9876", Name: networkContainerName},
+		{ID: "1234", Name: "migrate"},
+	}}
+	if err := kubelet.runtime.SyncPod(newInitContainersPod(api.RestartPolicy{}), runningPod, volumeMap{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	fakeDocker.Lock()
+	defer fakeDocker.Unlock()
+	if len(fakeDocker.Created) != 0 || len(fakeDocker.Stopped) != 0 {
+		t.Errorf("expected the running init container to be waited for, created %v, stopped %v", fakeDocker.Created, fakeDocker.Stopped)
+	}
+}
+
+func TestSyncPodFailedInitContainer(t *testing.T) {
+	tests := []struct {
+		restartPolicy api.RestartPolicy
+		created       int
+	}{
+		{api.RestartPolicy{Never: &api.RestartPolicyNever{}}, 0},
+		{api.RestartPolicy{OnFailure: &api.RestartPolicyOnFailure{}}, 1},
+		{api.RestartPolicy{Always: &api.RestartPolicyAlways{}}, 1},
+	}
+	for i, test := range tests {
+		kubelet, _, fakeDocker := newTestKubelet(t)
+		fakeDocker.ContainerList = []docker.APIContainers{
+			{
+				// network container
+				Names: []string{"/k8s--net--foo.test--"},
+				ID:    "9876",
+			},
+			{
+				Names: []string{"/k8s--migrate--foo.test--"},
+				ID:    "1234",
+			},
+		}
+		fakeDocker.Container = &docker.Container{State: docker.State{ExitCode: 1}}
+		runningPod := RunningPod{FullName: "foo.test", Containers: []*RunningContainer{{ID: "9876", Name: networkContainerName}}}
+		if err := kubelet.runtime.SyncPod(newInitContainersPod(test.restartPolicy), runningPod, volumeMap{}); err != nil {
+			t.Errorf("%d: unexpected error: %v", i, err)
+		}
+		fakeDocker.Lock()
+		if len(fakeDocker.Created) != test.created {
+			t.Errorf("%d: expected %d containers to be created, got %v", i, test.created, fakeDocker.Created)
+		}
+		if test.created > 0 && !matchString(t, "k8s--migrate\\.", fakeDocker.Created[0]) {
+			t.Errorf("%d: expected the failed init container to be run again, got %v", i, fakeDocker.Created)
+		}
+		fakeDocker.Unlock()
+	}
+}
+
 func TestSyncPodsWithNetCreatesContainerCallsHandler(t *testing.T) {
 	kubelet, _, fakeDocker := newTestKubelet(t)
 	fakeHttp := fakeHTTP{}
@@ -313,7 +562,7 @@ func TestSyncPodsWithNetCreatesContainerCallsHandler(t *testing.T) {
 	kubelet.drainWorkers()
 
 	verifyCalls(t, fakeDocker, []string{
-		"list", "list", "list", "inspect", "list", "create", "start"})
+		"list", "list", "inspect", "list", "create", "start"})
 
 	fakeDocker.Lock()
 	if len(fakeDocker.Created) != 1 ||
@@ -353,7 +602,7 @@ func TestSyncPodsDeletesWithNoNetContainer(t *testing.T) {
 	kubelet.drainWorkers()
 
 	verifyCalls(t, fakeDocker, []string{
-		"list", "list", "stop", "create", "start", "list", "list", "inspect", "list", "create", "start"})
+		"list", "stop", "create", "start", "list", "list", "inspect", "list", "create", "start"})
 
 	// A map iteration is used to delete containers, so must not depend on
 	// order here.
@@ -389,8 +638,9 @@ func TestSyncPodsDeletes(t *testing.T) {
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+	kubelet.drainWorkers()
 
-	verifyCalls(t, fakeDocker, []string{"list", "list", "stop", "stop"})
+	// The pods are killed by their own workers, so only the stopped containers are checked.
 
 	// A map iteration is used to delete containers, so must not depend on
 	// order here.
@@ -407,23 +657,23 @@ func TestSyncPodsDeletes(t *testing.T) {
 
 func TestSyncPodDeletesDuplicate(t *testing.T) {
 	kubelet, _, fakeDocker := newTestKubelet(t)
-	dockerContainers := dockertools.DockerContainers{
-		"1234": &docker.APIContainers{
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
 			// the k8s prefix is required for the kubelet to manage the container
 			Names: []string{"/k8s--foo--bar.test--1"},
 			ID:    "1234",
 		},
-		"9876": &docker.APIContainers{
+		{
 			// network container
 			Names: []string{"/k8s--net--bar.test--"},
 			ID:    "9876",
 		},
-		"4567": &docker.APIContainers{
+		{
 			// Duplicate for the same container.
 			Names: []string{"/k8s--foo--bar.test--2"},
 			ID:    "4567",
 		},
-		"2304": &docker.APIContainers{
+		{
 			// Container for another pod, untouched.
 			Names: []string{"/k8s--baz--fiz.test--6"},
 			ID:    "2304",
@@ -438,12 +688,12 @@ func TestSyncPodDeletesDuplicate(t *testing.T) {
 				{Name: "foo"},
 			},
 		},
-	}, dockerContainers)
+	}, getRunningPod(t, kubelet, "bar.test"))
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	verifyCalls(t, fakeDocker, []string{"list", "stop"})
+	verifyCalls(t, fakeDocker, []string{"list", "list", "inspect", "inspect", "stop"})
 
 	// Expect one of the duplicates to be killed.
 	if len(fakeDocker.Stopped) != 1 || (len(fakeDocker.Stopped) != 0 && fakeDocker.Stopped[0] != "1234" && fakeDocker.Stopped[0] != "4567") {
@@ -460,13 +710,13 @@ func (f *FalseHealthChecker) HealthCheck(podFullName string, state api.PodState,
 func TestSyncPodBadHash(t *testing.T) {
 	kubelet, _, fakeDocker := newTestKubelet(t)
 	kubelet.healthChecker = &FalseHealthChecker{}
-	dockerContainers := dockertools.DockerContainers{
-		"1234": &docker.APIContainers{
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
 			// the k8s prefix is required for the kubelet to manage the container
 			Names: []string{"/k8s--bar.1234--foo.test"},
 			ID:    "1234",
 		},
-		"9876": &docker.APIContainers{
+		{
 			// network container
 			Names: []string{"/k8s--net--foo.test--"},
 			ID:    "9876",
@@ -481,12 +731,12 @@ func TestSyncPodBadHash(t *testing.T) {
 				{Name: "bar"},
 			},
 		},
-	}, dockerContainers)
+	}, getRunningPod(t, kubelet, "foo.test"))
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	verifyCalls(t, fakeDocker, []string{"list", "stop", "list", "create", "start"})
+	verifyCalls(t, fakeDocker, []string{"list", "list", "inspect", "inspect", "stop", "list", "create", "start"})
 
 	// A map interation is used to delete containers, so must not depend on
 	// order here.
@@ -501,14 +751,17 @@ func TestSyncPodBadHash(t *testing.T) {
 
 func TestSyncPodUnhealthy(t *testing.T) {
 	kubelet, _, fakeDocker := newTestKubelet(t)
-	kubelet.healthChecker = &FalseHealthChecker{}
-	dockerContainers := dockertools.DockerContainers{
-		"1234": &docker.APIContainers{
+	kubelet.prober = newProber(&FalseHealthChecker{}, kubelet.readiness)
+	// The liveness probe of the container already failed.
+	kubelet.prober.unhealthy.Insert("1234")
+	defer kubelet.prober.forget("1234")
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
 			// the k8s prefix is required for the kubelet to manage the container
 			Names: []string{"/k8s--bar--foo.test"},
 			ID:    "1234",
 		},
-		"9876": &docker.APIContainers{
+		{
 			// network container
 			Names: []string{"/k8s--net--foo.test--"},
 			ID:    "9876",
@@ -528,12 +781,12 @@ func TestSyncPodUnhealthy(t *testing.T) {
 				},
 			},
 		},
-	}, dockerContainers)
+	}, getRunningPod(t, kubelet, "foo.test"))
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	verifyCalls(t, fakeDocker, []string{"list", "stop", "list", "create", "start"})
+	verifyCalls(t, fakeDocker, []string{"list", "list", "inspect", "inspect", "stop", "list", "create", "start"})
 
 	// A map interation is used to delete containers, so must not depend on
 	// order here.
@@ -559,7 +812,10 @@ func TestMakeEnvVariables(t *testing.T) {
 			},
 		},
 	}
-	vars := makeEnvironmentVariables(&container)
+	vars, err := makeEnvironmentVariables(&Pod{}, &container, "machine", "")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
 	if len(vars) != len(container.Env) {
 		t.Errorf("Vars don't match.  Expected: %#v Found: %#v", container.Env, vars)
 	}
@@ -571,6 +827,101 @@ func TestMakeEnvVariables(t *testing.T) {
 	}
 }
 
+func TestMakeEnvVariablesFromPodFields(t *testing.T) {
+	pod := &Pod{
+		Name:      "foo",
+		Namespace: "test",
+		Manifest: api.ContainerManifest{
+			ID:     "foo",
+			Labels: map[string]string{"name": "foo", "tier": "backend"},
+		},
+	}
+	container := api.Container{
+		Env: []api.EnvVar{
+			{Name: "POD_ID", ValueFrom: &api.EnvVarSource{FieldPath: "id"}},
+			{Name: "HOST", ValueFrom: &api.EnvVarSource{FieldPath: "host"}},
+			{Name: "POD_IP", ValueFrom: &api.EnvVarSource{FieldPath: "podIP"}},
+			{Name: "TIER", ValueFrom: &api.EnvVarSource{FieldPath: "labels.tier"}},
+			{Name: "MISSING", ValueFrom: &api.EnvVarSource{FieldPath: "labels.missing"}},
+			{Name: "LABELS", ValueFrom: &api.EnvVarSource{FieldPath: "labels"}},
+		},
+	}
+	vars, err := makeEnvironmentVariables(pod, &container, "machine", "1.2.3.4")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"POD_ID=foo",
+		"HOST=machine",
+		"POD_IP=1.2.3.4",
+		"TIER=backend",
+		"MISSING=",
+		"LABELS=name=\"foo\"\ntier=\"backend\"\n",
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("expected %q, got %q", expected, vars)
+	}
+	if !usesPodField(&container, "podIP") || usesPodField(&api.Container{}, "podIP") {
+		t.Errorf("expected only the container with POD_IP to use the IP address of the pod")
+	}
+
+	container.Env = []api.EnvVar{{Name: "BAD", ValueFrom: &api.EnvVarSource{FieldPath: "desiredState.host"}}}
+	if _, err := makeEnvironmentVariables(pod, &container, "machine", ""); err == nil {
+		t.Errorf("expected an error for an unsupported field path")
+	}
+}
+
+func TestGetPodField(t *testing.T) {
+	kubelet, _, _ := newTestKubelet(t)
+	kubelet.hostname = "machine"
+	pods := []Pod{{Name: "foo", Namespace: "test", Manifest: api.ContainerManifest{ID: "foo", Labels: map[string]string{"tier": "backend"}}}}
+	kubelet.runtime = &FakeRuntime{}
+	if err := kubelet.SyncPods(pods); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+
+	tests := map[string]string{"id": "foo", "host": "machine", "labels.tier": "backend", "labels": "tier=\"backend\"\n"}
+	for fieldPath, expected := range tests {
+		value, err := kubelet.GetPodField("foo", fieldPath)
+		if err != nil || value != expected {
+			t.Errorf("%s: expected %q, got %q, %v", fieldPath, expected, value, err)
+		}
+	}
+	if _, err := kubelet.GetPodField("foo", "podIP"); err == nil {
+		t.Errorf("expected an error for the IP address of the pod")
+	}
+	if _, err := kubelet.GetPodField("bar", "id"); err == nil {
+		t.Errorf("expected an error for an unknown pod")
+	}
+
+	// The pods of different sources which share an ID are ambiguous.
+	pods = append(pods, Pod{Name: "foo", Namespace: "other", Manifest: api.ContainerManifest{ID: "foo"}})
+	if err := kubelet.SyncPods(pods); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+	if _, err := kubelet.GetPodField("foo", "id"); err == nil {
+		t.Errorf("expected an error for pods which share an ID")
+	}
+}
+
+func TestEnvironmentOfHostNetworkPod(t *testing.T) {
+	kubelet, _, _ := newTestKubelet(t)
+	kubelet.hostname = "10.1.2.3"
+	pod := &Pod{Name: "foo", Namespace: "test", Manifest: api.ContainerManifest{ID: "foo", HostNetwork: true}}
+	container := api.Container{
+		Env: []api.EnvVar{{Name: "POD_IP", ValueFrom: &api.EnvVarSource{FieldPath: "podIP"}}},
+	}
+	vars, err := kubelet.environment(pod, &container, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"POD_IP=10.1.2.3"}; !reflect.DeepEqual(vars, expected) {
+		t.Errorf("expected %q, got %q", expected, vars)
+	}
+}
+
 func TestMountExternalVolumes(t *testing.T) {
 	kubelet, _, _ := newTestKubelet(t)
 	manifest := api.ContainerManifest{
@@ -630,10 +981,13 @@ func TestMakeVolumesAndBinds(t *testing.T) {
 	podVolumes := volumeMap{
 		"disk":  &volume.HostDirectory{"/mnt/disk"},
 		"disk4": &volume.HostDirectory{"/mnt/host"},
-		"disk5": &volume.EmptyDirectory{"disk5", "podID", "/var/lib/kubelet"},
+		"disk5": &volume.EmptyDirectory{Name: "disk5", PodID: "podID", RootDir: "/var/lib/kubelet"},
 	}
 
-	binds := makeBinds(&pod, &container, podVolumes)
+	binds, err := makeBinds(&pod, &container, podVolumes, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
 
 	expectedBinds := []string{
 		"/mnt/disk:/mnt/path",
@@ -648,6 +1002,56 @@ func TestMakeVolumesAndBinds(t *testing.T) {
 	verifyStringArrayEquals(t, binds, expectedBinds)
 }
 
+func TestMakeBindsWithSubPaths(t *testing.T) {
+	shared := &volume.EmptyDirectory{Name: "shared", PodID: "podID", RootDir: "/var/lib/kubelet"}
+	podVolumes := volumeMap{
+		"shared": shared,
+		"secret": &volume.SecretVolume{Name: "secret", PodID: "podID", RootDir: "/var/lib/kubelet"},
+	}
+	container := api.Container{
+		VolumeMounts: []api.VolumeMount{
+			{Name: "shared", MountPath: "/data", SubPath: "foo/data"},
+			{Name: "shared", MountPath: "/logs", SubPath: "logs", ReadOnly: true},
+			{Name: "shared", MountPath: "/mnt", MountPropagation: api.MountPropagationHostToContainer},
+			{Name: "secret", MountPath: "/etc/secret"},
+		},
+	}
+	var mounted []string
+	mountSubPath := func(index int, volumePath, subPath string) (string, error) {
+		mounted = append(mounted, fmt.Sprintf("%d:%s/%s", index, volumePath, subPath))
+		return fmt.Sprintf("/subpaths/%d", index), nil
+	}
+	binds, err := makeBinds(&Pod{Name: "pod", Namespace: "test"}, &container, podVolumes, mountSubPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedBinds := []string{
+		"/subpaths/0:/data",
+		"/subpaths/1:/logs:ro",
+		shared.GetPath() + ":/mnt:rslave",
+		podVolumes["secret"].GetPath() + ":/etc/secret:ro",
+	}
+	verifyStringArrayEquals(t, binds, expectedBinds)
+	expectedMounts := []string{"0:" + shared.GetPath() + "/foo/data", "1:" + shared.GetPath() + "/logs"}
+	if !reflect.DeepEqual(mounted, expectedMounts) {
+		t.Errorf("expected %v to be mounted, got %v", expectedMounts, mounted)
+	}
+
+	// The kubelet replaces the files of secret volumes.
+	container.VolumeMounts = []api.VolumeMount{{Name: "secret", MountPath: "/etc/secret", SubPath: "foo"}}
+	if binds, err := makeBinds(&Pod{Name: "pod", Namespace: "test"}, &container, podVolumes, mountSubPath); err == nil {
+		t.Errorf("expected an error for a subPath of a secret volume, got %v", binds)
+	}
+
+	mountSubPath = func(index int, volumePath, subPath string) (string, error) {
+		return "", fmt.Errorf("subPath %q goes through a symlink", subPath)
+	}
+	container.VolumeMounts = []api.VolumeMount{{Name: "shared", MountPath: "/data", SubPath: "escape/etc"}}
+	if binds, err := makeBinds(&Pod{Name: "pod", Namespace: "test"}, &container, podVolumes, mountSubPath); err == nil {
+		t.Errorf("expected the error of the subPath mount, got %v", binds)
+	}
+}
+
 func TestMakePortsAndBindings(t *testing.T) {
 	container := api.Container{
 		Ports: []api.Port{
@@ -805,11 +1209,10 @@ func TestGetRootInfo(t *testing.T) {
 	mockCadvisor.On("ContainerInfo", containerPath, cadvisorReq).Return(containerInfo, nil)
 
 	kubelet := Kubelet{
-		dockerClient:   &fakeDocker,
-		dockerPuller:   &dockertools.FakeDockerPuller{},
 		cadvisorClient: mockCadvisor,
-		podWorkers:     newPodWorkers(),
 	}
+	kubelet.podWorkers = newPodWorkers(kubelet.listPod)
+	kubelet.runtime = newDockerRuntime(&fakeDocker, &dockertools.FakeDockerPuller{}, nil, &kubelet)
 
 	// If the container name is an empty string, then it means the root container.
 	_, err := kubelet.GetRootInfo(req)
@@ -903,7 +1306,7 @@ func TestRunInContainerNoSuchPod(t *testing.T) {
 	fakeCommandRunner := fakeContainerCommandRunner{}
 	kubelet, _, fakeDocker := newTestKubelet(t)
 	fakeDocker.ContainerList = []docker.APIContainers{}
-	kubelet.runner = &fakeCommandRunner
+	kubelet.runtime.(*dockerRuntime).runner = &fakeCommandRunner
 
 	podName := "podFoo"
 	podNamespace := "etcd"
@@ -924,7 +1327,7 @@ func TestRunInContainerNoSuchPod(t *testing.T) {
 func TestRunInContainer(t *testing.T) {
 	fakeCommandRunner := fakeContainerCommandRunner{}
 	kubelet, _, fakeDocker := newTestKubelet(t)
-	kubelet.runner = &fakeCommandRunner
+	kubelet.runtime.(*dockerRuntime).runner = &fakeCommandRunner
 
 	containerID := "abc1234"
 	podName := "podFoo"
@@ -958,7 +1361,7 @@ func TestRunInContainer(t *testing.T) {
 func TestRunHandlerExec(t *testing.T) {
 	fakeCommandRunner := fakeContainerCommandRunner{}
 	kubelet, _, fakeDocker := newTestKubelet(t)
-	kubelet.runner = &fakeCommandRunner
+	kubelet.runtime.(*dockerRuntime).runner = &fakeCommandRunner
 
 	containerID := "abc1234"
 	podName := "podFoo"
@@ -1069,8 +1472,8 @@ func TestSyncPodEventHandlerFails(t *testing.T) {
 	kubelet.httpClient = &fakeHTTP{
 		err: fmt.Errorf("test error"),
 	}
-	dockerContainers := dockertools.DockerContainers{
-		"9876": &docker.APIContainers{
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
 			// network container
 			Names: []string{"/k8s--net--foo.test--"},
 			ID:    "9876",
@@ -1095,14 +1498,468 @@ func TestSyncPodEventHandlerFails(t *testing.T) {
 				},
 			},
 		},
-	}, dockerContainers)
+	}, getRunningPod(t, kubelet, "foo.test"))
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	verifyCalls(t, fakeDocker, []string{"list", "list", "create", "start", "stop"})
+	verifyCalls(t, fakeDocker, []string{"list", "list", "inspect", "list", "create", "start", "stop"})
 
 	if len(fakeDocker.Stopped) != 1 {
 		t.Errorf("Wrong containers were stopped: %v", fakeDocker.Stopped)
 	}
 }
+
+func TestSyncPodsWithFakeRuntime(t *testing.T) {
+	fakeRuntime := &FakeRuntime{
+		PodList: RunningPods{
+			{FullName: "foo.test", Containers: []*RunningContainer{{ID: "1234", Name: "bar", Running: true}}},
+			{FullName: "stale.test", Containers: []*RunningContainer{{ID: "5678", Name: "baz", Running: true}}},
+		},
+	}
+	kubelet := &Kubelet{runtime: fakeRuntime, readiness: newReadinessStates(), rootDirectory: "/tmp/kubelet"}
+	kubelet.podWorkers = newPodWorkers(kubelet.listPod)
+	err := kubelet.SyncPods([]Pod{
+		{
+			Name:      "foo",
+			Namespace: "test",
+			Manifest: api.ContainerManifest{
+				ID:         "foo",
+				Containers: []api.Container{{Name: "bar"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+
+	if !reflect.DeepEqual(fakeRuntime.SyncedPods, []string{"foo.test"}) {
+		t.Errorf("unexpected synced pods: %v", fakeRuntime.SyncedPods)
+	}
+	if !reflect.DeepEqual(fakeRuntime.KilledPods, []string{"stale.test"}) {
+		t.Errorf("unexpected killed pods: %v", fakeRuntime.KilledPods)
+	}
+	if len(fakeRuntime.PodList) != 1 || fakeRuntime.PodList[0].FullName != "foo.test" {
+		t.Errorf("unexpected pods left: %v", fakeRuntime.PodList)
+	}
+}
+
+func TestRunInContainerWithFakeRuntime(t *testing.T) {
+	fakeRuntime := &FakeRuntime{
+		PodList: RunningPods{
+			{FullName: "foo.test", UUID: "uuid", Containers: []*RunningContainer{{ID: "1234", Name: "bar", Running: true}}},
+		},
+	}
+	kubelet := &Kubelet{runtime: fakeRuntime}
+	if _, err := kubelet.RunInContainer("foo.test", "", "bar", []string{"ls"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := kubelet.RunInContainer("foo.test", "", "missing", []string{"ls"}); err == nil {
+		t.Errorf("expected error for a missing container")
+	}
+	if err := fakeRuntime.AssertCalls([]string{"GetPods", "RunInContainer", "GetPods"}); err != nil {
+		t.Error(err)
+	}
+	if !reflect.DeepEqual(fakeRuntime.Commands, [][]string{{"ls"}}) {
+		t.Errorf("unexpected commands: %v", fakeRuntime.Commands)
+	}
+}
+
+func TestSyncChangedPodsWithFakeRuntime(t *testing.T) {
+	fakeRuntime := &FakeRuntime{
+		PodList: RunningPods{
+			{FullName: "stale.test", Containers: []*RunningContainer{{ID: "5678", Name: "baz", Running: true}}},
+		},
+	}
+	kubelet := &Kubelet{runtime: fakeRuntime, readiness: newReadinessStates(), rootDirectory: "/tmp/kubelet"}
+	kubelet.podWorkers = newPodWorkers(kubelet.listPod)
+	pods := []Pod{
+		{Name: "foo", Namespace: "test", Manifest: api.ContainerManifest{ID: "foo", Containers: []api.Container{{Name: "bar"}}}},
+		{Name: "bar", Namespace: "test", Manifest: api.ContainerManifest{ID: "bar", Containers: []api.Container{{Name: "bar"}}}},
+	}
+	err := kubelet.SyncChangedPods(pods, util.NewStringSet("bar.test"))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+
+	if !reflect.DeepEqual(fakeRuntime.SyncedPods, []string{"bar.test"}) {
+		t.Errorf("unexpected synced pods: %v", fakeRuntime.SyncedPods)
+	}
+	if !reflect.DeepEqual(fakeRuntime.KilledPods, []string{"stale.test"}) {
+		t.Errorf("unexpected killed pods: %v", fakeRuntime.KilledPods)
+	}
+}
+
+func TestChangedPods(t *testing.T) {
+	oldPods := []Pod{
+		{Name: "foo", Namespace: "test", Manifest: api.ContainerManifest{ID: "foo", Containers: []api.Container{{Name: "bar"}}}},
+		{Name: "bar", Namespace: "test", Manifest: api.ContainerManifest{ID: "bar", Containers: []api.Container{{Name: "bar"}}}},
+	}
+	pods := []Pod{
+		{Name: "foo", Namespace: "test", Manifest: api.ContainerManifest{ID: "foo", Containers: []api.Container{{Name: "bar"}}}},
+		{Name: "bar", Namespace: "test", Manifest: api.ContainerManifest{ID: "bar", Containers: []api.Container{{Name: "bar", Image: "new"}}}},
+		{Name: "new", Namespace: "test", Manifest: api.ContainerManifest{ID: "new"}},
+	}
+	changed := changedPods(oldPods, pods)
+	if !reflect.DeepEqual(changed, util.NewStringSet("bar.test", "new.test")) {
+		t.Errorf("unexpected changed pods: %v", changed.List())
+	}
+}
+
+func TestSyncPodsListsContainersInWorker(t *testing.T) {
+	fakeRuntime := &FakeRuntime{}
+	kubelet := &Kubelet{
+		runtime:       fakeRuntime,
+		readiness:     newReadinessStates(),
+		rootDirectory: "/tmp/kubelet",
+	}
+	kubelet.podWorkers = newPodWorkers(kubelet.listPod)
+	// A previous worker for the pod is still running when the pods are synced, and starts the
+	// containers of the pod before it finishes.
+	release := make(chan struct{})
+	kubelet.podWorkers.Run("foo.test", "", RunningPod{}, func(RunningPod) {
+		<-release
+		fakeRuntime.Lock()
+		defer fakeRuntime.Unlock()
+		fakeRuntime.PodList = RunningPods{{FullName: "foo.test", Containers: []*RunningContainer{{ID: "1234", Name: "bar", Running: true}}}}
+	})
+	pods := []Pod{{Name: "foo", Namespace: "test", Manifest: api.ContainerManifest{ID: "foo", Containers: []api.Container{{Name: "bar"}}}}}
+	if err := kubelet.SyncPods(pods); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	close(release)
+	kubelet.drainWorkers()
+
+	if len(fakeRuntime.SyncedRunningPods) != 1 || len(fakeRuntime.SyncedRunningPods[0].Containers) != 1 {
+		t.Errorf("expected the pod to be synced with the containers started by the previous worker, got %#v", fakeRuntime.SyncedRunningPods)
+	}
+}
+
+func TestPodWorkersRunsPendingActions(t *testing.T) {
+	listed := []string{}
+	workers := newPodWorkers(func(podFullName, uuid string) (RunningPod, error) {
+		listed = append(listed, uuid)
+		return RunningPod{FullName: podFullName, UUID: uuid}, nil
+	})
+	release := make(chan struct{})
+	done := make(chan string, 4)
+	workers.Run("foo", "old", RunningPod{}, func(RunningPod) {
+		<-release
+		done <- "first"
+	})
+	// The last action requested for an instance of the pod replaces the pending one, and the
+	// actions of different instances run in the order they were requested.
+	workers.Run("foo", "old", RunningPod{}, func(runningPod RunningPod) { done <- "second" })
+	workers.Run("foo", "new", RunningPod{}, func(runningPod RunningPod) { done <- "sync " + runningPod.UUID })
+	workers.Run("foo", "old", RunningPod{}, func(runningPod RunningPod) { done <- "kill " + runningPod.UUID })
+	close(release)
+
+	for _, expected := range []string{"first", "kill old", "sync new"} {
+		if action := <-done; action != expected {
+			t.Errorf("expected %s, got %s", expected, action)
+		}
+	}
+	for {
+		workers.lock.Lock()
+		length := len(workers.workers)
+		workers.lock.Unlock()
+		if length == 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(done) != 0 {
+		t.Errorf("unexpected action run: %s", <-done)
+	}
+	if !reflect.DeepEqual(listed, []string{"old", "new"}) {
+		t.Errorf("expected the pods of the pending actions to be listed again, got %v", listed)
+	}
+}
+
+func TestSyncPodsListsContainersOnce(t *testing.T) {
+	fakeRuntime := &FakeRuntime{
+		PodList: RunningPods{
+			{FullName: "foo.test", Containers: []*RunningContainer{{ID: "1234", Name: "bar", Running: true}}},
+			{FullName: "stale.test", Containers: []*RunningContainer{{ID: "5678", Name: "baz", Running: true}}},
+		},
+	}
+	kubelet := &Kubelet{runtime: fakeRuntime, readiness: newReadinessStates(), rootDirectory: "/tmp/kubelet"}
+	kubelet.podWorkers = newPodWorkers(kubelet.listPod)
+	pods := []Pod{{Name: "foo", Namespace: "test", Manifest: api.ContainerManifest{ID: "foo", Containers: []api.Container{{Name: "bar"}}}}}
+	if err := kubelet.SyncPods(pods); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+
+	listings := 0
+	for _, call := range fakeRuntime.called {
+		if call == "GetPods" {
+			listings++
+		}
+	}
+	if listings != 1 {
+		t.Errorf("expected the containers to be listed once, got %v", fakeRuntime.called)
+	}
+	if len(fakeRuntime.SyncedRunningPods) != 1 || len(fakeRuntime.SyncedRunningPods[0].Containers) != 1 {
+		t.Errorf("expected the pod to be synced with its listed containers, got %#v", fakeRuntime.SyncedRunningPods)
+	}
+	if !reflect.DeepEqual(fakeRuntime.KilledPods, []string{"stale.test"}) {
+		t.Errorf("expected the stale pod to be killed, got %v", fakeRuntime.KilledPods)
+	}
+}
+
+func TestSyncPodsKillsOldInstanceAndSyncsNewOne(t *testing.T) {
+	fakeRuntime := &FakeRuntime{
+		PodList: RunningPods{
+			{FullName: "foo.test", UUID: "old", Containers: []*RunningContainer{{ID: "1234", Name: "bar", Running: true}}},
+		},
+	}
+	kubelet := &Kubelet{runtime: fakeRuntime, readiness: newReadinessStates(), rootDirectory: "/tmp/kubelet"}
+	kubelet.podWorkers = newPodWorkers(kubelet.listPod)
+	// The kill of the old instance shares the worker of the sync of the new one, and must not
+	// replace it.
+	pods := []Pod{{Name: "foo", Namespace: "test", Manifest: api.ContainerManifest{ID: "foo", UUID: "new", Containers: []api.Container{{Name: "bar"}}}}}
+	if err := kubelet.SyncPods(pods); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+
+	if !reflect.DeepEqual(fakeRuntime.SyncedPods, []string{"foo.test"}) {
+		t.Errorf("expected the new instance to be synced, got %v", fakeRuntime.SyncedPods)
+	}
+	if !reflect.DeepEqual(fakeRuntime.KilledPods, []string{"foo.test"}) {
+		t.Errorf("expected the old instance to be killed, got %v", fakeRuntime.KilledPods)
+	}
+	if len(fakeRuntime.PodList) != 1 || fakeRuntime.PodList[0].UUID != "new" {
+		t.Errorf("expected only the new instance to be left, got %#v", fakeRuntime.PodList)
+	}
+}
+
+func TestKillPodRunsPreStopHandler(t *testing.T) {
+	fakeCommandRunner := fakeContainerCommandRunner{}
+	kubelet, _, fakeDocker := newTestKubelet(t)
+	kubelet.runtime.(*dockerRuntime).runner = &fakeCommandRunner
+	fakeDocker.ContainerList = []docker.APIContainers{
+		{
+			ID:    "1234",
+			Names: []string{"/k8s--bar--foo.test"},
+		},
+		{
+			ID:    "9876",
+			Names: []string{"/k8s--net--foo.test"},
+		},
+	}
+	pod := &Pod{
+		Name:      "foo",
+		Namespace: "test",
+		Manifest: api.ContainerManifest{
+			ID: "foo",
+			Containers: []api.Container{
+				{
+					Name: "bar",
+					Lifecycle: &api.Lifecycle{
+						PreStop: &api.Handler{
+							Exec: &api.ExecAction{Command: []string{"shutdown"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	runningPod := getRunningPod(t, kubelet, "foo.test")
+
+	if err := kubelet.runtime.KillPod(pod, runningPod); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if fakeCommandRunner.ID != "1234" || !reflect.DeepEqual(fakeCommandRunner.Cmd, []string{"shutdown"}) {
+		t.Errorf("PreStop handler was not run: %#v", fakeCommandRunner)
+	}
+	// The network container is stopped last.
+	if !reflect.DeepEqual(fakeDocker.Stopped, []string{"1234", "9876"}) {
+		t.Errorf("unexpected stopped containers: %v", fakeDocker.Stopped)
+	}
+}
+
+type fakeNetworkPlugin struct {
+	calls []string
+	ip    string
+	err   error
+}
+
+func (f *fakeNetworkPlugin) SetUpPod(podFullName, containerID, netns string) error {
+	f.calls = append(f.calls, fmt.Sprintf("setup %s %s %s", podFullName, containerID, netns))
+	return f.err
+}
+
+func (f *fakeNetworkPlugin) TearDownPod(podFullName, containerID, netns string) error {
+	f.calls = append(f.calls, fmt.Sprintf("teardown %s %s %s", podFullName, containerID, netns))
+	return f.err
+}
+
+func (f *fakeNetworkPlugin) PodIP(podFullName, containerID, netns string) (string, error) {
+	f.calls = append(f.calls, fmt.Sprintf("status %s %s %s", podFullName, containerID, netns))
+	return f.ip, f.err
+}
+
+func TestNetworkPluginSetsUpAndTearsDownPods(t *testing.T) {
+	plugin := &fakeNetworkPlugin{ip: "10.1.2.3"}
+	kubelet, _, fakeDocker := newTestKubelet(t)
+	kubelet.runtime.(*dockerRuntime).network = plugin
+	fakeDocker.Container = &docker.Container{ID: "/k8s--net--foo.test", State: docker.State{Running: true, Pid: 42}}
+	pod := &Pod{Name: "foo", Namespace: "test", Manifest: api.ContainerManifest{ID: "foo"}}
+
+	if err := kubelet.runtime.SyncPod(pod, RunningPod{}, volumeMap{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	fakeDocker.Lock()
+	if len(fakeDocker.Created) != 1 {
+		t.Fatalf("expected the network container to be created, got %v", fakeDocker.Created)
+	}
+	netID := "/" + fakeDocker.Created[0]
+	fakeDocker.Unlock()
+	if len(plugin.calls) == 0 || plugin.calls[0] != "setup foo.test "+netID+" /proc/42/ns/net" {
+		t.Errorf("expected the network of the pod to be set up, got %v", plugin.calls)
+	}
+
+	info, err := kubelet.runtime.GetPodInfo("foo.test", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if settings := info[networkContainerName].DetailInfo.NetworkSettings; settings == nil || settings.IPAddress != "10.1.2.3" {
+		t.Errorf("expected the IP address reported by the plugin, got %#v", settings)
+	}
+	plugin.calls = nil
+	if _, err := kubelet.runtime.GetPodInfo("foo.test", ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plugin.calls) != 0 {
+		t.Errorf("expected the IP address of the pod to be cached, got %v", plugin.calls)
+	}
+
+	plugin.calls = nil
+	if err := kubelet.runtime.KillPod(pod, getRunningPod(t, kubelet, "foo.test")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(plugin.calls, []string{"teardown foo.test " + netID + " /proc/42/ns/net"}) {
+		t.Errorf("expected the network of the pod to be torn down, got %v", plugin.calls)
+	}
+}
+
+func TestNetworkPluginTearsDownExitedNetworkContainers(t *testing.T) {
+	plugin := &fakeNetworkPlugin{}
+	kubelet, _, fakeDocker := newTestKubelet(t)
+	kubelet.runtime.(*dockerRuntime).network = plugin
+	runningPods := RunningPods{{FullName: "foo.test", Containers: []*RunningContainer{{ID: "1234", Name: networkContainerName, Running: true}}}}
+
+	kubelet.runtime.CleanupPods(runningPods)
+	if len(plugin.calls) != 0 {
+		t.Errorf("expected the network of the running network container to be kept, got %v", plugin.calls)
+	}
+
+	// The network container exits on its own.
+	fakeDocker.Container = &docker.Container{ID: "1234", State: docker.State{Running: false}}
+	kubelet.runtime.CleanupPods(RunningPods{})
+	if !reflect.DeepEqual(plugin.calls, []string{"teardown foo.test 1234 "}) {
+		t.Errorf("expected the network of the exited network container to be torn down, got %v", plugin.calls)
+	}
+	plugin.calls = nil
+	kubelet.runtime.CleanupPods(RunningPods{})
+	if len(plugin.calls) != 0 {
+		t.Errorf("expected the network to be torn down once, got %v", plugin.calls)
+	}
+}
+
+func TestNetworkPluginTearsDownDuplicateNetworkContainers(t *testing.T) {
+	plugin := &fakeNetworkPlugin{}
+	kubelet, _, fakeDocker := newTestKubelet(t)
+	kubelet.runtime.(*dockerRuntime).network = plugin
+	fakeDocker.Container = &docker.Container{State: docker.State{Running: true, Pid: 42}}
+	pod := &Pod{Name: "foo", Namespace: "test", Manifest: api.ContainerManifest{ID: "foo"}}
+	runningPod := RunningPod{
+		FullName: "foo.test",
+		Containers: []*RunningContainer{
+			{ID: "1234", Name: networkContainerName, Running: true},
+			{ID: "9876", Name: networkContainerName, Running: true},
+		},
+	}
+
+	if err := kubelet.runtime.SyncPod(pod, runningPod, volumeMap{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(plugin.calls, []string{"teardown foo.test 9876 /proc/42/ns/net"}) {
+		t.Errorf("expected the network of the duplicate network container to be torn down, got %v", plugin.calls)
+	}
+	fakeDocker.Lock()
+	defer fakeDocker.Unlock()
+	if !reflect.DeepEqual(fakeDocker.Stopped, []string{"9876"}) {
+		t.Errorf("expected the duplicate network container to be killed, got %v", fakeDocker.Stopped)
+	}
+}
+
+func TestNetworkPluginSetUpFailure(t *testing.T) {
+	plugin := &fakeNetworkPlugin{err: fmt.Errorf("no addresses left")}
+	kubelet, _, fakeDocker := newTestKubelet(t)
+	kubelet.runtime.(*dockerRuntime).network = plugin
+	fakeDocker.Container = &docker.Container{State: docker.State{Running: true, Pid: 42}}
+	pod := &Pod{Name: "foo", Namespace: "test", Manifest: api.ContainerManifest{ID: "foo", Containers: []api.Container{{Name: "bar"}}}}
+
+	if err := kubelet.runtime.SyncPod(pod, RunningPod{}, volumeMap{}); err == nil {
+		t.Errorf("expected an error")
+	}
+	fakeDocker.Lock()
+	defer fakeDocker.Unlock()
+	if len(fakeDocker.Created) != 1 || len(fakeDocker.Stopped) != 1 {
+		t.Errorf("expected only the network container to be created and killed, created %v, stopped %v", fakeDocker.Created, fakeDocker.Stopped)
+	}
+}
+
+func TestSyncPodsKillsWithLastKnownSpec(t *testing.T) {
+	fakeRuntime := &FakeRuntime{}
+	kubelet := &Kubelet{runtime: fakeRuntime, readiness: newReadinessStates(), rootDirectory: "/tmp/kubelet"}
+	kubelet.podWorkers = newPodWorkers(kubelet.listPod)
+	pods := []Pod{
+		{
+			Name:      "foo",
+			Namespace: "test",
+			Manifest: api.ContainerManifest{
+				ID:                            "foo",
+				Containers:                    []api.Container{{Name: "bar"}},
+				TerminationGracePeriodSeconds: 5,
+			},
+		},
+	}
+	if err := kubelet.SyncPods(pods); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+	if err := kubelet.SyncPods([]Pod{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+	if err := kubelet.SyncPods([]Pod{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(fakeRuntime.KilledPods, []string{"foo.test"}) {
+		t.Errorf("unexpected killed pods: %v", fakeRuntime.KilledPods)
+	}
+	if fakeRuntime.KilledSpecs[0] == nil || fakeRuntime.KilledSpecs[0].Manifest.TerminationGracePeriodSeconds != 5 {
+		t.Errorf("expected the pod to be killed with its last known spec, got %#v", fakeRuntime.KilledSpecs[0])
+	}
+	// Once the pod is gone, its spec is forgotten.
+	if len(kubelet.podSpecs) != 0 {
+		t.Errorf("unexpected pod specs: %v", kubelet.podSpecs)
+	}
+}
+
+func TestTerminationGracePeriod(t *testing.T) {
+	if period := terminationGracePeriod(nil); period != defaultTerminationGracePeriod {
+		t.Errorf("expected %v, got %v", defaultTerminationGracePeriod, period)
+	}
+	pod := &Pod{Manifest: api.ContainerManifest{TerminationGracePeriodSeconds: 5}}
+	if period := terminationGracePeriod(pod); period != 5*time.Second {
+		t.Errorf("expected 5s, got %v", period)
+	}
+}
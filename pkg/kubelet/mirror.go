@@ -0,0 +1,171 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/latest"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/validation"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/golang/glog"
+)
+
+// The etcd directory the apiserver stores pods in.
+const etcdPodsKey = "/registry/pods"
+
+func mirrorPodKey(mirrorID string) string {
+	return etcdPodsKey + "/" + mirrorID
+}
+
+// mirrorPodID returns the ID of the mirror pod of a static pod. It includes the hostname, since
+// the same config file is often used on every host.
+func mirrorPodID(pod *Pod, hostname string) string {
+	return fmt.Sprintf("%s-%s-%s", pod.Name, pod.Namespace, hostname)
+}
+
+// mirrorPods keeps a mirror pod in the apiserver for every static pod of the kubelet, so that
+// static pods are visible to users and to the scheduler. Mirror pods are written to the etcd
+// registry of the apiserver directly, since the apiserver refuses to change them.
+type mirrorPods struct {
+	hostname string
+	helper   tools.EtcdHelper
+
+	// The manifests of the mirror pods written so far, by mirror pod ID. Nil until the mirror
+	// pods left behind by a previous run of the kubelet are cleaned up.
+	mirrored map[string]*api.ContainerManifest
+
+	lock sync.Mutex
+	// The full names of the static pods, by the full name the kubelet server knows their mirror
+	// pods by.
+	staticPods map[string]string
+}
+
+func newMirrorPods(hostname string, client tools.EtcdClient) *mirrorPods {
+	return &mirrorPods{
+		hostname: hostname,
+		helper: tools.EtcdHelper{
+			client,
+			latest.Codec,
+			latest.ResourceVersioner,
+		},
+		staticPods: make(map[string]string),
+	}
+}
+
+// sync creates or updates the mirror pods of the static pods in pods, and deletes the mirror
+// pods of the static pods which are gone. It is only called from the sync loop.
+func (m *mirrorPods) sync(pods []Pod) {
+	if m.mirrored == nil {
+		if err := m.cleanup(); err != nil {
+			glog.Errorf("Error listing the mirror pods of %s: %v", m.hostname, err)
+			return
+		}
+	}
+	desired := util.NewStringSet()
+	staticPods := make(map[string]string)
+	for i := range pods {
+		pod := &pods[i]
+		if !IsStaticPod(pod) {
+			continue
+		}
+		id := mirrorPodID(pod, m.hostname)
+		desired.Insert(id)
+		staticPods[GetPodFullName(&Pod{Name: id, Namespace: EtcdSource})] = GetPodFullName(pod)
+		if manifest, found := m.mirrored[id]; found && reflect.DeepEqual(*manifest, pod.Manifest) {
+			continue
+		}
+		if err := m.set(id, pod); err != nil {
+			glog.Errorf("Error writing the mirror pod of %s: %v", GetPodFullName(pod), err)
+			continue
+		}
+		manifest := pod.Manifest
+		m.mirrored[id] = &manifest
+	}
+	for id := range m.mirrored {
+		if desired.Has(id) {
+			continue
+		}
+		if err := m.helper.Delete(mirrorPodKey(id), false); err != nil && !tools.IsEtcdNotFound(err) {
+			glog.Errorf("Error deleting mirror pod %s: %v", id, err)
+			continue
+		}
+		delete(m.mirrored, id)
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.staticPods = staticPods
+}
+
+// set writes the mirror pod of a static pod. A pod with the same ID which isn't a mirror pod of
+// the host is left alone. The mirror pod is validated like the apiserver validates pods, since it
+// is written to its registry directly.
+func (m *mirrorPods) set(id string, pod *Pod) error {
+	return m.helper.AtomicUpdate(mirrorPodKey(id), &api.Pod{}, func(obj runtime.Object) (runtime.Object, error) {
+		mirror := obj.(*api.Pod)
+		if mirror.ID == "" {
+			mirror.ID = id
+			mirror.CreationTimestamp = util.Now()
+		} else if mirror.Labels[api.MirrorPodLabel] != m.hostname {
+			return nil, fmt.Errorf("pod %s already exists and is not a mirror pod of %s", id, m.hostname)
+		}
+		mirror.Labels = map[string]string{api.MirrorPodLabel: m.hostname}
+		mirror.DesiredState = api.PodState{
+			Manifest: pod.Manifest,
+			Status:   api.PodRunning,
+			Host:     m.hostname,
+		}
+		mirror.DesiredState.Manifest.ID = id
+		if errs := validation.ValidatePod(mirror); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid mirror pod %s: %v", id, errs)
+		}
+		return mirror, nil
+	})
+}
+
+// cleanup records the mirror pods of the host, so that the ones of the static pods which were
+// removed while the kubelet was down get deleted.
+func (m *mirrorPods) cleanup() error {
+	var pods []api.Pod
+	if err := m.helper.ExtractList(etcdPodsKey, &pods, nil); err != nil {
+		return err
+	}
+	m.mirrored = make(map[string]*api.ContainerManifest)
+	for i := range pods {
+		if pods[i].Labels[api.MirrorPodLabel] == m.hostname {
+			m.mirrored[pods[i].ID] = &pods[i].DesiredState.Manifest
+		}
+	}
+	return nil
+}
+
+// staticPodFullName returns the full name of the static pod whose mirror pod is known to the
+// kubelet server by podFullName, or podFullName if it is not the name of a mirror pod.
+func (m *mirrorPods) staticPodFullName(podFullName string) string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if staticPodFullName, found := m.staticPods[podFullName]; found {
+		return staticPodFullName
+	}
+	return podFullName
+}
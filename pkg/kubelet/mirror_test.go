@@ -0,0 +1,110 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/latest"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func getMirrorPod(t *testing.T, fakeClient *tools.FakeEtcdClient, id string) *api.Pod {
+	var pod api.Pod
+	if err := latest.Codec.DecodeInto([]byte(fakeClient.Data[mirrorPodKey(id)].R.Node.Value), &pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return &pod
+}
+
+func TestSyncMirrorPods(t *testing.T) {
+	fakeClient := tools.NewFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+	fakeClient.Data[etcdPodsKey] = tools.EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Nodes: []*etcd.Node{
+					{Value: runtime.EncodeOrDie(latest.Codec, &api.Pod{
+						JSONBase: api.JSONBase{ID: "stale-file-machine"},
+						Labels:   map[string]string{api.MirrorPodLabel: "machine"},
+					})},
+					{Value: runtime.EncodeOrDie(latest.Codec, &api.Pod{
+						JSONBase: api.JSONBase{ID: "stale-file-other"},
+						Labels:   map[string]string{api.MirrorPodLabel: "other"},
+					})},
+					{Value: runtime.EncodeOrDie(latest.Codec, &api.Pod{
+						JSONBase: api.JSONBase{ID: "scheduled"},
+					})},
+					{Value: runtime.EncodeOrDie(latest.Codec, &api.Pod{
+						JSONBase: api.JSONBase{ID: "user-file-machine"},
+					})},
+				},
+			},
+		},
+	}
+	fakeClient.ExpectNotFoundGet(mirrorPodKey("foo-file-machine"))
+	fakeClient.ExpectNotFoundGet(mirrorPodKey("invalid-file-machine"))
+	user := &api.Pod{JSONBase: api.JSONBase{ID: "user-file-machine"}}
+	fakeClient.Set(mirrorPodKey("user-file-machine"), runtime.EncodeOrDie(latest.Codec, user), 0)
+	mirrors := newMirrorPods("machine", fakeClient)
+	pods := []Pod{
+		{Name: "foo", Namespace: FileSource, Manifest: api.ContainerManifest{Version: "v1beta1", ID: "foo", Containers: []api.Container{{Name: "bar", Image: "bar"}}}},
+		{Name: "scheduled", Namespace: EtcdSource, Manifest: api.ContainerManifest{ID: "scheduled"}},
+		// A pod of a user has the ID the mirror pod would have.
+		{Name: "user", Namespace: FileSource, Manifest: api.ContainerManifest{Version: "v1beta1", ID: "user", Containers: []api.Container{{Name: "bar", Image: "bar"}}}},
+		{Name: "invalid", Namespace: FileSource, Manifest: api.ContainerManifest{Version: "v1beta1", ID: "invalid", Containers: []api.Container{{Name: "bar"}}}},
+	}
+
+	mirrors.sync(pods)
+	mirror := getMirrorPod(t, fakeClient, "foo-file-machine")
+	if mirror.ID != "foo-file-machine" || mirror.Labels[api.MirrorPodLabel] != "machine" || mirror.DesiredState.Host != "machine" {
+		t.Errorf("unexpected mirror pod: %#v", mirror)
+	}
+	if mirror.DesiredState.Manifest.ID != "foo-file-machine" || !reflect.DeepEqual(mirror.DesiredState.Manifest.Containers, pods[0].Manifest.Containers) {
+		t.Errorf("unexpected mirror pod manifest: %#v", mirror.DesiredState.Manifest)
+	}
+	if !reflect.DeepEqual(fakeClient.DeletedKeys, []string{mirrorPodKey("stale-file-machine")}) {
+		t.Errorf("expected the stale mirror pod of the host to be deleted, got %v", fakeClient.DeletedKeys)
+	}
+	if name := mirrors.staticPodFullName("foo-file-machine.etcd"); name != "foo.file" {
+		t.Errorf("expected the mirror pod to resolve to its static pod, got %s", name)
+	}
+	if name := mirrors.staticPodFullName("scheduled.etcd"); name != "scheduled.etcd" {
+		t.Errorf("expected other pods to be left alone, got %s", name)
+	}
+	if pod := getMirrorPod(t, fakeClient, "user-file-machine"); !reflect.DeepEqual(pod, user) {
+		t.Errorf("expected the pod of the user to be left alone, got %#v", pod)
+	}
+	if response := fakeClient.Data[mirrorPodKey("invalid-file-machine")]; response.R != nil && response.R.Node != nil {
+		t.Errorf("expected no mirror pod for an invalid static pod")
+	}
+
+	pods[0].Manifest.Containers = []api.Container{{Name: "bar", Image: "new"}}
+	mirrors.sync(pods)
+	if mirror := getMirrorPod(t, fakeClient, "foo-file-machine"); mirror.DesiredState.Manifest.Containers[0].Image != "new" {
+		t.Errorf("expected the mirror pod to be updated, got %#v", mirror)
+	}
+
+	mirrors.sync(pods[1:2])
+	if !reflect.DeepEqual(fakeClient.DeletedKeys, []string{mirrorPodKey("stale-file-machine"), mirrorPodKey("foo-file-machine")}) {
+		t.Errorf("expected the mirror pod to be deleted, got %v", fakeClient.DeletedKeys)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// The commands an exec plugin is called with, followed by the full name of the pod, the ID of
+// its network container and the path of its network namespace.
+const (
+	setUpCommand    = "setup"
+	tearDownCommand = "teardown"
+	statusCommand   = "status"
+)
+
+// execPlugin runs an executable to set up and tear down the network of pods. The status command
+// prints the IP address of the pod, if any.
+type execPlugin struct {
+	path string
+}
+
+// NewExecPlugin returns a Plugin which runs the executable at path.
+func NewExecPlugin(path string) Plugin {
+	return &execPlugin{path}
+}
+
+// run returns the standard output of the plugin. Its standard error is only logged, or reported
+// if the plugin fails.
+func (p *execPlugin) run(command, podFullName, containerID, netns string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(p.path, command, podFullName, containerID, netns)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("network plugin %s %s of pod %s failed: %v (%s)", p.path, command, podFullName, err, strings.TrimSpace(stderr.String()))
+	}
+	glog.V(4).Infof("Network plugin %s %s of pod %s: %s (%s)", p.path, command, podFullName, strings.TrimSpace(string(output)), strings.TrimSpace(stderr.String()))
+	return string(output), nil
+}
+
+func (p *execPlugin) SetUpPod(podFullName, containerID, netns string) error {
+	_, err := p.run(setUpCommand, podFullName, containerID, netns)
+	return err
+}
+
+func (p *execPlugin) TearDownPod(podFullName, containerID, netns string) error {
+	_, err := p.run(tearDownCommand, podFullName, containerID, netns)
+	return err
+}
+
+func (p *execPlugin) PodIP(podFullName, containerID, netns string) (string, error) {
+	output, err := p.run(statusCommand, podFullName, containerID, netns)
+	if err != nil {
+		return "", err
+	}
+	ip := strings.TrimSpace(output)
+	if ip != "" && net.ParseIP(ip) == nil {
+		return "", fmt.Errorf("network plugin %s %s of pod %s printed an invalid IP address: %q", p.path, statusCommand, podFullName, ip)
+	}
+	return ip, nil
+}
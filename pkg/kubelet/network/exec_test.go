@@ -0,0 +1,73 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPlugin = `#!/bin/sh
+echo "$@" >> "$(dirname "$0")/calls"
+case "$1" in
+status)
+	echo "looking up $2" >&2
+	if [ "$2" = "bad.test" ]; then echo "unknown"; else echo " 10.1.2.3"; fi ;;
+teardown) echo "no such pod" >&2; exit 1 ;;
+esac
+`
+
+func TestExecPlugin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plugin")
+	if err := ioutil.WriteFile(path, []byte(testPlugin), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plugin := NewExecPlugin(path)
+
+	if err := plugin.SetUpPod("foo.test", "1234", "/proc/42/ns/net"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	ip, err := plugin.PodIP("foo.test", "1234", "/proc/42/ns/net")
+	if err != nil || ip != "10.1.2.3" {
+		t.Errorf("expected the IP of the pod, got %q, %v", ip, err)
+	}
+	// Anything but an IP address on standard output is rejected.
+	if ip, err := plugin.PodIP("bad.test", "5678", ""); err == nil {
+		t.Errorf("expected an error for an invalid IP address, got %q", ip)
+	}
+	err = plugin.TearDownPod("foo.test", "1234", "/proc/42/ns/net")
+	if err == nil || !strings.Contains(err.Error(), "no such pod") {
+		t.Errorf("expected the output of the plugin in the error, got %v", err)
+	}
+
+	calls, err := ioutil.ReadFile(filepath.Join(dir, "calls"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "setup foo.test 1234 /proc/42/ns/net\nstatus foo.test 1234 /proc/42/ns/net\nstatus bad.test 5678 \nteardown foo.test 1234 /proc/42/ns/net\n"
+	if string(calls) != expected {
+		t.Errorf("expected calls %q, got %q", expected, calls)
+	}
+}
@@ -0,0 +1,32 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package network
+
+// Plugin sets up and tears down the network of pods. The containers of a pod share the network
+// namespace of the network container of the pod, which the plugin configures. netns is the path
+// of that namespace, e.g. /proc/<pid>/ns/net.
+type Plugin interface {
+	// SetUpPod is called after the network container of a pod is started, and before the other
+	// containers of the pod are.
+	SetUpPod(podFullName, containerID, netns string) error
+	// TearDownPod is called before the network container of a pod is stopped, or once it is
+	// found to have exited, in which case netns is "". It may be called again for a pod whose
+	// network was already torn down.
+	TearDownPod(podFullName, containerID, netns string) error
+	// PodIP returns the IP address of a pod, or "" if the address Docker assigned is used.
+	PodIP(podFullName, containerID, netns string) (string, error)
+}
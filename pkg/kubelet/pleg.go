@@ -0,0 +1,122 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/golang/glog"
+)
+
+// PodLifecycleEventType is the kind of change a PodLifecycleEvent reports.
+type PodLifecycleEventType string
+
+const (
+	// ContainerStarted means that a container of the pod is now running.
+	ContainerStarted PodLifecycleEventType = "ContainerStarted"
+	// ContainerDied means that a container of the pod is no longer running.
+	ContainerDied PodLifecycleEventType = "ContainerDied"
+	// ContainerRemoved means that a container of the pod no longer exists.
+	ContainerRemoved PodLifecycleEventType = "ContainerRemoved"
+)
+
+// PodLifecycleEvent reports a change of the state of a container of a pod.
+type PodLifecycleEvent struct {
+	PodFullName string
+	UUID        string
+	Type        PodLifecycleEventType
+	// ContainerID is the runtime ID of the container that changed.
+	ContainerID string
+}
+
+// containerRecord is the state of a container seen by the last relist.
+type containerRecord struct {
+	podFullName string
+	uuid        string
+	running     bool
+}
+
+// podLifecycleEventGenerator relists all the containers of a Runtime periodically, and
+// generates an event for every container whose state changed since the previous relist.
+// This lets the kubelet sync only the pods that actually changed.
+type podLifecycleEventGenerator struct {
+	runtime      Runtime
+	relistPeriod time.Duration
+	events       chan *PodLifecycleEvent
+	// Containers seen by the last relist, keyed by their runtime ID.
+	containers map[string]containerRecord
+}
+
+func newPodLifecycleEventGenerator(runtime Runtime, relistPeriod time.Duration) *podLifecycleEventGenerator {
+	return &podLifecycleEventGenerator{
+		runtime:      runtime,
+		relistPeriod: relistPeriod,
+		events:       make(chan *PodLifecycleEvent, defaultChanSize),
+		containers:   make(map[string]containerRecord),
+	}
+}
+
+// Start relists the containers every relistPeriod in a goroutine. Never returns.
+func (g *podLifecycleEventGenerator) Start() {
+	go util.Forever(g.relist, g.relistPeriod)
+}
+
+// Watch returns the channel the events are delivered on.
+func (g *podLifecycleEventGenerator) Watch() <-chan *PodLifecycleEvent {
+	return g.events
+}
+
+// relist lists the containers of the runtime, and sends an event for each container which
+// started, died or was removed since the previous relist.
+func (g *podLifecycleEventGenerator) relist() {
+	runningPods, err := g.runtime.GetPods(true)
+	if err != nil {
+		glog.Errorf("Unable to relist containers: %v", err)
+		return
+	}
+	containers := make(map[string]containerRecord)
+	for _, pod := range runningPods {
+		for _, container := range pod.Containers {
+			record := containerRecord{pod.FullName, pod.UUID, container.Running}
+			containers[container.ID] = record
+			old, found := g.containers[container.ID]
+			switch {
+			case record.running && (!found || !old.running):
+				g.send(record, ContainerStarted, container.ID)
+			case !record.running && (!found || old.running):
+				g.send(record, ContainerDied, container.ID)
+			}
+		}
+	}
+	for id, old := range g.containers {
+		if _, found := containers[id]; !found {
+			g.send(old, ContainerRemoved, id)
+		}
+	}
+	g.containers = containers
+}
+
+func (g *podLifecycleEventGenerator) send(record containerRecord, eventType PodLifecycleEventType, containerID string) {
+	glog.V(4).Infof("Container %s of pod %s: %s", containerID, record.podFullName, eventType)
+	g.events <- &PodLifecycleEvent{
+		PodFullName: record.podFullName,
+		UUID:        record.uuid,
+		Type:        eventType,
+		ContainerID: containerID,
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func receiveEvents(g *podLifecycleEventGenerator) []PodLifecycleEvent {
+	events := []PodLifecycleEvent{}
+	for {
+		select {
+		case e := <-g.Watch():
+			events = append(events, *e)
+		default:
+			sort.Sort(byContainerID(events))
+			return events
+		}
+	}
+}
+
+type byContainerID []PodLifecycleEvent
+
+func (b byContainerID) Len() int           { return len(b) }
+func (b byContainerID) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byContainerID) Less(i, j int) bool { return b[i].ContainerID < b[j].ContainerID }
+
+func TestRelist(t *testing.T) {
+	fakeRuntime := &FakeRuntime{
+		PodList: RunningPods{
+			{FullName: "foo.test", UUID: "1", Containers: []*RunningContainer{
+				{ID: "c1", Name: "bar", Running: true},
+				{ID: "c2", Name: "baz", Running: false},
+			}},
+		},
+	}
+	g := newPodLifecycleEventGenerator(fakeRuntime, time.Second)
+
+	g.relist()
+	expected := []PodLifecycleEvent{
+		{PodFullName: "foo.test", UUID: "1", Type: ContainerStarted, ContainerID: "c1"},
+		{PodFullName: "foo.test", UUID: "1", Type: ContainerDied, ContainerID: "c2"},
+	}
+	if events := receiveEvents(g); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %#v, got %#v", expected, events)
+	}
+
+	// Nothing changed, so no events.
+	g.relist()
+	if events := receiveEvents(g); len(events) != 0 {
+		t.Errorf("unexpected events: %#v", events)
+	}
+
+	fakeRuntime.PodList = RunningPods{
+		{FullName: "foo.test", UUID: "1", Containers: []*RunningContainer{
+			{ID: "c1", Name: "bar", Running: false},
+		}},
+		{FullName: "new.test", UUID: "2", Containers: []*RunningContainer{
+			{ID: "c3", Name: "bar", Running: true},
+		}},
+	}
+	g.relist()
+	expected = []PodLifecycleEvent{
+		{PodFullName: "foo.test", UUID: "1", Type: ContainerDied, ContainerID: "c1"},
+		{PodFullName: "foo.test", UUID: "1", Type: ContainerRemoved, ContainerID: "c2"},
+		{PodFullName: "new.test", UUID: "2", Type: ContainerStarted, ContainerID: "c3"},
+	}
+	if events := receiveEvents(g); !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %#v, got %#v", expected, events)
+	}
+}
@@ -0,0 +1,229 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/health"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/golang/glog"
+)
+
+// Defaults for the optional fields of api.LivenessProbe.
+const (
+	defaultProbePeriodSeconds    = 10
+	defaultProbeTimeoutSeconds   = 1
+	defaultProbeSuccessThreshold = 1
+	defaultProbeFailureThreshold = 3
+)
+
+type probeType string
+
+const (
+	livenessProbe  probeType = "liveness"
+	readinessProbe probeType = "readiness"
+)
+
+type probeKey struct {
+	containerID string
+	probeType   probeType
+}
+
+// probeWorker periodically runs one probe of a container.
+type probeWorker struct {
+	podFullName string
+	// container has its LivenessProbe set to the probe being run, since that is the probe
+	// the health checkers run.
+	container api.Container
+	created   int64
+	stop      chan struct{}
+
+	// Guarded by prober.lock.
+	currentState api.PodState
+	successes    int
+	failures     int
+}
+
+// prober runs the liveness and readiness probes of containers in their own goroutines, so
+// that slow probes don't hold up the sync of pods, and caches their results.
+type prober struct {
+	checker   health.HealthChecker
+	readiness *readinessStates
+
+	lock    sync.Mutex
+	workers map[probeKey]*probeWorker
+	// The containers which crossed the failure threshold of their liveness probe.
+	unhealthy util.StringSet
+}
+
+func newProber(checker health.HealthChecker, readiness *readinessStates) *prober {
+	return &prober{
+		checker:   checker,
+		readiness: readiness,
+		workers:   make(map[probeKey]*probeWorker),
+		unhealthy: util.NewStringSet(),
+	}
+}
+
+// start starts the probe workers of a running container, unless they are already running.
+// currentState is used by the probes of the container from now on, e.g. for its PodIP.
+func (p *prober) start(podFullName string, currentState api.PodState, container api.Container, runningContainer *RunningContainer) {
+	if container.LivenessProbe != nil {
+		p.startWorker(livenessProbe, container.LivenessProbe, podFullName, currentState, container, runningContainer)
+	}
+	if container.ReadinessProbe != nil {
+		p.startWorker(readinessProbe, container.ReadinessProbe, podFullName, currentState, container, runningContainer)
+	}
+}
+
+func (p *prober) startWorker(probeType probeType, probe *api.LivenessProbe, podFullName string, currentState api.PodState, container api.Container, runningContainer *RunningContainer) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	key := probeKey{runningContainer.ID, probeType}
+	if worker, found := p.workers[key]; found {
+		worker.currentState = currentState
+		return
+	}
+	// The health checkers give up on the probe after its timeout.
+	probeCopy := *probe
+	if probeCopy.TimeoutSeconds == 0 {
+		probeCopy.TimeoutSeconds = defaultProbeTimeoutSeconds
+	}
+	container.LivenessProbe = &probeCopy
+	worker := &probeWorker{
+		podFullName:  podFullName,
+		container:    container,
+		created:      runningContainer.Created,
+		stop:         make(chan struct{}),
+		currentState: currentState,
+	}
+	p.workers[key] = worker
+	if probeType == readinessProbe {
+		// Not ready until the probe succeeds.
+		p.readiness.set(runningContainer.ID, false)
+	}
+	go p.run(key, worker)
+}
+
+// healthy returns the liveness of a container according to the results of its probe so far.
+func (p *prober) healthy(containerID string) health.Status {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.unhealthy.Has(containerID) {
+		return health.Unhealthy
+	}
+	return health.Healthy
+}
+
+// forget stops the workers and forgets the results of a container which died or was removed.
+func (p *prober) forget(containerID string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, probeType := range []probeType{livenessProbe, readinessProbe} {
+		key := probeKey{containerID, probeType}
+		if worker, found := p.workers[key]; found {
+			close(worker.stop)
+			delete(p.workers, key)
+		}
+	}
+	p.unhealthy.Delete(containerID)
+	p.readiness.forget(containerID)
+}
+
+// run probes the container every period once its initial delay is over, until the worker is stopped.
+func (p *prober) run(key probeKey, worker *probeWorker) {
+	defer util.HandleCrash()
+	probe := worker.container.LivenessProbe
+	delay := time.Unix(worker.created+probe.InitialDelaySeconds, 0).Sub(time.Now())
+	if delay > 0 {
+		select {
+		case <-worker.stop:
+			return
+		case <-time.After(delay):
+		}
+	}
+	period := time.Duration(probe.PeriodSeconds) * time.Second
+	if period == 0 {
+		period = defaultProbePeriodSeconds * time.Second
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		p.probe(key, worker)
+		select {
+		case <-worker.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe runs the probe of a worker once, and updates the result of the probe once the
+// success or failure threshold is crossed.
+func (p *prober) probe(key probeKey, worker *probeWorker) {
+	p.lock.Lock()
+	currentState := worker.currentState
+	p.lock.Unlock()
+
+	// A probe which can't be run fails, so a probe which keeps erroring crosses the failure threshold.
+	status, err := p.checker.HealthCheck(worker.podFullName, currentState, worker.container)
+	if err != nil {
+		glog.V(1).Infof("%s probe of pod %s container %s errored: %v", key.probeType, worker.podFullName, worker.container.Name, err)
+		status = health.Unhealthy
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if status == health.Healthy {
+		worker.successes++
+		worker.failures = 0
+	} else {
+		worker.failures++
+		worker.successes = 0
+	}
+	probe := worker.container.LivenessProbe
+	successThreshold := probe.SuccessThreshold
+	if successThreshold == 0 {
+		successThreshold = defaultProbeSuccessThreshold
+	}
+	failureThreshold := probe.FailureThreshold
+	if failureThreshold == 0 {
+		failureThreshold = defaultProbeFailureThreshold
+	}
+	var passing bool
+	switch {
+	case worker.successes >= successThreshold:
+		passing = true
+	case worker.failures >= failureThreshold:
+		passing = false
+	default:
+		return
+	}
+	switch key.probeType {
+	case livenessProbe:
+		if passing {
+			p.unhealthy.Delete(key.containerID)
+		} else {
+			p.unhealthy.Insert(key.containerID)
+		}
+	case readinessProbe:
+		p.readiness.set(key.containerID, passing)
+	}
+}
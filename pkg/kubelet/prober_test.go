@@ -0,0 +1,184 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/health"
+)
+
+type fakeHealthChecker struct {
+	lock   sync.Mutex
+	status health.Status
+	err    error
+	probed *api.LivenessProbe
+}
+
+func (f *fakeHealthChecker) HealthCheck(podFullName string, state api.PodState, container api.Container) (health.Status, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.probed = container.LivenessProbe
+	return f.status, f.err
+}
+
+func (f *fakeHealthChecker) setStatus(status health.Status) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.status = status
+}
+
+func newTestWorker(probe *api.LivenessProbe) *probeWorker {
+	return &probeWorker{
+		podFullName: "foo.test",
+		container:   api.Container{Name: "bar", LivenessProbe: probe},
+		stop:        make(chan struct{}),
+	}
+}
+
+func TestProberThresholds(t *testing.T) {
+	checker := &fakeHealthChecker{status: health.Unhealthy}
+	p := newProber(checker, newReadinessStates())
+	liveness := probeKey{"1234", livenessProbe}
+	worker := newTestWorker(&api.LivenessProbe{Type: "http", FailureThreshold: 2})
+
+	p.probe(liveness, worker)
+	if p.healthy("1234") != health.Healthy {
+		t.Errorf("expected the container to stay healthy below the failure threshold")
+	}
+	p.probe(liveness, worker)
+	if p.healthy("1234") != health.Unhealthy {
+		t.Errorf("expected the container to be unhealthy at the failure threshold")
+	}
+	checker.setStatus(health.Healthy)
+	p.probe(liveness, worker)
+	if p.healthy("1234") != health.Healthy {
+		t.Errorf("expected the container to be healthy after one success")
+	}
+
+	readiness := probeKey{"1234", readinessProbe}
+	worker = newTestWorker(&api.LivenessProbe{Type: "http", SuccessThreshold: 2})
+	p.readiness.set("1234", false)
+	p.probe(readiness, worker)
+	if p.readiness.IsReady("1234") {
+		t.Errorf("expected the container not to be ready below the success threshold")
+	}
+	p.probe(readiness, worker)
+	if !p.readiness.IsReady("1234") {
+		t.Errorf("expected the container to be ready at the success threshold")
+	}
+}
+
+func TestProberCountsErrorsAsFailures(t *testing.T) {
+	checker := &fakeHealthChecker{status: health.Healthy, err: fmt.Errorf("connection refused")}
+	p := newProber(checker, newReadinessStates())
+	liveness := probeKey{"1234", livenessProbe}
+	worker := newTestWorker(&api.LivenessProbe{Type: "http"})
+
+	for i := 0; i < defaultProbeFailureThreshold; i++ {
+		p.probe(liveness, worker)
+	}
+	if p.healthy("1234") != health.Unhealthy {
+		t.Errorf("expected the container to be unhealthy after %d errors", defaultProbeFailureThreshold)
+	}
+}
+
+func TestProberDefaultThresholds(t *testing.T) {
+	checker := &fakeHealthChecker{status: health.Unhealthy}
+	p := newProber(checker, newReadinessStates())
+	readiness := probeKey{"1234", readinessProbe}
+	worker := newTestWorker(&api.LivenessProbe{Type: "http"})
+
+	for i := 0; i < defaultProbeFailureThreshold; i++ {
+		p.readiness.set("1234", true)
+		p.probe(readiness, worker)
+	}
+	if p.readiness.IsReady("1234") {
+		t.Errorf("expected the container not to be ready after %d failures", defaultProbeFailureThreshold)
+	}
+	checker.setStatus(health.Healthy)
+	p.probe(readiness, worker)
+	if !p.readiness.IsReady("1234") {
+		t.Errorf("expected the container to be ready after one success")
+	}
+	if p.healthy("1234") != health.Healthy {
+		t.Errorf("expected the readiness probe not to affect the liveness of the container")
+	}
+}
+
+func TestProberStart(t *testing.T) {
+	readinessProbe := &api.LivenessProbe{Type: "http", InitialDelaySeconds: 100}
+	checker := &fakeHealthChecker{status: health.Healthy}
+	p := newProber(checker, newReadinessStates())
+	container := api.Container{Name: "bar", ReadinessProbe: readinessProbe}
+	runningContainer := &RunningContainer{ID: "1234", Created: time.Now().Unix()}
+
+	p.start("foo.test", api.PodState{}, container, runningContainer)
+	p.start("foo.test", api.PodState{PodIP: "1.2.3.4"}, container, runningContainer)
+	if len(p.workers) != 1 {
+		t.Fatalf("expected one worker, got %#v", p.workers)
+	}
+	worker := p.workers[probeKey{"1234", "readiness"}]
+	if worker == nil || worker.container.LivenessProbe.InitialDelaySeconds != 100 {
+		t.Errorf("expected a worker running the readiness probe, got %#v", worker)
+	}
+	if worker != nil && worker.container.LivenessProbe.TimeoutSeconds != defaultProbeTimeoutSeconds {
+		t.Errorf("expected the probe to default its timeout, got %#v", worker.container.LivenessProbe)
+	}
+	if worker != nil && worker.currentState.PodIP != "1.2.3.4" {
+		t.Errorf("expected the state of the worker to be updated, got %#v", worker.currentState)
+	}
+	if p.readiness.IsReady("1234") {
+		t.Errorf("expected the container not to be ready before its initial delay is over")
+	}
+
+	p.unhealthy.Insert("1234")
+	p.forget("1234")
+	if len(p.workers) != 0 {
+		t.Errorf("expected the workers to be stopped, got %#v", p.workers)
+	}
+	if p.healthy("1234") != health.Healthy || !p.readiness.IsReady("1234") {
+		t.Errorf("expected the results of the probes to be forgotten")
+	}
+	if worker != nil {
+		select {
+		case <-worker.stop:
+		default:
+			t.Errorf("expected the worker to be stopped")
+		}
+	}
+}
+
+func TestHandleContainerEventForgetsDeadContainers(t *testing.T) {
+	kubelet := &Kubelet{readiness: newReadinessStates()}
+	kubelet.prober = newProber(&fakeHealthChecker{status: health.Healthy}, kubelet.readiness)
+	container := api.Container{Name: "bar", ReadinessProbe: &api.LivenessProbe{Type: "http", InitialDelaySeconds: 100}}
+	kubelet.prober.start("foo.test", api.PodState{}, container, &RunningContainer{ID: "1234", Created: time.Now().Unix()})
+
+	kubelet.handleContainerEvent(&PodLifecycleEvent{PodFullName: "foo.test", Type: ContainerStarted, ContainerID: "1234"})
+	if len(kubelet.prober.workers) != 1 || kubelet.readiness.IsReady("1234") {
+		t.Errorf("expected the probe of a started container to keep running")
+	}
+	kubelet.handleContainerEvent(&PodLifecycleEvent{PodFullName: "foo.test", Type: ContainerDied, ContainerID: "1234"})
+	if len(kubelet.prober.workers) != 0 || !kubelet.readiness.IsReady("1234") {
+		t.Errorf("expected the probe of a dead container to be stopped and its result forgotten")
+	}
+}
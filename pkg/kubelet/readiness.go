@@ -0,0 +1,54 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"sync"
+)
+
+// readinessStates records the result of the last readiness probe of each container, keyed by
+// the runtime ID of the container. It is safe for concurrent use.
+type readinessStates struct {
+	lock   sync.Mutex
+	states map[string]bool
+}
+
+func newReadinessStates() *readinessStates {
+	return &readinessStates{states: make(map[string]bool)}
+}
+
+// IsReady returns the result of the last readiness probe of a container. Containers
+// which have never been probed, e.g. because they have no readiness probe, are ready.
+func (r *readinessStates) IsReady(containerID string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	ready, found := r.states[containerID]
+	return ready || !found
+}
+
+func (r *readinessStates) set(containerID string, ready bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.states[containerID] = ready
+}
+
+// forget forgets the state of a container.
+func (r *readinessStates) forget(containerID string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.states, containerID)
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestGetPodInfoReportsReadiness(t *testing.T) {
+	running := api.ContainerState{Running: &api.ContainerStateRunning{}}
+	fakeRuntime := &FakeRuntime{
+		PodInfo: map[string]api.PodInfo{
+			"foo.test": {
+				"ready":    {State: running},
+				"notready": {State: running},
+				"stopped":  {State: api.ContainerState{Termination: &api.ContainerStateTerminated{}}},
+			},
+		},
+	}
+	kubelet := &Kubelet{runtime: fakeRuntime, readiness: newReadinessStates()}
+	info := fakeRuntime.PodInfo["foo.test"]
+	notReady := info["notready"]
+	notReady.DetailInfo.ID = "1234"
+	info["notready"] = notReady
+	kubelet.readiness.set("1234", false)
+
+	podInfo, err := kubelet.GetPodInfo("foo.test", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !podInfo["ready"].Ready || podInfo["notready"].Ready || podInfo["stopped"].Ready {
+		t.Errorf("unexpected readiness: %#v", podInfo)
+	}
+}
+
+func TestReadinessStatesForget(t *testing.T) {
+	states := newReadinessStates()
+	states.set("1234", false)
+	states.set("5678", false)
+	states.forget("5678")
+	if states.IsReady("1234") {
+		t.Errorf("expected 1234 to stay not ready")
+	}
+	if !states.IsReady("5678") {
+		t.Errorf("expected the state of 5678 to be forgotten")
+	}
+}
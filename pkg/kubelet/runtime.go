@@ -0,0 +1,139 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"io"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/health"
+)
+
+// Runtime is the interface the kubelet uses to manage the containers of its pods.
+// It hides the details of the container runtime, e.g. how containers are named.
+type Runtime interface {
+	// GetPods returns the pods which have containers managed by the kubelet.
+	// If all is false, only running containers are returned.
+	GetPods(all bool) (RunningPods, error)
+	// SyncPod brings the containers of runningPod in line with the manifest of pod.
+	// The volumes of the pod must already be set up.
+	SyncPod(pod *Pod, runningPod RunningPod, podVolumes volumeMap) error
+	// KillPod gracefully stops all the containers of a pod. pod is the last known spec of the
+	// pod, used for its PreStop handlers and termination grace period, or nil if it is unknown.
+	KillPod(pod *Pod, runningPod RunningPod) error
+	// GetPodInfo returns the status of the containers of a pod.
+	GetPodInfo(podFullName, uuid string) (api.PodInfo, error)
+	// GetContainerLogs writes the logs of a container of a pod to stdout and stderr.
+	GetContainerLogs(podFullName, containerName string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) error
+	// RunInContainer runs a command in a container and returns its combined output.
+	RunInContainer(containerID string, cmd []string) ([]byte, error)
+	// PullImage makes the image available for the containers that use it. dockercfgs are the
+	// contents of .dockercfg files with credentials to use in addition to the node's.
+	PullImage(image string, dockercfgs [][]byte) error
+	// CleanupPods releases what the runtime still holds for containers which are not running,
+	// e.g. the network of a network container which exited. runningPods are the running pods.
+	CleanupPods(runningPods RunningPods)
+	// ContainerDiskUsage returns the bytes written by each running container to its own
+	// filesystem, by container ID.
+	ContainerDiskUsage() (map[string]int64, error)
+}
+
+// ContainerLogsOptions holds the options for retrieving the logs of a container.
+type ContainerLogsOptions struct {
+	// Tail is the number of lines to return from the end of the log, or "all".
+	// It is ignored when Follow is set.
+	Tail string
+	// Follow streams the log until the container exits or the caller goes away.
+	Follow bool
+	// Previous returns the logs of the last terminated instance of the container.
+	Previous bool
+	// SinceTime, if non-zero, skips log lines written before this time.
+	SinceTime time.Time
+	// Timestamps prefixes every log line with the time it was written.
+	Timestamps bool
+	// LimitBytes, if positive, caps the number of bytes returned.
+	LimitBytes int64
+}
+
+// runtimeHooks are the kubelet callbacks a Runtime calls while syncing a pod.
+type runtimeHooks interface {
+	// runHandler runs a lifecycle handler of a container.
+	runHandler(podFullName, uuid string, container *api.Container, handler *api.Handler) error
+	// startProbes starts probing the liveness and readiness of a running container, unless it
+	// is already being probed.
+	startProbes(podFullName string, currentState api.PodState, container api.Container, runningContainer *RunningContainer)
+	// healthy returns the liveness of a container according to the results of its probe so far.
+	healthy(containerID string) health.Status
+	// pullCredentials returns the contents of the .dockercfg files of the image pull secrets of
+	// a pod. The result is non-nil, even if the pod has no image pull secrets.
+	pullCredentials(pod *Pod) ([][]byte, error)
+	// resolvConf returns the path of the resolv.conf file of the containers of a pod, or "" if
+	// the pod uses the resolver of the node.
+	resolvConf(pod *Pod) (string, error)
+	// environment returns the environment variables of a container of a pod. podIP is the IP
+	// address of the pod, "" if it isn't known.
+	environment(pod *Pod, container *api.Container, podIP string) ([]string, error)
+	// mountSubPath mounts the directory subPath of the volume at volumePath for the mount with
+	// the given index of a container of a pod, and returns the path of the mount.
+	mountSubPath(pod *Pod, container *api.Container, index int, volumePath, subPath string) (string, error)
+}
+
+// RunningContainer is a container as reported by the Runtime.
+type RunningContainer struct {
+	// ID is the runtime specific identifier of the container.
+	ID string
+	// Name is the name of the container in the pod manifest.
+	Name string
+	// Hash of the container spec the container was started from, zero if unknown.
+	Hash uint64
+	// Created is the unix time the container was created at.
+	Created int64
+	// Running is false for containers that have exited.
+	Running bool
+}
+
+// RunningPod is a group of containers reported by the Runtime which belong to the same pod.
+type RunningPod struct {
+	FullName   string
+	UUID       string
+	Containers []*RunningContainer
+}
+
+// FindContainer returns the container with the given name, or nil if the pod has none.
+func (p *RunningPod) FindContainer(containerName string) *RunningContainer {
+	for _, container := range p.Containers {
+		if container.Name == containerName {
+			return container
+		}
+	}
+	return nil
+}
+
+// RunningPods is a list of pods reported by the Runtime.
+type RunningPods []*RunningPod
+
+// FindPod returns the pod with the given full name and uuid. A blank uuid matches any pod with the
+// full name. If no such pod exists, an empty pod is returned.
+func (p RunningPods) FindPod(podFullName, uuid string) RunningPod {
+	for _, pod := range p {
+		if pod.FullName == podFullName && (uuid == "" || pod.UUID == uuid) {
+			return *pod
+		}
+	}
+	return RunningPod{FullName: podFullName, UUID: uuid}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/latest"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+)
+
+// The etcd directory the apiserver stores secrets in.
+const etcdSecretsKey = "/registry/secrets"
+
+// etcdSecrets reads the secrets pods refer to from the etcd registry of the apiserver, like
+// mirrorPods writes to it, since the kubelet has no client of the apiserver.
+type etcdSecrets struct {
+	helper tools.EtcdHelper
+}
+
+func newEtcdSecrets(client tools.EtcdClient) *etcdSecrets {
+	return &etcdSecrets{
+		helper: tools.EtcdHelper{
+			client,
+			latest.Codec,
+			latest.ResourceVersioner,
+		},
+	}
+}
+
+// get reads the secret with the given ID.
+func (s *etcdSecrets) get(id string) (*api.Secret, error) {
+	var secret api.Secret
+	if err := s.helper.ExtractObj(etcdSecretsKey+"/"+id, &secret, false); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/latest"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubelet/dockertools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+	"github.com/coreos/go-etcd/etcd"
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestSyncPodsPullsWithImagePullSecrets(t *testing.T) {
+	kubelet, fakeEtcdClient, fakeDocker := newTestKubelet(t)
+	puller := &dockertools.FakeDockerPuller{}
+	kubelet.runtime = newDockerRuntime(fakeDocker, puller, nil, kubelet)
+	kubelet.secrets = newEtcdSecrets(fakeEtcdClient)
+	fakeDocker.ContainerList = []docker.APIContainers{}
+	dockercfg := []byte(`{"https://registry.example.com": {"auth": "Zm9vOmJhcg=="}}`)
+	fakeEtcdClient.Data[etcdSecretsKey+"/registry-creds"] = tools.EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Value: runtime.EncodeOrDie(latest.Codec, &api.Secret{
+					JSONBase: api.JSONBase{ID: "registry-creds"},
+					Data:     map[string]string{api.SecretDockerConfigKey: base64.StdEncoding.EncodeToString(dockercfg)},
+				}),
+			},
+		},
+	}
+
+	err := kubelet.SyncPods([]Pod{
+		{
+			Name:      "foo",
+			Namespace: "test",
+			Manifest: api.ContainerManifest{
+				ID:               "foo",
+				Containers:       []api.Container{{Name: "bar", Image: "registry.example.com/bar"}},
+				ImagePullSecrets: []string{"registry-creds"},
+			},
+		},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	kubelet.drainWorkers()
+
+	puller.Lock()
+	defer puller.Unlock()
+	if !reflect.DeepEqual(puller.ImagesPulled, []string{networkContainerImage, "registry.example.com/bar"}) {
+		t.Errorf("unexpected images pulled: %v", puller.ImagesPulled)
+	}
+	if !reflect.DeepEqual(puller.DockerConfigs, [][][]byte{nil, {dockercfg}}) {
+		t.Errorf("expected the image to be pulled with the credentials of the secret, got %q", puller.DockerConfigs)
+	}
+}
+
+func TestPullCredentialsMissingSecret(t *testing.T) {
+	kubelet, fakeEtcdClient, _ := newTestKubelet(t)
+	pod := &Pod{Name: "foo", Manifest: api.ContainerManifest{ImagePullSecrets: []string{"missing"}}}
+	if _, err := kubelet.pullCredentials(pod); err == nil {
+		t.Errorf("expected an error without a source of secrets")
+	}
+
+	kubelet.secrets = newEtcdSecrets(fakeEtcdClient)
+	fakeEtcdClient.ExpectNotFoundGet(etcdSecretsKey + "/missing")
+	if _, err := kubelet.pullCredentials(pod); err == nil {
+		t.Errorf("expected an error for a missing secret")
+	}
+
+	dockercfgs, err := kubelet.pullCredentials(&Pod{Name: "foo"})
+	if err != nil || dockercfgs == nil || len(dockercfgs) != 0 {
+		t.Errorf("expected no credentials for a pod without image pull secrets, got %v, %v", dockercfgs, err)
+	}
+}
+
+func TestGetSecret(t *testing.T) {
+	kubelet, fakeEtcdClient, _ := newTestKubelet(t)
+	if _, err := kubelet.GetSecret("creds"); err == nil {
+		t.Errorf("expected an error without a source of secrets")
+	}
+
+	kubelet.secrets = newEtcdSecrets(fakeEtcdClient)
+	fakeEtcdClient.Data[etcdSecretsKey+"/creds"] = tools.EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Value: runtime.EncodeOrDie(latest.Codec, &api.Secret{
+					JSONBase: api.JSONBase{ID: "creds"},
+					Data:     map[string]string{"password": "c2VjcmV0"},
+				}),
+			},
+		},
+	}
+	secret, err := kubelet.GetSecret("creds")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret.ID != "creds" || secret.Data["password"] != "c2VjcmV0" {
+		t.Errorf("unexpected secret: %#v", secret)
+	}
+}
@@ -66,9 +66,11 @@ type HostInterface interface {
 	GetContainerInfo(podFullName, uuid, containerName string, req *info.ContainerInfoRequest) (*info.ContainerInfo, error)
 	GetRootInfo(req *info.ContainerInfoRequest) (*info.ContainerInfo, error)
 	GetMachineInfo() (*info.MachineInfo, error)
+	GetSummary() (*Summary, error)
 	GetPodInfo(name, uuid string) (api.PodInfo, error)
+	GetNodeConditions() []api.NodeCondition
 	RunInContainer(name, uuid, container string, cmd []string) ([]byte, error)
-	GetKubeletContainerLogs(podFullName, containerName, tail string, follow bool, stdout, stderr io.Writer) error
+	GetKubeletContainerLogs(podFullName, containerName string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) error
 	ServeLogs(w http.ResponseWriter, req *http.Request)
 }
 
@@ -89,7 +91,9 @@ func (s *Server) InstallDefaultHandlers() {
 	s.mux.HandleFunc("/container", s.handleContainer)
 	s.mux.HandleFunc("/containers", s.handleContainers)
 	s.mux.HandleFunc("/podInfo", s.handlePodInfo)
+	s.mux.HandleFunc("/nodeConditions", s.handleNodeConditions)
 	s.mux.HandleFunc("/stats/", s.handleStats)
+	s.mux.HandleFunc("/stats/summary", s.handleSummary)
 	s.mux.HandleFunc("/logs/", s.handleLogs)
 	s.mux.HandleFunc("/spec/", s.handleSpec)
 	s.mux.HandleFunc("/run/", s.handleRun)
@@ -118,7 +122,7 @@ func (s *Server) handleContainer(w http.ResponseWriter, req *http.Request) {
 	}
 	//TODO: sha1 of manifest?
 	pod.Name = "1"
-	s.updates <- PodUpdate{[]Pod{pod}, SET}
+	s.updates <- PodUpdate{Pods: []Pod{pod}, Op: SET}
 
 }
 
@@ -141,7 +145,7 @@ func (s *Server) handleContainers(w http.ResponseWriter, req *http.Request) {
 		pods[i].Name = fmt.Sprintf("%d", i+1)
 		pods[i].Manifest = manifests[i]
 	}
-	s.updates <- PodUpdate{pods, SET}
+	s.updates <- PodUpdate{Pods: pods, Op: SET}
 
 }
 
@@ -173,9 +177,11 @@ func (s *Server) handleContainerLogs(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	uriValues := u.Query()
-	follow, _ := strconv.ParseBool(uriValues.Get("follow"))
-	tail := uriValues.Get("tail")
+	logOpts, err := parseContainerLogsOptions(u.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"message": %q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
 
 	podFullName := GetPodFullName(&Pod{Name: podID, Namespace: "etcd"})
 
@@ -183,15 +189,56 @@ func (s *Server) handleContainerLogs(w http.ResponseWriter, req *http.Request) {
 	if flusher, ok := w.(http.Flusher); ok {
 		fw.flusher = flusher
 	}
+	// Mark the logs as plain text, so that proxies pass them through untouched.
+	w.Header().Set("Content-Type", "text/plain")
 	w.Header().Set("Transfer-Encoding", "chunked")
 	w.WriteHeader(http.StatusOK)
-	err = s.host.GetKubeletContainerLogs(podFullName, containerName, tail, follow, &fw, &fw)
+	err = s.host.GetKubeletContainerLogs(podFullName, containerName, logOpts, &fw, &fw)
 	if err != nil {
 		s.error(w, err)
 		return
 	}
 }
 
+// parseContainerLogsOptions builds the log options out of the query of a containerLogs request.
+// The supported parameters are follow, tail, previous, timestamps, limitBytes and either
+// sinceTime (RFC3339) or sinceSeconds (relative to now).
+func parseContainerLogsOptions(query url.Values) (*ContainerLogsOptions, error) {
+	logOpts := &ContainerLogsOptions{
+		Tail: query.Get("tail"),
+	}
+	logOpts.Follow, _ = strconv.ParseBool(query.Get("follow"))
+	logOpts.Previous, _ = strconv.ParseBool(query.Get("previous"))
+	logOpts.Timestamps, _ = strconv.ParseBool(query.Get("timestamps"))
+
+	sinceTime, sinceSeconds := query.Get("sinceTime"), query.Get("sinceSeconds")
+	if len(sinceTime) > 0 && len(sinceSeconds) > 0 {
+		return nil, errors.New("at most one of sinceTime or sinceSeconds may be specified")
+	}
+	if len(sinceTime) > 0 {
+		t, err := time.Parse(time.RFC3339, sinceTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sinceTime %q: %v", sinceTime, err)
+		}
+		logOpts.SinceTime = t
+	}
+	if len(sinceSeconds) > 0 {
+		seconds, err := strconv.ParseInt(sinceSeconds, 10, 64)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid sinceSeconds %q: must be a positive integer", sinceSeconds)
+		}
+		logOpts.SinceTime = time.Now().Add(-time.Duration(seconds) * time.Second)
+	}
+	if limitBytes := query.Get("limitBytes"); len(limitBytes) > 0 {
+		limit, err := strconv.ParseInt(limitBytes, 10, 64)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid limitBytes %q: must be a positive integer", limitBytes)
+		}
+		logOpts.LimitBytes = limit
+	}
+	return logOpts, nil
+}
+
 // handlePodInfo handles podInfo requests against the Kubelet
 func (s *Server) handlePodInfo(w http.ResponseWriter, req *http.Request) {
 	u, err := url.ParseRequestURI(req.RequestURI)
@@ -227,11 +274,44 @@ func (s *Server) handlePodInfo(w http.ResponseWriter, req *http.Request) {
 	w.Write(data)
 }
 
+// handleNodeConditions handles nodeConditions requests against the Kubelet.
+func (s *Server) handleNodeConditions(w http.ResponseWriter, req *http.Request) {
+	conditions := s.host.GetNodeConditions()
+	if conditions == nil {
+		conditions = []api.NodeCondition{}
+	}
+	data, err := json.Marshal(conditions)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	w.Header().Add("Content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
 // handleStats handles stats requests against the Kubelet.
 func (s *Server) handleStats(w http.ResponseWriter, req *http.Request) {
 	s.serveStats(w, req)
 }
 
+// handleSummary handles requests for the resource usage of the node and of its pods.
+func (s *Server) handleSummary(w http.ResponseWriter, req *http.Request) {
+	summary, err := s.host.GetSummary()
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	data, err := json.Marshal(summary)
+	if err != nil {
+		s.error(w, err)
+		return
+	}
+	w.Header().Add("Content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
 // handleLogs handles logs requests against the Kubelet.
 func (s *Server) handleLogs(w http.ResponseWriter, req *http.Request) {
 	s.host.ServeLogs(w, req)
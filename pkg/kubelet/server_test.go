@@ -25,9 +25,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
+	"net/url"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
@@ -37,18 +39,24 @@ import (
 
 type fakeKubelet struct {
 	infoFunc          func(name string) (api.PodInfo, error)
+	conditionsFunc    func() []api.NodeCondition
 	containerInfoFunc func(podFullName, containerName string, req *info.ContainerInfoRequest) (*info.ContainerInfo, error)
 	rootInfoFunc      func(query *info.ContainerInfoRequest) (*info.ContainerInfo, error)
 	machineInfoFunc   func() (*info.MachineInfo, error)
+	summaryFunc       func() (*Summary, error)
 	logFunc           func(w http.ResponseWriter, req *http.Request)
 	runFunc           func(podFullName, uuid, containerName string, cmd []string) ([]byte, error)
-	containerLogsFunc func(podFullName, containerName, tail string, follow bool, stdout, stderr io.Writer) error
+	containerLogsFunc func(podFullName, containerName string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) error
 }
 
 func (fk *fakeKubelet) GetPodInfo(name, uuid string) (api.PodInfo, error) {
 	return fk.infoFunc(name)
 }
 
+func (fk *fakeKubelet) GetNodeConditions() []api.NodeCondition {
+	return fk.conditionsFunc()
+}
+
 func (fk *fakeKubelet) GetContainerInfo(podFullName, uuid, containerName string, req *info.ContainerInfoRequest) (*info.ContainerInfo, error) {
 	return fk.containerInfoFunc(podFullName, containerName, req)
 }
@@ -61,12 +69,16 @@ func (fk *fakeKubelet) GetMachineInfo() (*info.MachineInfo, error) {
 	return fk.machineInfoFunc()
 }
 
+func (fk *fakeKubelet) GetSummary() (*Summary, error) {
+	return fk.summaryFunc()
+}
+
 func (fk *fakeKubelet) ServeLogs(w http.ResponseWriter, req *http.Request) {
 	fk.logFunc(w, req)
 }
 
-func (fk *fakeKubelet) GetKubeletContainerLogs(podFullName, containerName, tail string, follow bool, stdout, stderr io.Writer) error {
-	return fk.containerLogsFunc(podFullName, containerName, tail, follow, stdout, stderr)
+func (fk *fakeKubelet) GetKubeletContainerLogs(podFullName, containerName string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) error {
+	return fk.containerLogsFunc(podFullName, containerName, logOpts, stdout, stderr)
 }
 
 func (fk *fakeKubelet) RunInContainer(podFullName, uuid, containerName string, cmd []string) ([]byte, error) {
@@ -174,6 +186,25 @@ func TestPodInfo(t *testing.T) {
 	}
 }
 
+func TestNodeConditions(t *testing.T) {
+	fw := newServerTest()
+	expected := []api.NodeCondition{{Kind: api.NodeMemoryPressure, Reason: "low on memory"}}
+	fw.fakeKubelet.conditionsFunc = func() []api.NodeCondition {
+		return expected
+	}
+	resp, err := http.Get(fw.testHTTPServer.URL + "/nodeConditions")
+	if err != nil {
+		t.Fatalf("Got error GETing: %v", err)
+	}
+	var got []api.NodeCondition
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("Error decoding body: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected: %#v, got: %#v", expected, got)
+	}
+}
+
 func TestContainerInfo(t *testing.T) {
 	fw := newServerTest()
 	expectedInfo := &info.ContainerInfo{}
@@ -248,6 +279,36 @@ func TestMachineInfo(t *testing.T) {
 	}
 }
 
+func TestSummary(t *testing.T) {
+	fw := newServerTest()
+	expected := &Summary{
+		Time: time.Unix(1000, 0).UTC(),
+		Node: ResourceUsage{CPUUsageNanoseconds: 100, Network: &NetworkUsage{RxBytes: 10}},
+		Pods: []PodUsage{{
+			Name:       "foo.etcd",
+			Usage:      ResourceUsage{MemoryUsageBytes: 2048, FilesystemUsedBytes: 30},
+			Containers: []ContainerUsage{{Name: "bar", Usage: ResourceUsage{MemoryUsageBytes: 2048, FilesystemUsedBytes: 10}}},
+			Volumes:    []VolumeUsage{{Name: "data", FilesystemUsedBytes: 20}},
+		}},
+	}
+	fw.fakeKubelet.summaryFunc = func() (*Summary, error) {
+		return expected, nil
+	}
+
+	resp, err := http.Get(fw.testHTTPServer.URL + "/stats/summary")
+	if err != nil {
+		t.Fatalf("Got error GETing: %v", err)
+	}
+	defer resp.Body.Close()
+	var received Summary
+	if err := json.NewDecoder(resp.Body).Decode(&received); err != nil {
+		t.Fatalf("received invalid json data: %v", err)
+	}
+	if !reflect.DeepEqual(&received, expected) {
+		t.Errorf("expected %#v, got %#v", expected, received)
+	}
+}
+
 func TestServeLogs(t *testing.T) {
 	fw := newServerTest()
 
@@ -366,18 +427,18 @@ func TestContainerLogs(t *testing.T) {
 	expectedContainerName := "baz"
 	expectedTail := ""
 	expectedFollow := false
-	fw.fakeKubelet.containerLogsFunc = func(podFullName, containerName, tail string, follow bool, stdout, stderr io.Writer) error {
+	fw.fakeKubelet.containerLogsFunc = func(podFullName, containerName string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) error {
 		if podFullName != expectedPodName {
 			t.Errorf("expected %s, got %s", expectedPodName, podFullName)
 		}
 		if containerName != expectedContainerName {
 			t.Errorf("expected %s, got %s", expectedContainerName, containerName)
 		}
-		if tail != expectedTail {
-			t.Errorf("expected %s, got %s", expectedTail, tail)
+		if logOpts.Tail != expectedTail {
+			t.Errorf("expected %s, got %s", expectedTail, logOpts.Tail)
 		}
-		if follow != expectedFollow {
-			t.Errorf("expected %t, got %t", expectedFollow, follow)
+		if logOpts.Follow != expectedFollow {
+			t.Errorf("expected %t, got %t", expectedFollow, logOpts.Follow)
 		}
 		return nil
 	}
@@ -405,18 +466,18 @@ func TestContainerLogsWithTail(t *testing.T) {
 	expectedContainerName := "baz"
 	expectedTail := "5"
 	expectedFollow := false
-	fw.fakeKubelet.containerLogsFunc = func(podFullName, containerName, tail string, follow bool, stdout, stderr io.Writer) error {
+	fw.fakeKubelet.containerLogsFunc = func(podFullName, containerName string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) error {
 		if podFullName != expectedPodName {
 			t.Errorf("expected %s, got %s", expectedPodName, podFullName)
 		}
 		if containerName != expectedContainerName {
 			t.Errorf("expected %s, got %s", expectedContainerName, containerName)
 		}
-		if tail != expectedTail {
-			t.Errorf("expected %s, got %s", expectedTail, tail)
+		if logOpts.Tail != expectedTail {
+			t.Errorf("expected %s, got %s", expectedTail, logOpts.Tail)
 		}
-		if follow != expectedFollow {
-			t.Errorf("expected %t, got %t", expectedFollow, follow)
+		if logOpts.Follow != expectedFollow {
+			t.Errorf("expected %t, got %t", expectedFollow, logOpts.Follow)
 		}
 		return nil
 	}
@@ -444,18 +505,18 @@ func TestContainerLogsWithFollow(t *testing.T) {
 	expectedContainerName := "baz"
 	expectedTail := ""
 	expectedFollow := true
-	fw.fakeKubelet.containerLogsFunc = func(podFullName, containerName, tail string, follow bool, stdout, stderr io.Writer) error {
+	fw.fakeKubelet.containerLogsFunc = func(podFullName, containerName string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) error {
 		if podFullName != expectedPodName {
 			t.Errorf("expected %s, got %s", expectedPodName, podFullName)
 		}
 		if containerName != expectedContainerName {
 			t.Errorf("expected %s, got %s", expectedContainerName, containerName)
 		}
-		if tail != expectedTail {
-			t.Errorf("expected %s, got %s", expectedTail, tail)
+		if logOpts.Tail != expectedTail {
+			t.Errorf("expected %s, got %s", expectedTail, logOpts.Tail)
 		}
-		if follow != expectedFollow {
-			t.Errorf("expected %t, got %t", expectedFollow, follow)
+		if logOpts.Follow != expectedFollow {
+			t.Errorf("expected %t, got %t", expectedFollow, logOpts.Follow)
 		}
 		return nil
 	}
@@ -474,3 +535,72 @@ func TestContainerLogsWithFollow(t *testing.T) {
 		t.Errorf("Expected: '%v', got: '%v'", output, result)
 	}
 }
+
+func TestContainerLogsWithOptions(t *testing.T) {
+	fw := newServerTest()
+	podName := "foo"
+	expectedContainerName := "baz"
+	expectedSinceTime := time.Date(2014, time.November, 5, 10, 0, 0, 0, time.UTC)
+	fw.fakeKubelet.containerLogsFunc = func(podFullName, containerName string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) error {
+		if !logOpts.Previous {
+			t.Errorf("expected previous to be set")
+		}
+		if !logOpts.Timestamps {
+			t.Errorf("expected timestamps to be set")
+		}
+		if !logOpts.SinceTime.Equal(expectedSinceTime) {
+			t.Errorf("expected %v, got %v", expectedSinceTime, logOpts.SinceTime)
+		}
+		if logOpts.LimitBytes != 100 {
+			t.Errorf("expected 100, got %d", logOpts.LimitBytes)
+		}
+		return nil
+	}
+	resp, err := http.Get(fw.testHTTPServer.URL + "/containerLogs/" + podName + "/" + expectedContainerName +
+		"?previous=true&timestamps=true&sinceTime=2014-11-05T10:00:00Z&limitBytes=100")
+	if err != nil {
+		t.Fatalf("Got error GETing: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/plain" {
+		t.Errorf("unexpected content type: %s", contentType)
+	}
+}
+
+func TestContainerLogsInvalidOptions(t *testing.T) {
+	fw := newServerTest()
+	fw.fakeKubelet.containerLogsFunc = func(podFullName, containerName string, logOpts *ContainerLogsOptions, stdout, stderr io.Writer) error {
+		t.Errorf("unexpected call with %#v", logOpts)
+		return nil
+	}
+	for _, query := range []string{
+		"sinceTime=yesterday",
+		"sinceSeconds=-5",
+		"sinceSeconds=10&sinceTime=2014-11-05T10:00:00Z",
+		"limitBytes=0",
+	} {
+		resp, err := http.Get(fw.testHTTPServer.URL + "/containerLogs/foo/baz?" + query)
+		if err != nil {
+			t.Fatalf("Got error GETing: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected bad request for %s, got %d", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestParseContainerLogsSinceSeconds(t *testing.T) {
+	before := time.Now()
+	logOpts, err := parseContainerLogsOptions(url.Values{"sinceSeconds": []string{"60"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	after := time.Now()
+	if logOpts.SinceTime.Before(before.Add(-time.Minute)) || logOpts.SinceTime.After(after.Add(-time.Minute)) {
+		t.Errorf("unexpected since time %v", logOpts.SinceTime)
+	}
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/google/cadvisor/info"
+)
+
+// Summary is the latest resource usage of a node and of the pods running on it.
+type Summary struct {
+	// The time the usage was collected at.
+	Time time.Time     `json:"time"`
+	Node ResourceUsage `json:"node"`
+	Pods []PodUsage    `json:"pods"`
+}
+
+// ResourceUsage is the resource usage of a node, a pod or a container.
+type ResourceUsage struct {
+	// Cumulative CPU time, in nanoseconds.
+	CPUUsageNanoseconds uint64 `json:"cpuUsageNanoseconds"`
+	// The memory in use, in bytes, and the part of it which was recently accessed.
+	MemoryUsageBytes      uint64 `json:"memoryUsageBytes"`
+	MemoryWorkingSetBytes uint64 `json:"memoryWorkingSetBytes"`
+	// Omitted for containers, which share the network of their pod.
+	Network *NetworkUsage `json:"network,omitempty"`
+	// The disk space used, in bytes: by the writable layer of a container, by the containers
+	// and volumes of a pod, or on the filesystem of the root directory of the kubelet for the node.
+	FilesystemUsedBytes uint64 `json:"filesystemUsedBytes"`
+}
+
+// NetworkUsage is the cumulative network traffic of a node or a pod.
+type NetworkUsage struct {
+	RxBytes  uint64 `json:"rxBytes"`
+	RxErrors uint64 `json:"rxErrors"`
+	TxBytes  uint64 `json:"txBytes"`
+	TxErrors uint64 `json:"txErrors"`
+}
+
+// PodUsage is the resource usage of a running pod. The CPU and memory usage of the pod is the
+// sum of the usage of its containers.
+type PodUsage struct {
+	// The full name of the pod.
+	Name       string           `json:"name"`
+	UUID       string           `json:"uuid,omitempty"`
+	Usage      ResourceUsage    `json:"usage"`
+	Containers []ContainerUsage `json:"containers"`
+	Volumes    []VolumeUsage    `json:"volumes,omitempty"`
+}
+
+// ContainerUsage is the resource usage of a running container of a pod.
+type ContainerUsage struct {
+	Name  string        `json:"name"`
+	Usage ResourceUsage `json:"usage"`
+}
+
+// VolumeUsage is the disk space used by a volume of a pod which is stored under the root
+// directory of the kubelet.
+type VolumeUsage struct {
+	Name                string `json:"name"`
+	FilesystemUsedBytes uint64 `json:"filesystemUsedBytes"`
+}
+
+// diskUsagePeriod is how often the disk usage of the containers and volumes is computed, since
+// it means walking their files.
+const diskUsagePeriod = time.Minute
+
+// diskUsageCache is the disk usage of the containers and of the volumes of the pods, as of the
+// last updateDiskUsage.
+type diskUsageCache struct {
+	lock sync.RWMutex
+	// By container ID.
+	containers map[string]int64
+	volumes    map[podKey][]VolumeUsage
+}
+
+// updateDiskUsage computes the disk usage of the running containers and of the volumes of the
+// known pods, which GetSummary then reports.
+func (kl *Kubelet) updateDiskUsage() {
+	containers, err := kl.runtime.ContainerDiskUsage()
+	if err != nil {
+		glog.Errorf("Unable to get the disk usage of the containers: %v", err)
+	}
+	volumes := make(map[podKey][]VolumeUsage)
+	for key, spec := range kl.getPodSpecs() {
+		if spec != nil {
+			volumes[key] = kl.volumeUsage(spec)
+		}
+	}
+	kl.cachedDiskUsage.lock.Lock()
+	defer kl.cachedDiskUsage.lock.Unlock()
+	kl.cachedDiskUsage.containers = containers
+	kl.cachedDiskUsage.volumes = volumes
+}
+
+// GetSummary returns the resource usage of the node and of the running pods. The CPU, memory and
+// network usage is only known with cAdvisor. The disk usage of the containers and volumes is the
+// one of the last updateDiskUsage.
+func (kl *Kubelet) GetSummary() (*Summary, error) {
+	runningPods, err := kl.runtime.GetPods(false)
+	if err != nil {
+		return nil, err
+	}
+	kl.cachedDiskUsage.lock.RLock()
+	defer kl.cachedDiskUsage.lock.RUnlock()
+	summary := &Summary{Time: time.Now(), Pods: []PodUsage{}}
+	kl.fillUsage(&summary.Node, "/")
+	if used, err := statfsUsedBytes(kl.rootDirectory); err == nil {
+		summary.Node.FilesystemUsedBytes = used
+	} else {
+		glog.Errorf("Unable to get the disk usage of %s: %v", kl.rootDirectory, err)
+	}
+
+	for _, runningPod := range runningPods {
+		pod := PodUsage{Name: runningPod.FullName, UUID: runningPod.UUID, Containers: []ContainerUsage{}}
+		for _, container := range runningPod.Containers {
+			containerPath := fmt.Sprintf("/docker/%s", container.ID)
+			if container.Name == networkContainerName {
+				// The containers of the pod share the network of the network container.
+				var netUsage ResourceUsage
+				kl.fillUsage(&netUsage, containerPath)
+				pod.Usage.Network = netUsage.Network
+				continue
+			}
+			usage := ContainerUsage{Name: container.Name}
+			kl.fillUsage(&usage.Usage, containerPath)
+			usage.Usage.Network = nil
+			if used := kl.cachedDiskUsage.containers[container.ID]; used > 0 {
+				usage.Usage.FilesystemUsedBytes = uint64(used)
+			}
+			pod.Usage.CPUUsageNanoseconds += usage.Usage.CPUUsageNanoseconds
+			pod.Usage.MemoryUsageBytes += usage.Usage.MemoryUsageBytes
+			pod.Usage.MemoryWorkingSetBytes += usage.Usage.MemoryWorkingSetBytes
+			pod.Usage.FilesystemUsedBytes += usage.Usage.FilesystemUsedBytes
+			pod.Containers = append(pod.Containers, usage)
+		}
+		pod.Volumes = kl.cachedDiskUsage.volumes[podKey{runningPod.FullName, runningPod.UUID}]
+		for _, volume := range pod.Volumes {
+			pod.Usage.FilesystemUsedBytes += volume.FilesystemUsedBytes
+		}
+		summary.Pods = append(summary.Pods, pod)
+	}
+	return summary, nil
+}
+
+// fillUsage sets the CPU, memory and network usage from the latest stats of a cgroup.
+func (kl *Kubelet) fillUsage(usage *ResourceUsage, containerPath string) {
+	if kl.cadvisorClient == nil {
+		return
+	}
+	cinfo, err := kl.statsFromContainerPath(containerPath, &info.ContainerInfoRequest{NumStats: 1})
+	if err != nil {
+		glog.V(1).Infof("Unable to get the stats of %s: %v", containerPath, err)
+		return
+	}
+	if cinfo == nil || len(cinfo.Stats) == 0 {
+		return
+	}
+	stats := cinfo.Stats[len(cinfo.Stats)-1]
+	if stats.Cpu != nil {
+		usage.CPUUsageNanoseconds = stats.Cpu.Usage.Total
+	}
+	if stats.Memory != nil {
+		usage.MemoryUsageBytes = stats.Memory.Usage
+		usage.MemoryWorkingSetBytes = stats.Memory.WorkingSet
+	}
+	if stats.Network != nil {
+		usage.Network = &NetworkUsage{
+			RxBytes:  stats.Network.RxBytes,
+			RxErrors: stats.Network.RxErrors,
+			TxBytes:  stats.Network.TxBytes,
+			TxErrors: stats.Network.TxErrors,
+		}
+	}
+}
+
+// volumeUsage returns the disk space used by the volumes of a pod under the root directory.
+// Volumes stored elsewhere, e.g. host directories, are left out.
+func (kl *Kubelet) volumeUsage(pod *Pod) []VolumeUsage {
+	var volumes []VolumeUsage
+	for _, volume := range pod.Manifest.Volumes {
+		// The directories of the volumes are (ROOT_DIR)/(POD_ID)/volumes/(VOLUME_KIND)/(VOLUME_NAME)
+		dirs, err := filepath.Glob(path.Join(kl.rootDirectory, pod.Manifest.ID, "volumes", "*", volume.Name))
+		if err != nil || len(dirs) == 0 {
+			continue
+		}
+		var used uint64
+		for _, dir := range dirs {
+			used += diskUsage(dir)
+		}
+		volumes = append(volumes, VolumeUsage{Name: volume.Name, FilesystemUsedBytes: used})
+	}
+	return volumes
+}
+
+// diskUsage returns the size of the files under dir, in bytes.
+func diskUsage(dir string) uint64 {
+	var used uint64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Files may be removed while walking.
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			used += uint64(info.Size())
+		}
+		return nil
+	})
+	return used
+}
+
+// statfsUsedBytes returns the bytes used on the filesystem of path.
+func statfsUsedBytes(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return (stat.Blocks - stat.Bfree) * uint64(stat.Bsize), nil
+}
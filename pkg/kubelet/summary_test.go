@@ -0,0 +1,160 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubelet
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/google/cadvisor/info"
+)
+
+func newContainerStats(cpu, memory, rxBytes uint64) *info.ContainerInfo {
+	stats := &info.ContainerStats{
+		Cpu:     &info.CpuStats{},
+		Memory:  &info.MemoryStats{Usage: memory, WorkingSet: memory / 2},
+		Network: &info.NetworkStats{RxBytes: rxBytes, TxBytes: rxBytes * 2},
+	}
+	stats.Cpu.Usage.Total = cpu
+	return &info.ContainerInfo{Stats: []*info.ContainerStats{stats}}
+}
+
+func TestGetSummary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "summary")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	volumeDir := path.Join(dir, "foo", "volumes", "empty", "data")
+	if err := os.MkdirAll(path.Join(volumeDir, "sub"), 0750); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ioutil.WriteFile(path.Join(volumeDir, "a"), make([]byte, 100), 0600)
+	ioutil.WriteFile(path.Join(volumeDir, "sub", "b"), make([]byte, 50), 0600)
+
+	fakeRuntime := &FakeRuntime{
+		PodList: RunningPods{{
+			FullName: "foo.test",
+			Containers: []*RunningContainer{
+				{ID: "net-id", Name: networkContainerName},
+				{ID: "bar-id", Name: "bar"},
+				{ID: "baz-id", Name: "baz"},
+			},
+		}},
+		DiskUsage: map[string]int64{"bar-id": 1000, "baz-id": 2000},
+	}
+	mockCadvisor := &mockCadvisorClient{}
+	req := &info.ContainerInfoRequest{NumStats: 1}
+	mockCadvisor.On("ContainerInfo", "/", req).Return(newContainerStats(10000, 1<<30, 500), nil)
+	mockCadvisor.On("ContainerInfo", "/docker/net-id", req).Return(newContainerStats(1, 10, 300), nil)
+	mockCadvisor.On("ContainerInfo", "/docker/bar-id", req).Return(newContainerStats(100, 2048, 0), nil)
+	mockCadvisor.On("ContainerInfo", "/docker/baz-id", req).Return(newContainerStats(200, 4096, 0), nil)
+	kubelet := &Kubelet{
+		runtime:        fakeRuntime,
+		cadvisorClient: mockCadvisor,
+		rootDirectory:  dir,
+		podSpecs: map[podKey]*Pod{
+			{"foo.test", ""}: {
+				Name:      "foo",
+				Namespace: "test",
+				Manifest: api.ContainerManifest{
+					ID: "foo",
+					Volumes: []api.Volume{
+						{Name: "data", Source: &api.VolumeSource{EmptyDirectory: &api.EmptyDirectory{}}},
+						{Name: "host", Source: &api.VolumeSource{HostDirectory: &api.HostDirectory{Path: "/var/log"}}},
+					},
+				},
+			},
+		},
+	}
+
+	kubelet.updateDiskUsage()
+	summary, err := kubelet.GetSummary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mockCadvisor.AssertExpectations(t)
+	if summary.Node.CPUUsageNanoseconds != 10000 || summary.Node.MemoryUsageBytes != 1<<30 ||
+		summary.Node.Network == nil || summary.Node.Network.RxBytes != 500 || summary.Node.FilesystemUsedBytes == 0 {
+		t.Errorf("unexpected node usage: %#v", summary.Node)
+	}
+	expected := []PodUsage{{
+		Name: "foo.test",
+		Usage: ResourceUsage{
+			CPUUsageNanoseconds:   300,
+			MemoryUsageBytes:      6144,
+			MemoryWorkingSetBytes: 3072,
+			Network:               &NetworkUsage{RxBytes: 300, TxBytes: 600},
+			FilesystemUsedBytes:   3150,
+		},
+		Containers: []ContainerUsage{
+			{Name: "bar", Usage: ResourceUsage{CPUUsageNanoseconds: 100, MemoryUsageBytes: 2048, MemoryWorkingSetBytes: 1024, FilesystemUsedBytes: 1000}},
+			{Name: "baz", Usage: ResourceUsage{CPUUsageNanoseconds: 200, MemoryUsageBytes: 4096, MemoryWorkingSetBytes: 2048, FilesystemUsedBytes: 2000}},
+		},
+		Volumes: []VolumeUsage{{Name: "data", FilesystemUsedBytes: 150}},
+	}}
+	if !reflect.DeepEqual(summary.Pods, expected) {
+		t.Errorf("expected %#v, got %#v", expected, summary.Pods)
+	}
+}
+
+func TestGetSummaryWithoutCadvisor(t *testing.T) {
+	fakeRuntime := &FakeRuntime{
+		PodList:   RunningPods{{FullName: "foo.test", Containers: []*RunningContainer{{ID: "bar-id", Name: "bar"}}}},
+		DiskUsage: map[string]int64{"bar-id": 1000},
+	}
+	kubelet := &Kubelet{runtime: fakeRuntime, rootDirectory: os.TempDir()}
+	kubelet.updateDiskUsage()
+	summary, err := kubelet.GetSummary()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []PodUsage{{
+		Name:       "foo.test",
+		Usage:      ResourceUsage{FilesystemUsedBytes: 1000},
+		Containers: []ContainerUsage{{Name: "bar", Usage: ResourceUsage{FilesystemUsedBytes: 1000}}},
+	}}
+	if !reflect.DeepEqual(summary.Pods, expected) {
+		t.Errorf("expected %#v, got %#v", expected, summary.Pods)
+	}
+}
+
+func TestGetSummaryServesCachedDiskUsage(t *testing.T) {
+	fakeRuntime := &FakeRuntime{
+		PodList:   RunningPods{{FullName: "foo.test", Containers: []*RunningContainer{{ID: "bar-id", Name: "bar"}}}},
+		DiskUsage: map[string]int64{"bar-id": 1000},
+	}
+	kubelet := &Kubelet{runtime: fakeRuntime, rootDirectory: os.TempDir()}
+	kubelet.updateDiskUsage()
+	fakeRuntime.DiskUsage = map[string]int64{"bar-id": 2000}
+	for i := 0; i < 2; i++ {
+		summary, err := kubelet.GetSummary()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if used := summary.Pods[0].Containers[0].Usage.FilesystemUsedBytes; used != 1000 {
+			t.Errorf("expected the cached disk usage, got %d", used)
+		}
+	}
+	if err := fakeRuntime.AssertCalls([]string{"ContainerDiskUsage", "GetPods", "GetPods"}); err != nil {
+		t.Errorf("%v", err)
+	}
+}
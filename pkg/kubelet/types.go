@@ -20,6 +20,17 @@ import (
 	"fmt"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+)
+
+// The names of the config sources of the kubelet, which are also the namespaces of their pods.
+const (
+	// FileSource is the source of the pods read from the config file or directory.
+	FileSource = "file"
+	// HTTPSource is the source of the pods read from the manifest URL or posted to the kubelet server.
+	HTTPSource = "http"
+	// EtcdSource is the source of the pods scheduled on the kubelet through the apiserver.
+	EtcdSource = "etcd"
 )
 
 // Pod represents the structure of a pod on the Kubelet, distinct from the apiserver
@@ -52,9 +63,18 @@ const (
 type PodUpdate struct {
 	Pods []Pod
 	Op   PodOperation
+	// PendingSources are the config sources which haven't delivered their pods yet, as of the
+	// merged configuration in Pods. Only set in SET updates, nil if every source delivered.
+	PendingSources util.StringSet
 }
 
 // GetPodFullName returns a name that full identifies a pod across all config sources.
 func GetPodFullName(pod *Pod) string {
 	return fmt.Sprintf("%s.%s", pod.Name, pod.Namespace)
 }
+
+// IsStaticPod returns true if a pod comes from the file or HTTP source of the kubelet rather
+// than from the apiserver.
+func IsStaticPod(pod *Pod) bool {
+	return pod.Namespace == FileSource || pod.Namespace == HTTPSource
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry/etcd"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry/minion"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry/pod"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry/secret"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry/service"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
 	servicecontroller "github.com/GoogleCloudPlatform/kubernetes/pkg/service"
@@ -52,6 +53,8 @@ type Config struct {
 	MinionCacheTTL     time.Duration
 	MinionRegexp       string
 	PodInfoGetter      client.PodInfoGetter
+	// Optional, minions are reported without conditions if omitted.
+	NodeConditionGetter client.NodeConditionGetter
 }
 
 // Master contains state for a Kubernetes cluster master/api server.
@@ -62,6 +65,7 @@ type Master struct {
 	endpointRegistry   endpoint.Registry
 	minionRegistry     minion.Registry
 	bindingRegistry    binding.Registry
+	secretRegistry     secret.Registry
 	storage            map[string]apiserver.RESTStorage
 	client             *client.Client
 }
@@ -94,9 +98,10 @@ func New(c *Config) *Master {
 		endpointRegistry:   etcd.NewRegistry(c.EtcdHelper, nil),
 		bindingRegistry:    etcd.NewRegistry(c.EtcdHelper, manifestFactory),
 		minionRegistry:     minionRegistry,
+		secretRegistry:     etcd.NewRegistry(c.EtcdHelper, nil),
 		client:             c.Client,
 	}
-	m.init(c.Cloud, c.PodInfoGetter)
+	m.init(c.Cloud, c.PodInfoGetter, c.NodeConditionGetter)
 	return m
 }
 
@@ -126,10 +131,17 @@ func makeMinionRegistry(c *Config) minion.Registry {
 	return minionRegistry
 }
 
-func (m *Master) init(cloud cloudprovider.Interface, podInfoGetter client.PodInfoGetter) {
+func (m *Master) init(cloud cloudprovider.Interface, podInfoGetter client.PodInfoGetter, nodeConditionGetter client.NodeConditionGetter) {
 	podCache := NewPodCache(podInfoGetter, m.podRegistry)
 	go util.Forever(func() { podCache.UpdateAllContainers() }, time.Second*30)
 
+	var nodeConditionCache client.NodeConditionGetter
+	if nodeConditionGetter != nil {
+		cache := NewNodeConditionCache(nodeConditionGetter, m.minionRegistry)
+		go util.Forever(func() { cache.UpdateAllConditions() }, time.Second*10)
+		nodeConditionCache = cache
+	}
+
 	endpoints := servicecontroller.NewEndpointController(m.serviceRegistry, m.client)
 	go util.Forever(func() { endpoints.SyncServiceEndpoints() }, time.Second*10)
 
@@ -144,7 +156,8 @@ func (m *Master) init(cloud cloudprovider.Interface, podInfoGetter client.PodInf
 		"replicationControllers": controller.NewREST(m.controllerRegistry, m.podRegistry),
 		"services":               service.NewREST(m.serviceRegistry, cloud, m.minionRegistry),
 		"endpoints":              endpoint.NewREST(m.endpointRegistry),
-		"minions":                minion.NewREST(m.minionRegistry),
+		"minions":                minion.NewREST(m.minionRegistry, nodeConditionCache),
+		"secrets":                secret.NewREST(m.secretRegistry),
 
 		// TODO: should appear only in scheduler API group.
 		"bindings": binding.NewREST(m.bindingRegistry),
@@ -0,0 +1,82 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry/minion"
+
+	"github.com/golang/glog"
+)
+
+// NodeConditionCache contains a cache of the conditions of the minions, as well as the
+// mechanism for keeping that cache up to date, so that reading minions doesn't wait for
+// their kubelets.
+type NodeConditionCache struct {
+	nodeConditions client.NodeConditionGetter
+	minions        minion.Registry
+	// This is a map of minion ID to the conditions which held on it when it was last asked.
+	conditions map[string][]api.NodeCondition
+	lock       sync.Mutex
+}
+
+// NewNodeConditionCache returns a new NodeConditionCache which gets the conditions of the
+// minions registered in the given minion registry.
+func NewNodeConditionCache(nodeConditions client.NodeConditionGetter, minions minion.Registry) *NodeConditionCache {
+	return &NodeConditionCache{
+		nodeConditions: nodeConditions,
+		minions:        minions,
+		conditions:     map[string][]api.NodeCondition{},
+	}
+}
+
+// GetNodeConditions implements the NodeConditionGetter.GetNodeConditions.
+// The returned value should be treated as read-only.
+func (c *NodeConditionCache) GetNodeConditions(host string) ([]api.NodeCondition, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	conditions, ok := c.conditions[host]
+	if !ok {
+		return nil, client.ErrNodeConditionsNotAvailable
+	}
+	return conditions, nil
+}
+
+// UpdateAllConditions updates the conditions of all the minions, and forgets the minions
+// which are gone.
+func (c *NodeConditionCache) UpdateAllConditions() {
+	minions, err := c.minions.List()
+	if err != nil {
+		glog.Errorf("Error listing minions: %v", err)
+		return
+	}
+	conditions := map[string][]api.NodeCondition{}
+	for _, host := range minions {
+		hostConditions, err := c.nodeConditions.GetNodeConditions(host)
+		if err != nil {
+			glog.Errorf("Error getting the conditions of minion %s: %v", host, err)
+			continue
+		}
+		conditions[host] = hostConditions
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.conditions = conditions
+}
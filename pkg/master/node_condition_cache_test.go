@@ -0,0 +1,83 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry/registrytest"
+)
+
+type FakeNodeConditionGetter struct {
+	conditions map[string][]api.NodeCondition
+	hosts      []string
+}
+
+func (f *FakeNodeConditionGetter) GetNodeConditions(host string) ([]api.NodeCondition, error) {
+	f.hosts = append(f.hosts, host)
+	conditions, ok := f.conditions[host]
+	if !ok {
+		return nil, fmt.Errorf("unreachable")
+	}
+	return conditions, nil
+}
+
+func TestNodeConditionCacheGetMissing(t *testing.T) {
+	cache := NewNodeConditionCache(nil, nil)
+
+	conditions, err := cache.GetNodeConditions("machine")
+	if err != client.ErrNodeConditionsNotAvailable {
+		t.Errorf("Expected %#v, got %#v", client.ErrNodeConditionsNotAvailable, err)
+	}
+	if conditions != nil {
+		t.Errorf("Unexpected conditions: %#v", conditions)
+	}
+}
+
+func TestNodeConditionCacheUpdateAll(t *testing.T) {
+	pressure := []api.NodeCondition{{Kind: api.NodeDiskPressure}}
+	fake := &FakeNodeConditionGetter{
+		conditions: map[string][]api.NodeCondition{"machine": pressure, "gone": pressure},
+	}
+	cache := NewNodeConditionCache(fake, registrytest.NewMinionRegistry([]string{"machine", "unreachable"}))
+	cache.conditions["gone"] = pressure
+
+	cache.UpdateAllConditions()
+	if !reflect.DeepEqual(fake.hosts, []string{"machine", "unreachable"}) {
+		t.Errorf("Unexpected minions asked: %v", fake.hosts)
+	}
+	conditions, err := cache.GetNodeConditions("machine")
+	if err != nil || !reflect.DeepEqual(conditions, pressure) {
+		t.Errorf("Unexpected conditions: %#v, %v", conditions, err)
+	}
+	for _, host := range []string{"unreachable", "gone"} {
+		if _, err := cache.GetNodeConditions(host); err != client.ErrNodeConditionsNotAvailable {
+			t.Errorf("Expected no conditions for %s, got %v", host, err)
+		}
+	}
+
+	// Reading the cache doesn't ask the minions.
+	fake.hosts = nil
+	cache.GetNodeConditions("machine")
+	if len(fake.hosts) != 0 {
+		t.Errorf("Unexpected minions asked: %v", fake.hosts)
+	}
+}
@@ -178,8 +178,61 @@ func (r *Registry) assignPod(podID string, machine string) error {
 	return err
 }
 
+// UpdatePod updates the labels of an existing pod, and those of its manifest on the machine it
+// is bound to, so that its kubelet sees them. The other fields of a pod can't be updated yet.
 func (r *Registry) UpdatePod(pod *api.Pod) error {
-	return fmt.Errorf("unimplemented!")
+	podKey := makePodKey(pod.ID)
+	if err := r.ExtractObj(podKey, &api.Pod{}, false); err != nil {
+		return etcderr.InterpretGetError(err, "pod", pod.ID)
+	}
+	var machine string
+	err := r.AtomicUpdate(podKey, &api.Pod{}, func(obj runtime.Object) (runtime.Object, error) {
+		existing := obj.(*api.Pod)
+		existing.Labels = pod.Labels
+		machine = existing.DesiredState.Host
+		return existing, nil
+	})
+	if err != nil {
+		return etcderr.InterpretUpdateError(err, "pod", pod.ID)
+	}
+	if machine == "" {
+		return nil
+	}
+	contKey := makeContainerKey(machine)
+	return r.AtomicUpdate(contKey, &api.ContainerManifestList{}, func(in runtime.Object) (runtime.Object, error) {
+		manifests := in.(*api.ContainerManifestList)
+		for i := range manifests.Items {
+			if manifests.Items[i].ID == pod.ID {
+				manifests.Items[i].Labels = pod.Labels
+			}
+		}
+		return manifests, nil
+	})
+}
+
+// TerminatePod marks an existing pod as terminated, and removes it from the machine it is
+// bound to, so that the kubelet stops its containers. The pod is kept until it is deleted.
+// Terminating a pod again retries the removal from its machine.
+func (r *Registry) TerminatePod(podID string) error {
+	var pod api.Pod
+	podKey := makePodKey(podID)
+	if err := r.ExtractObj(podKey, &pod, false); err != nil {
+		return etcderr.InterpretUpdateError(err, "pod", podID)
+	}
+	err := r.AtomicUpdate(podKey, &api.Pod{}, func(obj runtime.Object) (runtime.Object, error) {
+		pod := obj.(*api.Pod)
+		pod.DesiredState.Status = api.PodTerminated
+		return pod, nil
+	})
+	if err != nil {
+		return etcderr.InterpretUpdateError(err, "pod", podID)
+	}
+	machine := pod.DesiredState.Host
+	if machine == "" {
+		// Pod was never scheduled anywhere, just return.
+		return nil
+	}
+	return r.removePodFromMachine(podID, machine)
 }
 
 // DeletePod deletes an existing pod specified by its ID.
@@ -201,25 +254,22 @@ func (r *Registry) DeletePod(podID string) error {
 		// Pod was never scheduled anywhere, just return.
 		return nil
 	}
-	// Next, remove the pod from the machine atomically.
+	// A terminated pod was already removed from its machine, unless that failed.
+	return r.removePodFromMachine(podID, machine)
+}
+
+// removePodFromMachine removes the manifest of a pod from the machine atomically. Removing a
+// pod which is no longer on the machine does nothing, so a failed removal can be retried.
+func (r *Registry) removePodFromMachine(podID, machine string) error {
 	contKey := makeContainerKey(machine)
 	return r.AtomicUpdate(contKey, &api.ContainerManifestList{}, func(in runtime.Object) (runtime.Object, error) {
 		manifests := in.(*api.ContainerManifestList)
 		newManifests := make([]api.ContainerManifest, 0, len(manifests.Items))
-		found := false
 		for _, manifest := range manifests.Items {
 			if manifest.ID != podID {
 				newManifests = append(newManifests, manifest)
-			} else {
-				found = true
 			}
 		}
-		if !found {
-			// This really shouldn't happen, it indicates something is broken, and likely
-			// there is a lost pod somewhere.
-			// However it is "deleted" so log it and move on
-			glog.Warningf("Couldn't find: %s in %#v", podID, manifests)
-		}
 		manifests.Items = newManifests
 		return manifests, nil
 	})
@@ -382,3 +432,42 @@ func (r *Registry) WatchEndpoints(label, field labels.Selector, resourceVersion
 	}
 	return nil, fmt.Errorf("only the 'ID' and default (everything) field selectors are supported")
 }
+
+// ListSecrets obtains a list of Secrets.
+func (r *Registry) ListSecrets() (*api.SecretList, error) {
+	list := &api.SecretList{}
+	err := r.ExtractList("/registry/secrets", &list.Items, &list.ResourceVersion)
+	return list, err
+}
+
+func makeSecretKey(id string) string {
+	return "/registry/secrets/" + id
+}
+
+// GetSecret gets a specific Secret specified by its ID.
+func (r *Registry) GetSecret(secretID string) (*api.Secret, error) {
+	var secret api.Secret
+	err := r.ExtractObj(makeSecretKey(secretID), &secret, false)
+	if err != nil {
+		return nil, etcderr.InterpretGetError(err, "secret", secretID)
+	}
+	return &secret, nil
+}
+
+// CreateSecret creates a new Secret.
+func (r *Registry) CreateSecret(secret *api.Secret) error {
+	err := r.CreateObj(makeSecretKey(secret.ID), secret)
+	return etcderr.InterpretCreateError(err, "secret", secret.ID)
+}
+
+// UpdateSecret replaces an existing Secret.
+func (r *Registry) UpdateSecret(secret *api.Secret) error {
+	err := r.SetObj(makeSecretKey(secret.ID), secret)
+	return etcderr.InterpretUpdateError(err, "secret", secret.ID)
+}
+
+// DeleteSecret deletes a Secret specified by its ID.
+func (r *Registry) DeleteSecret(secretID string) error {
+	err := r.Delete(makeSecretKey(secretID), false)
+	return etcderr.InterpretDeleteError(err, "secret", secretID)
+}
@@ -357,6 +357,151 @@ func TestEtcdDeletePod(t *testing.T) {
 	}
 }
 
+func TestEtcdUpdatePodLabels(t *testing.T) {
+	fakeClient := tools.NewFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+
+	fakeClient.Set("/registry/pods/foo", runtime.EncodeOrDie(latest.Codec, &api.Pod{
+		JSONBase:     api.JSONBase{ID: "foo"},
+		Labels:       map[string]string{"tier": "frontend"},
+		DesiredState: api.PodState{Host: "machine", Status: api.PodRunning},
+	}), 0)
+	fakeClient.Set("/registry/hosts/machine/kubelet", runtime.EncodeOrDie(latest.Codec, &api.ContainerManifestList{
+		Items: []api.ContainerManifest{
+			{ID: "foo", Labels: map[string]string{"tier": "frontend"}},
+			{ID: "bar"},
+		},
+	}), 0)
+	registry := NewTestEtcdRegistry(fakeClient)
+	err := registry.UpdatePod(&api.Pod{
+		JSONBase:     api.JSONBase{ID: "foo"},
+		Labels:       map[string]string{"tier": "backend"},
+		DesiredState: api.PodState{Host: "other"},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	pod, err := registry.GetPod("foo")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if pod.Labels["tier"] != "backend" || pod.DesiredState.Host != "machine" {
+		t.Errorf("Expected only the labels of the pod to be updated, got %#v", pod)
+	}
+	response, err := fakeClient.Get("/registry/hosts/machine/kubelet", false, false)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var manifests api.ContainerManifestList
+	latest.Codec.DecodeInto([]byte(response.Node.Value), &manifests)
+	if len(manifests.Items) != 2 || manifests.Items[0].Labels["tier"] != "backend" || manifests.Items[1].Labels != nil {
+		t.Errorf("Unexpected manifest list: %#v", manifests)
+	}
+
+	fakeClient.Data["/registry/pods/missing"] = tools.EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: nil,
+		},
+		E: tools.EtcdErrorNotFound,
+	}
+	if err := registry.UpdatePod(&api.Pod{JSONBase: api.JSONBase{ID: "missing"}}); !errors.IsNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestEtcdTerminatePod(t *testing.T) {
+	fakeClient := tools.NewFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+
+	key := "/registry/pods/foo"
+	fakeClient.Set(key, runtime.EncodeOrDie(latest.Codec, &api.Pod{
+		JSONBase:     api.JSONBase{ID: "foo"},
+		DesiredState: api.PodState{Host: "machine", Status: api.PodRunning},
+	}), 0)
+	fakeClient.Set("/registry/hosts/machine/kubelet", runtime.EncodeOrDie(latest.Codec, &api.ContainerManifestList{
+		Items: []api.ContainerManifest{
+			{ID: "foo"},
+			{ID: "bar"},
+		},
+	}), 0)
+	registry := NewTestEtcdRegistry(fakeClient)
+	err := registry.TerminatePod("foo")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(fakeClient.DeletedKeys) != 0 {
+		t.Errorf("Expected the pod to be kept, found deletes %#v", fakeClient.DeletedKeys)
+	}
+	pod, err := registry.GetPod("foo")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if pod.DesiredState.Status != api.PodTerminated {
+		t.Errorf("Expected the pod to be terminated, got %#v", pod.DesiredState)
+	}
+	response, err := fakeClient.Get("/registry/hosts/machine/kubelet", false, false)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	var manifests api.ContainerManifestList
+	latest.Codec.DecodeInto([]byte(response.Node.Value), &manifests)
+	if len(manifests.Items) != 1 || manifests.Items[0].ID != "bar" {
+		t.Errorf("Unexpected manifest list: %#v", manifests)
+	}
+
+	// Deleting the terminated pod leaves the other pods of the machine alone.
+	err = registry.DeletePod("foo")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(fakeClient.DeletedKeys) != 1 || fakeClient.DeletedKeys[0] != key {
+		t.Errorf("Unexpected deletes: %#v", fakeClient.DeletedKeys)
+	}
+	response, err = fakeClient.Get("/registry/hosts/machine/kubelet", false, false)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	latest.Codec.DecodeInto([]byte(response.Node.Value), &manifests)
+	if len(manifests.Items) != 1 || manifests.Items[0].ID != "bar" {
+		t.Errorf("Unexpected manifest list: %#v", manifests)
+	}
+}
+
+func TestEtcdTerminatePodRetriesRemoval(t *testing.T) {
+	fakeClient := tools.NewFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+
+	// The pod was marked as terminated, but removing it from its machine failed.
+	key := "/registry/pods/foo"
+	fakeClient.Set(key, runtime.EncodeOrDie(latest.Codec, &api.Pod{
+		JSONBase:     api.JSONBase{ID: "foo"},
+		DesiredState: api.PodState{Host: "machine", Status: api.PodTerminated},
+	}), 0)
+	registry := NewTestEtcdRegistry(fakeClient)
+	for _, op := range []func(string) error{registry.TerminatePod, registry.DeletePod} {
+		fakeClient.Set("/registry/hosts/machine/kubelet", runtime.EncodeOrDie(latest.Codec, &api.ContainerManifestList{
+			Items: []api.ContainerManifest{
+				{ID: "foo"},
+				{ID: "bar"},
+			},
+		}), 0)
+		if err := op("foo"); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		response, err := fakeClient.Get("/registry/hosts/machine/kubelet", false, false)
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		var manifests api.ContainerManifestList
+		latest.Codec.DecodeInto([]byte(response.Node.Value), &manifests)
+		if len(manifests.Items) != 1 || manifests.Items[0].ID != "bar" {
+			t.Errorf("Expected the pod to be removed from its machine, got %#v", manifests)
+		}
+	}
+}
+
 func TestEtcdDeletePodMultipleContainers(t *testing.T) {
 	fakeClient := tools.NewFakeEtcdClient(t)
 	fakeClient.TestIndex = true
@@ -993,3 +1138,109 @@ func TestEtcdWatchEndpointsBadSelector(t *testing.T) {
 //         Update
 //   In the buggy case, this will result in lost data.  In the correct case, the second update should fail
 //   and be retried.
+
+func TestEtcdListSecrets(t *testing.T) {
+	fakeClient := tools.NewFakeEtcdClient(t)
+	key := "/registry/secrets"
+	fakeClient.Data[key] = tools.EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Nodes: []*etcd.Node{
+					{
+						Value: runtime.EncodeOrDie(latest.Codec, &api.Secret{JSONBase: api.JSONBase{ID: "foo"}}),
+					},
+					{
+						Value: runtime.EncodeOrDie(latest.Codec, &api.Secret{JSONBase: api.JSONBase{ID: "bar"}}),
+					},
+				},
+			},
+		},
+		E: nil,
+	}
+	registry := NewTestEtcdRegistry(fakeClient)
+	secrets, err := registry.ListSecrets()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(secrets.Items) != 2 || secrets.Items[0].ID != "foo" || secrets.Items[1].ID != "bar" {
+		t.Errorf("Unexpected secret list: %#v", secrets)
+	}
+}
+
+func TestEtcdGetSecretNotFound(t *testing.T) {
+	fakeClient := tools.NewFakeEtcdClient(t)
+	fakeClient.Data["/registry/secrets/foo"] = tools.EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: nil,
+		},
+		E: tools.EtcdErrorNotFound,
+	}
+	registry := NewTestEtcdRegistry(fakeClient)
+	secret, err := registry.GetSecret("foo")
+	if secret != nil {
+		t.Errorf("Unexpected non-nil secret: %#v", secret)
+	}
+	if !errors.IsNotFound(err) {
+		t.Errorf("Unexpected error returned: %#v", err)
+	}
+}
+
+func TestEtcdCreateSecret(t *testing.T) {
+	fakeClient := tools.NewFakeEtcdClient(t)
+	registry := NewTestEtcdRegistry(fakeClient)
+	err := registry.CreateSecret(&api.Secret{
+		JSONBase: api.JSONBase{ID: "foo"},
+		Data:     map[string]string{"password": "c2VjcmV0"},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	secret, err := registry.GetSecret("foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret.ID != "foo" || secret.Data["password"] != "c2VjcmV0" {
+		t.Errorf("Unexpected secret: %#v", secret)
+	}
+
+	err = registry.CreateSecret(&api.Secret{JSONBase: api.JSONBase{ID: "foo"}})
+	if !errors.IsAlreadyExists(err) {
+		t.Errorf("expected already exists err, got %#v", err)
+	}
+}
+
+func TestEtcdUpdateSecret(t *testing.T) {
+	fakeClient := tools.NewFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+
+	resp, _ := fakeClient.Set("/registry/secrets/foo", runtime.EncodeOrDie(latest.Codec, &api.Secret{JSONBase: api.JSONBase{ID: "foo"}}), 0)
+	registry := NewTestEtcdRegistry(fakeClient)
+	err := registry.UpdateSecret(&api.Secret{
+		JSONBase: api.JSONBase{ID: "foo", ResourceVersion: resp.Node.ModifiedIndex},
+		Data:     map[string]string{"password": "c2VjcmV0"},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	secret, err := registry.GetSecret("foo")
+	if err != nil || secret.Data["password"] != "c2VjcmV0" {
+		t.Errorf("Unexpected secret: %#v, %v", secret, err)
+	}
+}
+
+func TestEtcdDeleteSecret(t *testing.T) {
+	fakeClient := tools.NewFakeEtcdClient(t)
+	registry := NewTestEtcdRegistry(fakeClient)
+	err := registry.DeleteSecret("foo")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	key := "/registry/secrets/foo"
+	if len(fakeClient.DeletedKeys) != 1 || fakeClient.DeletedKeys[0] != key {
+		t.Errorf("Expected a delete of %s, found %#v", key, fakeClient.DeletedKeys)
+	}
+}
@@ -21,20 +21,27 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+
+	"github.com/golang/glog"
 )
 
 // REST implements the RESTStorage interface, backed by a MinionRegistry.
 type REST struct {
 	registry Registry
+	// Optional, minions are reported without conditions if nil. Minions are read often, so
+	// this should not wait for their kubelets, see master.NodeConditionCache.
+	conditions client.NodeConditionGetter
 }
 
 // NewREST returns a new REST.
-func NewREST(m Registry) *REST {
+func NewREST(m Registry, conditions client.NodeConditionGetter) *REST {
 	return &REST{
-		registry: m,
+		registry:   m,
+		conditions: conditions,
 	}
 }
 
@@ -107,5 +114,13 @@ func (rs *REST) Update(minion runtime.Object) (<-chan runtime.Object, error) {
 }
 
 func (rs *REST) toApiMinion(name string) *api.Minion {
-	return &api.Minion{JSONBase: api.JSONBase{ID: name}}
+	minion := &api.Minion{JSONBase: api.JSONBase{ID: name}}
+	if rs.conditions != nil {
+		conditions, err := rs.conditions.GetNodeConditions(name)
+		if err != nil && err != client.ErrNodeConditionsNotAvailable {
+			glog.Errorf("Error getting the conditions of minion %s: %v", name, err)
+		}
+		minion.Conditions = conditions
+	}
+	return minion
 }
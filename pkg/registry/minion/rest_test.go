@@ -26,7 +26,7 @@ import (
 
 func TestMinionREST(t *testing.T) {
 	m := NewRegistry([]string{"foo", "bar"})
-	ms := NewREST(m)
+	ms := NewREST(m, nil)
 
 	if obj, err := ms.Get("foo"); err != nil || obj.(*api.Minion).ID != "foo" {
 		t.Errorf("missing expected object")
@@ -82,3 +82,29 @@ func TestMinionREST(t *testing.T) {
 		t.Errorf("Unexpected list value: %#v", list)
 	}
 }
+
+type fakeNodeConditionGetter map[string][]api.NodeCondition
+
+func (f fakeNodeConditionGetter) GetNodeConditions(host string) ([]api.NodeCondition, error) {
+	return f[host], nil
+}
+
+func TestMinionRESTConditions(t *testing.T) {
+	conditions := []api.NodeCondition{{Kind: api.NodeMemoryPressure, Reason: "low on memory"}}
+	ms := NewREST(NewRegistry([]string{"foo", "bar"}), fakeNodeConditionGetter{"foo": conditions})
+
+	obj, err := ms.Get("foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(obj.(*api.Minion).Conditions, conditions) {
+		t.Errorf("Expected conditions %#v, got %#v", conditions, obj)
+	}
+	obj, err = ms.Get("bar")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(obj.(*api.Minion).Conditions) != 0 {
+		t.Errorf("Expected no conditions, got %#v", obj)
+	}
+}
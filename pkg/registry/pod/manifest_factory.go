@@ -40,5 +40,6 @@ func (b *BasicManifestFactory) MakeManifest(machine string, pod api.Pod) (api.Co
 		pod.DesiredState.Manifest.ID = pod.ID
 		pod.DesiredState.Manifest.Containers[ix].Env = append(container.Env, envVars...)
 	}
+	pod.DesiredState.Manifest.Labels = pod.Labels
 	return pod.DesiredState.Manifest, nil
 }
@@ -33,6 +33,7 @@ func TestMakeManifestNoServices(t *testing.T) {
 
 	manifest, err := factory.MakeManifest("machine", api.Pod{
 		JSONBase: api.JSONBase{ID: "foobar"},
+		Labels:   map[string]string{"name": "foo"},
 		DesiredState: api.PodState{
 			Manifest: api.ContainerManifest{
 				Containers: []api.Container{
@@ -56,6 +57,9 @@ func TestMakeManifestNoServices(t *testing.T) {
 	if manifest.ID != "foobar" {
 		t.Errorf("Failed to assign ID to manifest: %#v", manifest.ID)
 	}
+	if !reflect.DeepEqual(manifest.Labels, map[string]string{"name": "foo"}) {
+		t.Errorf("Failed to assign labels to manifest: %#v", manifest.Labels)
+	}
 }
 
 func TestMakeManifestServices(t *testing.T) {
@@ -36,6 +36,8 @@ type Registry interface {
 	CreatePod(pod *api.Pod) error
 	// Update an existing pod
 	UpdatePod(pod *api.Pod) error
+	// Mark an existing pod as terminated, and stop it on its machine
+	TerminatePod(podID string) error
 	// Delete an existing pod
 	DeletePod(podID string) error
 }
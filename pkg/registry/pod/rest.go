@@ -77,6 +77,9 @@ func (rs *REST) Create(obj runtime.Object) (<-chan runtime.Object, error) {
 	if errs := validation.ValidatePod(pod); len(errs) > 0 {
 		return nil, errors.NewInvalid("pod", pod.ID, errs)
 	}
+	if err := checkNoMirrorPodLabel(pod); err != nil {
+		return nil, err
+	}
 
 	pod.CreationTimestamp = util.Now()
 
@@ -88,8 +91,27 @@ func (rs *REST) Create(obj runtime.Object) (<-chan runtime.Object, error) {
 	}), nil
 }
 
+// terminationTimeoutSlack is added to the grace period of a pod when waiting for its
+// containers to stop, since the kubelet takes a while to notice that the pod was deleted.
+const terminationTimeoutSlack = 30 * time.Second
+
+// Delete terminates the pod, and keeps it visible as terminating until its kubelet reports
+// that its containers are gone.
 func (rs *REST) Delete(id string) (<-chan runtime.Object, error) {
 	return apiserver.MakeAsync(func() (runtime.Object, error) {
+		pod, err := rs.registry.GetPod(id)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkNotMirrorPod(pod); err != nil {
+			return nil, err
+		}
+		if pod != nil && pod.DesiredState.Host != "" {
+			if err := rs.registry.TerminatePod(id); err != nil {
+				return nil, err
+			}
+			rs.waitForPodTerminated(pod)
+		}
 		return &api.Status{Status: api.StatusSuccess}, rs.registry.DeletePod(id)
 	}), nil
 }
@@ -104,7 +126,11 @@ func (rs *REST) Get(id string) (runtime.Object, error) {
 	}
 	if rs.podCache != nil || rs.podInfoGetter != nil {
 		rs.fillPodInfo(pod)
-		status, err := getPodStatus(pod, rs.minions)
+		minions, err := rs.listMinions()
+		if err != nil {
+			return pod, err
+		}
+		status, err := getPodStatus(pod, minions)
 		if err != nil {
 			return pod, err
 		}
@@ -134,10 +160,15 @@ func (rs *REST) filterFunc(label, field labels.Selector) func(*api.Pod) bool {
 func (rs *REST) List(label, field labels.Selector) (runtime.Object, error) {
 	pods, err := rs.registry.ListPodsPredicate(rs.filterFunc(label, field))
 	if err == nil {
+		// The minions are listed once for all the pods.
+		minions, err := rs.listMinions()
+		if err != nil {
+			return pods, err
+		}
 		for i := range pods.Items {
 			pod := &pods.Items[i]
 			rs.fillPodInfo(pod)
-			status, err := getPodStatus(pod, rs.minions)
+			status, err := getPodStatus(pod, minions)
 			if err != nil {
 				return pod, err
 			}
@@ -157,12 +188,24 @@ func (*REST) New() runtime.Object {
 	return &api.Pod{}
 }
 
+// Update changes the labels of a pod, which reach the containers of the pod through the
+// downward API. The other fields of a pod can't be changed yet.
 func (rs *REST) Update(obj runtime.Object) (<-chan runtime.Object, error) {
 	pod := obj.(*api.Pod)
 	if errs := validation.ValidatePod(pod); len(errs) > 0 {
 		return nil, errors.NewInvalid("pod", pod.ID, errs)
 	}
+	if err := checkNoMirrorPodLabel(pod); err != nil {
+		return nil, err
+	}
 	return apiserver.MakeAsync(func() (runtime.Object, error) {
+		existing, err := rs.registry.GetPod(pod.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkNotMirrorPod(existing); err != nil {
+			return nil, err
+		}
 		if err := rs.registry.UpdatePod(pod); err != nil {
 			return nil, err
 		}
@@ -170,6 +213,29 @@ func (rs *REST) Update(obj runtime.Object) (<-chan runtime.Object, error) {
 	}), nil
 }
 
+// checkNoMirrorPodLabel returns an error if pod has the label of mirror pods, which only kubelets
+// may set. Otherwise users could create pods which can't be deleted.
+func checkNoMirrorPodLabel(pod *api.Pod) error {
+	if value, found := pod.Labels[api.MirrorPodLabel]; found {
+		return errors.NewInvalid("pod", pod.ID, errors.ErrorList{
+			errors.NewFieldInvalid("labels."+api.MirrorPodLabel, value),
+		})
+	}
+	return nil
+}
+
+// checkNotMirrorPod returns an error if pod is the mirror of a static pod, which can only be
+// changed through the config of the kubelet running it.
+func checkNotMirrorPod(pod *api.Pod) error {
+	if pod == nil {
+		return nil
+	}
+	if host, found := pod.Labels[api.MirrorPodLabel]; found {
+		return errors.NewConflict("pod", pod.ID, fmt.Errorf("it mirrors a static pod of the kubelet on %s", host))
+	}
+	return nil
+}
+
 func (rs *REST) fillPodInfo(pod *api.Pod) {
 	pod.CurrentState.Host = pod.DesiredState.Host
 	if pod.CurrentState.Host == "" {
@@ -194,6 +260,11 @@ func (rs *REST) fillPodInfo(pod *api.Pod) {
 			}
 		}
 		pod.CurrentState.Info = info
+		if pod.DesiredState.Manifest.HostNetwork {
+			// The pod has no network container, it uses the network of its host.
+			pod.CurrentState.PodIP = getInstanceIP(rs.cloudProvider, pod.CurrentState.Host)
+			return
+		}
 		netContainerInfo, ok := info["net"]
 		if ok {
 			if netContainerInfo.DetailInfo.NetworkSettings != nil {
@@ -223,29 +294,42 @@ func getInstanceIP(cloud cloudprovider.Interface, host string) string {
 	return addr.String()
 }
 
-func getPodStatus(pod *api.Pod, minions client.MinionInterface) (api.PodStatus, error) {
+// listMinions returns the IDs of the minions, or nil if there is no way to list them.
+func (rs *REST) listMinions() (util.StringSet, error) {
+	if rs.minions == nil {
+		return nil, nil
+	}
+	res, err := rs.minions.ListMinions()
+	if err != nil {
+		glog.Errorf("Error listing minions: %v", err)
+		return nil, err
+	}
+	minions := util.NewStringSet()
+	for _, minion := range res.Items {
+		minions.Insert(minion.ID)
+	}
+	return minions, nil
+}
+
+// getPodStatus returns the status of a pod. minions are the IDs of the minions, or nil if
+// they are unknown.
+func getPodStatus(pod *api.Pod, minions util.StringSet) (api.PodStatus, error) {
 	if pod.CurrentState.Host == "" {
 		return api.PodWaiting, nil
 	}
 	if minions != nil {
-		res, err := minions.ListMinions()
-		if err != nil {
-			glog.Errorf("Error listing minions: %v", err)
-			return "", err
-		}
-		found := false
-		for _, minion := range res.Items {
-			if minion.ID == pod.CurrentState.Host {
-				found = true
-				break
-			}
-		}
-		if !found {
+		if !minions.Has(pod.CurrentState.Host) {
 			return api.PodTerminated, nil
 		}
 	} else {
 		glog.Errorf("Unexpected missing minion interface, status may be in-accurate")
 	}
+	if pod.DesiredState.Status == api.PodTerminated {
+		if hasRunningContainers(pod.CurrentState.Info) {
+			return api.PodTerminating, nil
+		}
+		return api.PodTerminated, nil
+	}
 	if pod.CurrentState.Info == nil {
 		return api.PodWaiting, nil
 	}
@@ -277,6 +361,40 @@ func getPodStatus(pod *api.Pod, minions client.MinionInterface) (api.PodStatus,
 	}
 }
 
+// hasRunningContainers returns true if any container in info is running.
+func hasRunningContainers(info api.PodInfo) bool {
+	for _, containerStatus := range info {
+		if containerStatus.State.Running != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForPodTerminated waits until the kubelet of the pod reports that none of its containers
+// are running, or until the termination grace period of the pod is well over.
+func (rs *REST) waitForPodTerminated(pod *api.Pod) {
+	if rs.podInfoGetter == nil {
+		return
+	}
+	gracePeriod := pod.DesiredState.Manifest.TerminationGracePeriodSeconds
+	if gracePeriod == 0 {
+		gracePeriod = api.DefaultTerminationGracePeriodSeconds
+	}
+	deadline := time.Now().Add(time.Duration(gracePeriod)*time.Second + terminationTimeoutSlack)
+	for time.Now().Before(deadline) {
+		info, err := rs.podInfoGetter.GetPodInfo(pod.DesiredState.Host, pod.ID)
+		if err == client.ErrPodInfoNotAvailable || (err == nil && !hasRunningContainers(info)) {
+			return
+		}
+		if err != nil {
+			glog.Errorf("Error getting container info of terminating pod %s: %v", pod.ID, err)
+		}
+		time.Sleep(rs.podPollPeriod)
+	}
+	glog.Warningf("Pod %s is still running on %s after its grace period, deleting it anyway", pod.ID, pod.DesiredState.Host)
+}
+
 func (rs *REST) waitForPodRunning(pod *api.Pod) (runtime.Object, error) {
 	for {
 		podObj, err := rs.Get(pod.ID)
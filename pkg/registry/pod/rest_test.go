@@ -18,7 +18,9 @@ package pod
 
 import (
 	"fmt"
+	"net"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -194,6 +196,34 @@ func TestListPodList(t *testing.T) {
 	}
 }
 
+func TestListPodListListsMinionsOnce(t *testing.T) {
+	podRegistry := registrytest.NewPodRegistry(nil)
+	podRegistry.Pods = &api.PodList{
+		Items: []api.Pod{
+			{JSONBase: api.JSONBase{ID: "foo"}, DesiredState: api.PodState{Host: "machine"}},
+			{JSONBase: api.JSONBase{ID: "bar"}, DesiredState: api.PodState{Host: "gone"}},
+		},
+	}
+	fakeClient := &client.Fake{
+		Minions: api.MinionList{Items: []api.Minion{{JSONBase: api.JSONBase{ID: "machine"}}}},
+	}
+	storage := REST{
+		registry: podRegistry,
+		minions:  fakeClient,
+	}
+	podsObj, err := storage.List(labels.Everything(), labels.Everything())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pods := podsObj.(*api.PodList)
+	if len(fakeClient.Actions) != 1 || fakeClient.Actions[0].Action != "list-minions" {
+		t.Errorf("expected the minions to be listed once, got %#v", fakeClient.Actions)
+	}
+	if len(pods.Items) != 2 || pods.Items[0].CurrentState.Status != api.PodWaiting || pods.Items[1].CurrentState.Status != api.PodTerminated {
+		t.Errorf("unexpected pods: %#v", pods.Items)
+	}
+}
+
 func TestListPodListSelection(t *testing.T) {
 	podRegistry := registrytest.NewPodRegistry(nil)
 	podRegistry.Pods = &api.PodList{
@@ -339,15 +369,7 @@ func TestGetPodCloud(t *testing.T) {
 }
 
 func TestMakePodStatus(t *testing.T) {
-	fakeClient := client.Fake{
-		Minions: api.MinionList{
-			Items: []api.Minion{
-				{
-					JSONBase: api.JSONBase{ID: "machine"},
-				},
-			},
-		},
-	}
+	minions := util.NewStringSet("machine")
 	desiredState := api.PodState{
 		Manifest: api.ContainerManifest{
 			Version: "v1beta1",
@@ -360,6 +382,8 @@ func TestMakePodStatus(t *testing.T) {
 	currentState := api.PodState{
 		Host: "machine",
 	}
+	terminatedState := desiredState
+	terminatedState.Status = api.PodTerminated
 	runningState := api.ContainerStatus{
 		State: api.ContainerState{
 			Running: &api.ContainerStateRunning{},
@@ -470,9 +494,36 @@ func TestMakePodStatus(t *testing.T) {
 			api.PodWaiting,
 			"mixed state #2",
 		},
+		{
+			&api.Pod{
+				DesiredState: terminatedState,
+				CurrentState: api.PodState{
+					Info: map[string]api.ContainerStatus{
+						"containerA": runningState,
+						"containerB": stoppedState,
+					},
+					Host: "machine",
+				},
+			},
+			api.PodTerminating,
+			"deleted but still running",
+		},
+		{
+			&api.Pod{
+				DesiredState: terminatedState,
+				CurrentState: api.PodState{
+					Info: map[string]api.ContainerStatus{
+						"containerA": stoppedState,
+					},
+					Host: "machine",
+				},
+			},
+			api.PodTerminated,
+			"deleted and stopped",
+		},
 	}
 	for _, test := range tests {
-		if status, err := getPodStatus(test.pod, &fakeClient); status != test.status {
+		if status, err := getPodStatus(test.pod, minions); status != test.status {
 			t.Errorf("In test %s, expected %v, got %v", test.test, test.status, status)
 			if err != nil {
 				t.Errorf("In test %s, unexpected error: %v", test.test, err)
@@ -547,12 +598,114 @@ func TestCreatePod(t *testing.T) {
 	}
 }
 
+func TestMirrorPodsAreReadOnly(t *testing.T) {
+	mirrorPod := &api.Pod{
+		JSONBase: api.JSONBase{ID: "foo"},
+		Labels:   map[string]string{api.MirrorPodLabel: "machine"},
+		DesiredState: api.PodState{
+			Manifest: api.ContainerManifest{Version: "v1beta1", ID: "foo"},
+			Host:     "machine",
+		},
+	}
+	podRegistry := registrytest.NewPodRegistry(nil)
+	podRegistry.Pod = mirrorPod
+	storage := REST{
+		registry:      podRegistry,
+		podPollPeriod: time.Millisecond * 100,
+	}
+
+	if _, err := storage.Create(&api.Pod{
+		JSONBase:     api.JSONBase{ID: "bar"},
+		Labels:       map[string]string{api.MirrorPodLabel: "machine"},
+		DesiredState: api.PodState{Manifest: api.ContainerManifest{Version: "v1beta1"}},
+	}); !errors.IsInvalid(err) {
+		t.Errorf("Expected to get an invalid resource error creating a mirror pod, got %v", err)
+	}
+
+	// Users can't add the label to their pods, otherwise they couldn't delete them anymore.
+	if _, err := storage.Update(mirrorPod); !errors.IsInvalid(err) {
+		t.Errorf("Expected to get an invalid resource error setting the mirror pod label, got %v", err)
+	}
+
+	update := *mirrorPod
+	update.Labels = nil
+	channel, err := storage.Update(&update)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := errors.FromObject(<-channel); !errors.IsConflict(err) {
+		t.Errorf("Expected to get a conflict error updating a mirror pod, got %v", err)
+	}
+
+	channel, err = storage.Delete("foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := errors.FromObject(<-channel); !errors.IsConflict(err) {
+		t.Errorf("Expected to get a conflict error deleting a mirror pod, got %v", err)
+	}
+	if podRegistry.Pod == nil || podRegistry.Pod.DesiredState.Status == api.PodTerminated {
+		t.Errorf("Expected the mirror pod to be left alone, got %#v", podRegistry.Pod)
+	}
+}
+
+func TestDeletePodWaitsForTermination(t *testing.T) {
+	podRegistry := registrytest.NewPodRegistry(nil)
+	podRegistry.Pod = &api.Pod{
+		JSONBase: api.JSONBase{ID: "foo"},
+		DesiredState: api.PodState{
+			Host:   "machine",
+			Status: api.PodRunning,
+		},
+	}
+	podInfoGetter := &FakePodInfoGetter{
+		info: api.PodInfo{
+			"bar": api.ContainerStatus{State: api.ContainerState{Running: &api.ContainerStateRunning{}}},
+		},
+	}
+	storage := REST{
+		registry:      podRegistry,
+		podInfoGetter: podInfoGetter,
+		podPollPeriod: time.Millisecond * 10,
+	}
+	channel, err := storage.Delete("foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case <-channel:
+		t.Fatalf("Pod was deleted while its containers are running")
+	case <-time.After(time.Millisecond * 50):
+	}
+	podRegistry.Lock()
+	if podRegistry.Pod.DesiredState.Status != api.PodTerminated {
+		t.Errorf("Expected the pod to be terminated, got %#v", podRegistry.Pod.DesiredState)
+	}
+	podRegistry.Unlock()
+
+	podInfoGetter.Lock()
+	podInfoGetter.err = client.ErrPodInfoNotAvailable
+	podInfoGetter.Unlock()
+	select {
+	case obj := <-channel:
+		if status, ok := obj.(*api.Status); !ok || status.Status != api.StatusSuccess {
+			t.Errorf("Unexpected result: %#v", obj)
+		}
+	case <-time.After(time.Second):
+		t.Error("Unexpected timeout on async channel")
+	}
+}
+
 type FakePodInfoGetter struct {
+	sync.Mutex
 	info api.PodInfo
 	err  error
 }
 
 func (f *FakePodInfoGetter) GetPodInfo(host, podID string) (api.PodInfo, error) {
+	f.Lock()
+	defer f.Unlock()
 	return f.info, f.err
 }
 
@@ -584,6 +737,23 @@ func TestFillPodInfo(t *testing.T) {
 	}
 }
 
+func TestFillPodInfoHostNetwork(t *testing.T) {
+	fakeGetter := FakePodInfoGetter{
+		info: map[string]api.ContainerStatus{
+			"foo": {DetailInfo: docker.Container{ID: "foobar"}},
+		},
+	}
+	storage := REST{
+		podCache:      &fakeGetter,
+		cloudProvider: &fake_cloud.FakeCloud{IP: net.ParseIP("10.0.0.5")},
+	}
+	pod := api.Pod{DesiredState: api.PodState{Host: "foo", Manifest: api.ContainerManifest{HostNetwork: true}}}
+	storage.fillPodInfo(&pod)
+	if pod.CurrentState.PodIP != "10.0.0.5" {
+		t.Errorf("Expected the IP address of the host, got %s", pod.CurrentState.PodIP)
+	}
+}
+
 func TestFillPodInfoNoData(t *testing.T) {
 	expectedIP := ""
 	fakeGetter := FakePodInfoGetter{
@@ -90,6 +90,16 @@ func (r *PodRegistry) UpdatePod(pod *api.Pod) error {
 	return r.Err
 }
 
+func (r *PodRegistry) TerminatePod(podId string) error {
+	r.Lock()
+	defer r.Unlock()
+	if r.Pod != nil {
+		r.Pod.DesiredState.Status = api.PodTerminated
+		r.mux.Action(watch.Modified, r.Pod)
+	}
+	return r.Err
+}
+
 func (r *PodRegistry) DeletePod(podId string) error {
 	r.Lock()
 	defer r.Unlock()
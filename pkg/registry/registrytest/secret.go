@@ -0,0 +1,54 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registrytest
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+type SecretRegistry struct {
+	Err     error
+	Secret  *api.Secret
+	Secrets *api.SecretList
+
+	DeletedID string
+	UpdatedID string
+}
+
+func (r *SecretRegistry) ListSecrets() (*api.SecretList, error) {
+	return r.Secrets, r.Err
+}
+
+func (r *SecretRegistry) GetSecret(id string) (*api.Secret, error) {
+	return r.Secret, r.Err
+}
+
+func (r *SecretRegistry) CreateSecret(secret *api.Secret) error {
+	r.Secret = secret
+	return r.Err
+}
+
+func (r *SecretRegistry) UpdateSecret(secret *api.Secret) error {
+	r.UpdatedID = secret.ID
+	r.Secret = secret
+	return r.Err
+}
+
+func (r *SecretRegistry) DeleteSecret(id string) error {
+	r.DeletedID = id
+	return r.Err
+}
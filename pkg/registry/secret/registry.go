@@ -0,0 +1,30 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// Registry is an interface for things that know how to store Secrets.
+type Registry interface {
+	ListSecrets() (*api.SecretList, error)
+	GetSecret(secretID string) (*api.Secret, error)
+	CreateSecret(secret *api.Secret) error
+	UpdateSecret(secret *api.Secret) error
+	DeleteSecret(secretID string) error
+}
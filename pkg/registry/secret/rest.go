@@ -0,0 +1,109 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/errors"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/validation"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/runtime"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+)
+
+// REST implements apiserver.RESTStorage for secrets.
+type REST struct {
+	registry Registry
+}
+
+// NewREST returns a new apiserver.RESTStorage for the given registry.
+func NewREST(registry Registry) *REST {
+	return &REST{
+		registry: registry,
+	}
+}
+
+// Create registers the given Secret.
+func (rs *REST) Create(obj runtime.Object) (<-chan runtime.Object, error) {
+	secret, ok := obj.(*api.Secret)
+	if !ok {
+		return nil, fmt.Errorf("not a secret: %#v", obj)
+	}
+	if errs := validation.ValidateSecret(secret); len(errs) > 0 {
+		return nil, errors.NewInvalid("secret", secret.ID, errs)
+	}
+
+	secret.CreationTimestamp = util.Now()
+
+	return apiserver.MakeAsync(func() (runtime.Object, error) {
+		err := rs.registry.CreateSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		return rs.registry.GetSecret(secret.ID)
+	}), nil
+}
+
+// Delete asynchronously deletes the Secret specified by its id.
+func (rs *REST) Delete(id string) (<-chan runtime.Object, error) {
+	return apiserver.MakeAsync(func() (runtime.Object, error) {
+		return &api.Status{Status: api.StatusSuccess}, rs.registry.DeleteSecret(id)
+	}), nil
+}
+
+// Get obtains the Secret specified by its id.
+func (rs *REST) Get(id string) (runtime.Object, error) {
+	return rs.registry.GetSecret(id)
+}
+
+// List obtains a list of all the Secrets.
+func (rs *REST) List(label, field labels.Selector) (runtime.Object, error) {
+	if !label.Empty() || !field.Empty() {
+		return nil, fmt.Errorf("label/field selectors are not supported on secrets")
+	}
+	secrets, err := rs.registry.ListSecrets()
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// New creates a new Secret for use with Create and Update.
+func (*REST) New() runtime.Object {
+	return &api.Secret{}
+}
+
+// Update replaces a given Secret instance with an existing instance in the registry.
+func (rs *REST) Update(obj runtime.Object) (<-chan runtime.Object, error) {
+	secret, ok := obj.(*api.Secret)
+	if !ok {
+		return nil, fmt.Errorf("not a secret: %#v", obj)
+	}
+	if errs := validation.ValidateSecret(secret); len(errs) > 0 {
+		return nil, errors.NewInvalid("secret", secret.ID, errs)
+	}
+	return apiserver.MakeAsync(func() (runtime.Object, error) {
+		err := rs.registry.UpdateSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		return rs.registry.GetSecret(secret.ID)
+	}), nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/errors"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry/registrytest"
+)
+
+func TestListSecrets(t *testing.T) {
+	mockRegistry := registrytest.SecretRegistry{
+		Secrets: &api.SecretList{
+			Items: []api.Secret{{JSONBase: api.JSONBase{ID: "foo"}}, {JSONBase: api.JSONBase{ID: "bar"}}},
+		},
+	}
+	storage := NewREST(&mockRegistry)
+	secretsObj, err := storage.List(labels.Everything(), labels.Everything())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	secrets := secretsObj.(*api.SecretList)
+	if len(secrets.Items) != 2 || secrets.Items[0].ID != "foo" || secrets.Items[1].ID != "bar" {
+		t.Errorf("Unexpected secret list: %#v", secrets)
+	}
+
+	if _, err := storage.List(labels.Set{"a": "b"}.AsSelector(), labels.Everything()); err == nil {
+		t.Errorf("Expected an error listing secrets with a label selector")
+	}
+}
+
+func TestListSecretsError(t *testing.T) {
+	mockRegistry := registrytest.SecretRegistry{
+		Err: fmt.Errorf("test error"),
+	}
+	storage := NewREST(&mockRegistry)
+	secrets, err := storage.List(labels.Everything(), labels.Everything())
+	if err != mockRegistry.Err {
+		t.Errorf("Expected %#v, Got %#v", mockRegistry.Err, err)
+	}
+	if secrets != nil {
+		t.Errorf("Unexpected non-nil secret list: %#v", secrets)
+	}
+}
+
+func TestCreateSecret(t *testing.T) {
+	mockRegistry := registrytest.SecretRegistry{}
+	storage := NewREST(&mockRegistry)
+	secret := &api.Secret{
+		JSONBase: api.JSONBase{ID: "foo"},
+		Data:     map[string]string{"password": "c2VjcmV0"},
+	}
+	channel, err := storage.Create(secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case obj := <-channel:
+		created, ok := obj.(*api.Secret)
+		if !ok || created.ID != "foo" || created.CreationTimestamp.IsZero() {
+			t.Errorf("Unexpected created secret: %#v", obj)
+		}
+	case <-time.After(time.Millisecond * 100):
+		t.Error("Unexpected timeout from async channel")
+	}
+}
+
+func TestCreateSecretInvalid(t *testing.T) {
+	mockRegistry := registrytest.SecretRegistry{}
+	storage := NewREST(&mockRegistry)
+	invalidSecrets := []*api.Secret{
+		{Data: map[string]string{"password": "c2VjcmV0"}},
+		{JSONBase: api.JSONBase{ID: "foo"}, Data: map[string]string{"../password": "c2VjcmV0"}},
+		{JSONBase: api.JSONBase{ID: "foo"}, Data: map[string]string{"password": "not base64!"}},
+	}
+	for _, secret := range invalidSecrets {
+		channel, err := storage.Create(secret)
+		if channel != nil {
+			t.Errorf("Expected nil channel")
+		}
+		if !errors.IsInvalid(err) {
+			t.Errorf("Expected to get an invalid resource error, got %v", err)
+		}
+		if channel, err = storage.Update(secret); channel != nil || !errors.IsInvalid(err) {
+			t.Errorf("Expected to get an invalid resource error updating, got %v", err)
+		}
+	}
+	if mockRegistry.Secret != nil {
+		t.Errorf("Unexpected stored secret: %#v", mockRegistry.Secret)
+	}
+}
+
+func TestUpdateSecret(t *testing.T) {
+	mockRegistry := registrytest.SecretRegistry{}
+	storage := NewREST(&mockRegistry)
+	channel, err := storage.Update(&api.Secret{JSONBase: api.JSONBase{ID: "foo"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-channel
+	if mockRegistry.UpdatedID != "foo" {
+		t.Errorf("Expected secret foo to be updated, got %q", mockRegistry.UpdatedID)
+	}
+}
+
+func TestDeleteSecret(t *testing.T) {
+	mockRegistry := registrytest.SecretRegistry{}
+	storage := NewREST(&mockRegistry)
+	channel, err := storage.Delete("foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-channel
+	if mockRegistry.DeletedID != "foo" {
+		t.Errorf("Expected secret foo to be deleted, got %q", mockRegistry.DeletedID)
+	}
+}
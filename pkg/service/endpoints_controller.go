@@ -59,8 +59,12 @@ func (e *EndpointController) SyncServiceEndpoints() error {
 			resultErr = err
 			continue
 		}
-		endpoints := make([]string, len(pods.Items))
-		for ix, pod := range pods.Items {
+		endpoints := []string{}
+		for _, pod := range pods.Items {
+			if !isPodReady(&pod) {
+				glog.V(2).Infof("Pod %s is not ready, excluding it from service %s", pod.ID, service.ID)
+				continue
+			}
 			port, err := findPort(&pod.DesiredState.Manifest, service.ContainerPort)
 			if err != nil {
 				glog.Errorf("Failed to find port for service: %v, %v", service, err)
@@ -70,7 +74,7 @@ func (e *EndpointController) SyncServiceEndpoints() error {
 				glog.Errorf("Failed to find an IP for pod: %v", pod)
 				continue
 			}
-			endpoints[ix] = net.JoinHostPort(pod.CurrentState.PodIP, strconv.Itoa(port))
+			endpoints = append(endpoints, net.JoinHostPort(pod.CurrentState.PodIP, strconv.Itoa(port)))
 		}
 		// TODO: this is totally broken, we need to compute this and store inside an AtomicUpdate loop.
 		err = e.serviceRegistry.UpdateEndpoints(&api.Endpoints{
@@ -85,6 +89,16 @@ func (e *EndpointController) SyncServiceEndpoints() error {
 	return resultErr
 }
 
+// isPodReady returns true if all the containers of the pod are reported ready.
+func isPodReady(pod *api.Pod) bool {
+	for _, container := range pod.DesiredState.Manifest.Containers {
+		if !pod.CurrentState.Info[container.Name].Ready {
+			return false
+		}
+	}
+	return true
+}
+
 // findPort locates the container port for the given manifest and portName.
 func findPort(manifest *api.ContainerManifest, portName util.IntOrString) (int, error) {
 	if ((portName.Kind == util.IntstrString && len(portName.StrVal) == 0) ||
@@ -41,6 +41,7 @@ func newPodList(count int) api.PodList {
 				Manifest: api.ContainerManifest{
 					Containers: []api.Container{
 						{
+							Name: "foo",
 							Ports: []api.Port{
 								{
 									ContainerPort: 8080,
@@ -52,6 +53,9 @@ func newPodList(count int) api.PodList {
 			},
 			CurrentState: api.PodState{
 				PodIP: "1.2.3.4",
+				Info: api.PodInfo{
+					"foo": {Ready: true},
+				},
 			},
 		})
 	}
@@ -197,6 +201,34 @@ func TestSyncEndpointsItems(t *testing.T) {
 	}
 }
 
+func TestSyncEndpointsSkipsPodsNotReady(t *testing.T) {
+	serviceList := api.ServiceList{
+		Items: []api.Service{
+			{
+				Selector: map[string]string{
+					"foo": "bar",
+				},
+			},
+		},
+	}
+	pods := newPodList(2)
+	pods.Items[1].CurrentState.PodIP = "5.6.7.8"
+	pods.Items[1].CurrentState.Info["foo"] = api.ContainerStatus{Ready: false}
+	testServer := makeTestServer(t,
+		serverResponse{http.StatusOK, pods},
+		serverResponse{http.StatusOK, serviceList})
+	client := client.NewOrDie(testServer.URL, "v1beta1", nil)
+	serviceRegistry := registrytest.ServiceRegistry{}
+	endpoints := NewEndpointController(&serviceRegistry, client)
+	if err := endpoints.SyncServiceEndpoints(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(serviceRegistry.Endpoints.Endpoints) != 1 ||
+		serviceRegistry.Endpoints.Endpoints[0] != "1.2.3.4:8080" {
+		t.Errorf("Unexpected endpoints update: %#v", serviceRegistry.Endpoints)
+	}
+}
+
 func TestSyncEndpointsPodError(t *testing.T) {
 	serviceList := api.ServiceList{
 		Items: []api.Service{
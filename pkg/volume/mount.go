@@ -0,0 +1,30 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volume
+
+// mounter mounts the tmpfs filesystems of memory-backed volumes, and the subdirectories of
+// volumes which are mounted with a subPath.
+type mounter interface {
+	// MountTmpfs mounts a tmpfs on a directory, limited to sizeLimit bytes if it isn't 0.
+	MountTmpfs(dir string, sizeLimit int64) error
+	// BindMount mounts the directory source on the directory dir.
+	BindMount(source, dir string) error
+	// Unmount unmounts the filesystem mounted on a directory.
+	Unmount(dir string) error
+	// IsTmpfs returns true if a tmpfs is mounted on a directory.
+	IsTmpfs(dir string) (bool, error)
+}
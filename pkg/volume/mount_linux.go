@@ -0,0 +1,65 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volume
+
+import (
+	"fmt"
+	"path"
+	"syscall"
+)
+
+// The filesystem type of tmpfs, from linux/magic.h.
+const tmpfsMagic = 0x01021994
+
+// systemMounter mounts filesystems with the mount system calls.
+type systemMounter struct{}
+
+func (systemMounter) MountTmpfs(dir string, sizeLimit int64) error {
+	options := ""
+	if sizeLimit > 0 {
+		options = fmt.Sprintf("size=%d", sizeLimit)
+	}
+	return syscall.Mount("tmpfs", dir, "tmpfs", 0, options)
+}
+
+func (systemMounter) BindMount(source, dir string) error {
+	return syscall.Mount(source, dir, "", syscall.MS_BIND, "")
+}
+
+func (systemMounter) Unmount(dir string) error {
+	return syscall.Unmount(dir, 0)
+}
+
+// IsTmpfs checks that the directory is a mount point as well, since the root directory of the
+// kubelet may itself be on a tmpfs.
+func (systemMounter) IsTmpfs(dir string) (bool, error) {
+	var fsStat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &fsStat); err != nil {
+		return false, err
+	}
+	if fsStat.Type != tmpfsMagic {
+		return false, nil
+	}
+	var dirStat, parentStat syscall.Stat_t
+	if err := syscall.Stat(dir, &dirStat); err != nil {
+		return false, err
+	}
+	if err := syscall.Stat(path.Dir(dir), &parentStat); err != nil {
+		return false, err
+	}
+	return dirStat.Dev != parentStat.Dev, nil
+}
@@ -0,0 +1,41 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volume
+
+import "fmt"
+
+// systemMounter is only supported on Linux; elsewhere memory-backed volumes can't be set up.
+type systemMounter struct{}
+
+func (systemMounter) MountTmpfs(dir string, sizeLimit int64) error {
+	return fmt.Errorf("memory-backed volumes are not supported on this platform")
+}
+
+func (systemMounter) BindMount(source, dir string) error {
+	return fmt.Errorf("bind mounts are not supported on this platform")
+}
+
+func (systemMounter) Unmount(dir string) error {
+	return fmt.Errorf("unmounting volumes is not supported on this platform")
+}
+
+func (systemMounter) IsTmpfs(dir string) (bool, error) {
+	return false, nil
+}
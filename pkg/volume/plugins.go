@@ -0,0 +1,220 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volume
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// Plugin creates the volumes of one kind.
+type Plugin interface {
+	// Name returns the kind of the volumes of the plugin, which names their directory under the
+	// directory of a pod: (ROOT_DIR)/(POD_ID)/volumes/(KIND)/(VOLUME_NAME).
+	Name() string
+	// CanSupport returns true if the plugin creates the volumes with the given source.
+	CanSupport(source *api.VolumeSource) bool
+	// NewBuilder returns a Builder for a volume of a pod.
+	NewBuilder(volume *api.Volume, podID string, rootDir string) (Builder, error)
+	// NewCleaner returns a Cleaner for a volume of a pod found under the root directory.
+	NewCleaner(volumeName string, podID string, rootDir string) (Cleaner, error)
+}
+
+// PluginRegistry holds the volume plugins of a kubelet, by name. A nil registry has no plugins.
+type PluginRegistry struct {
+	lock    sync.RWMutex
+	plugins map[string]Plugin
+}
+
+// NewPluginRegistry returns a registry of the given plugins.
+func NewPluginRegistry(plugins ...Plugin) (*PluginRegistry, error) {
+	registry := &PluginRegistry{plugins: map[string]Plugin{}}
+	for _, plugin := range plugins {
+		if err := registry.Register(plugin); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// DefaultPlugins returns the plugins of the volumes which need nothing from the kubelet: host
+// directories, empty directories and git repositories.
+func DefaultPlugins() []Plugin {
+	return []Plugin{&hostDirectoryPlugin{}, &emptyDirectoryPlugin{}, &gitRepoPlugin{}}
+}
+
+// Register adds a plugin to the registry. Returns an error if a plugin with the same name is
+// already registered.
+func (r *PluginRegistry) Register(plugin Plugin) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	name := plugin.Name()
+	if _, found := r.plugins[name]; found {
+		return fmt.Errorf("volume plugin already registered with name %s", name)
+	}
+	r.plugins[name] = plugin
+	return nil
+}
+
+// findPluginBySource returns the plugin which supports a volume source. Exactly one plugin must
+// support it.
+func (r *PluginRegistry) findPluginBySource(source *api.VolumeSource) (Plugin, error) {
+	if r == nil {
+		return nil, ErrUnsupportedVolumeType
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	var matches []string
+	for name, plugin := range r.plugins {
+		if plugin.CanSupport(source) {
+			matches = append(matches, name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, ErrUnsupportedVolumeType
+	case 1:
+		return r.plugins[matches[0]], nil
+	}
+	sort.Strings(matches)
+	return nil, fmt.Errorf("multiple volume plugins support the volume: %v", matches)
+}
+
+// findPluginByName returns the plugin of a kind of volumes, or nil.
+func (r *PluginRegistry) findPluginByName(name string) Plugin {
+	if r == nil {
+		return nil
+	}
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.plugins[name]
+}
+
+// hostDirectoryPlugin creates HostDirectory volumes. They aren't stored under the root directory,
+// so they are never found to be cleaned up.
+type hostDirectoryPlugin struct{}
+
+func (plugin *hostDirectoryPlugin) Name() string {
+	return "host"
+}
+
+func (plugin *hostDirectoryPlugin) CanSupport(source *api.VolumeSource) bool {
+	return source.HostDirectory != nil
+}
+
+func (plugin *hostDirectoryPlugin) NewBuilder(volume *api.Volume, podID string, rootDir string) (Builder, error) {
+	return createHostDirectory(volume), nil
+}
+
+func (plugin *hostDirectoryPlugin) NewCleaner(volumeName string, podID string, rootDir string) (Cleaner, error) {
+	return nil, ErrUnsupportedVolumeType
+}
+
+// emptyDirectoryPlugin creates EmptyDirectory volumes.
+type emptyDirectoryPlugin struct{}
+
+func (plugin *emptyDirectoryPlugin) Name() string {
+	return "empty"
+}
+
+func (plugin *emptyDirectoryPlugin) CanSupport(source *api.VolumeSource) bool {
+	return source.EmptyDirectory != nil
+}
+
+func (plugin *emptyDirectoryPlugin) NewBuilder(volume *api.Volume, podID string, rootDir string) (Builder, error) {
+	return createEmptyDirectory(volume, podID, rootDir), nil
+}
+
+func (plugin *emptyDirectoryPlugin) NewCleaner(volumeName string, podID string, rootDir string) (Cleaner, error) {
+	return &EmptyDirectory{Name: volumeName, PodID: podID, RootDir: rootDir}, nil
+}
+
+// gitRepoPlugin creates GitDirectory volumes.
+type gitRepoPlugin struct{}
+
+func (plugin *gitRepoPlugin) Name() string {
+	return "git"
+}
+
+func (plugin *gitRepoPlugin) CanSupport(source *api.VolumeSource) bool {
+	return source.GitRepo != nil
+}
+
+func (plugin *gitRepoPlugin) NewBuilder(volume *api.Volume, podID string, rootDir string) (Builder, error) {
+	return createGitDirectory(volume, podID, rootDir), nil
+}
+
+func (plugin *gitRepoPlugin) NewCleaner(volumeName string, podID string, rootDir string) (Cleaner, error) {
+	return &GitDirectory{Name: volumeName, PodID: podID, RootDir: rootDir}, nil
+}
+
+// secretPlugin creates SecretVolume volumes. It isn't one of the DefaultPlugins, since it needs a
+// source of secrets.
+type secretPlugin struct {
+	getter SecretGetter
+}
+
+// NewSecretPlugin returns a plugin creating volumes from the Secrets read by getter.
+func NewSecretPlugin(getter SecretGetter) Plugin {
+	return &secretPlugin{getter}
+}
+
+func (plugin *secretPlugin) Name() string {
+	return "secret"
+}
+
+func (plugin *secretPlugin) CanSupport(source *api.VolumeSource) bool {
+	return source.Secret != nil
+}
+
+func (plugin *secretPlugin) NewBuilder(volume *api.Volume, podID string, rootDir string) (Builder, error) {
+	return createSecretVolume(volume, podID, rootDir, plugin.getter), nil
+}
+
+func (plugin *secretPlugin) NewCleaner(volumeName string, podID string, rootDir string) (Cleaner, error) {
+	return &SecretVolume{Name: volumeName, PodID: podID, RootDir: rootDir}, nil
+}
+
+// downwardAPIPlugin creates DownwardAPIVolume volumes. It isn't one of the DefaultPlugins, since
+// it needs a source of the fields of the pods.
+type downwardAPIPlugin struct {
+	getter PodFieldGetter
+}
+
+// NewDownwardAPIPlugin returns a plugin creating volumes from the fields of pods read by getter.
+func NewDownwardAPIPlugin(getter PodFieldGetter) Plugin {
+	return &downwardAPIPlugin{getter}
+}
+
+func (plugin *downwardAPIPlugin) Name() string {
+	return "downward"
+}
+
+func (plugin *downwardAPIPlugin) CanSupport(source *api.VolumeSource) bool {
+	return source.DownwardAPI != nil
+}
+
+func (plugin *downwardAPIPlugin) NewBuilder(volume *api.Volume, podID string, rootDir string) (Builder, error) {
+	return createDownwardAPIVolume(volume, podID, rootDir, plugin.getter), nil
+}
+
+func (plugin *downwardAPIPlugin) NewCleaner(volumeName string, podID string, rootDir string) (Cleaner, error) {
+	return &DownwardAPIVolume{Name: volumeName, PodID: podID, RootDir: rootDir}, nil
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volume
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+)
+
+// MountSubPath mounts the directory subPath of the volume at volumePath on target, creating the
+// missing directories. The containers of a pod may replace any directory of a volume they write
+// to with a symlink leading out of the volume, so each directory of subPath is opened relative to
+// the one before without following symlinks, and the directory which was opened is mounted
+// rather than its path. target must not be writable by the containers.
+func MountSubPath(volumePath, subPath, target string) error {
+	return mountSubPath(systemMounter{}, volumePath, subPath, target)
+}
+
+func mountSubPath(m mounter, volumePath, subPath, target string) error {
+	fd, err := openSubPath(volumePath, subPath)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+	if err := os.MkdirAll(target, 0750); err != nil {
+		return err
+	}
+	// The mount of a previous container may be left on target.
+	if err := unmountIfMounted(m, target); err != nil {
+		return err
+	}
+	return m.BindMount(fmt.Sprintf("/proc/self/fd/%d", fd), target)
+}
+
+// openSubPath opens the directory subPath within volumePath, creating the missing directories.
+func openSubPath(volumePath, subPath string) (int, error) {
+	const flags = syscall.O_RDONLY | syscall.O_DIRECTORY | syscall.O_NOFOLLOW | syscall.O_CLOEXEC
+	fd, err := syscall.Open(volumePath, flags, 0)
+	if err != nil {
+		return -1, &os.PathError{Op: "open", Path: volumePath, Err: err}
+	}
+	current := volumePath
+	for _, element := range strings.Split(subPath, "/") {
+		if element == "" || element == "." {
+			continue
+		}
+		if element == ".." {
+			syscall.Close(fd)
+			return -1, fmt.Errorf("subPath %q leaves the volume", subPath)
+		}
+		current = path.Join(current, element)
+		if err := syscall.Mkdirat(fd, element, 0750); err != nil && err != syscall.EEXIST {
+			syscall.Close(fd)
+			return -1, &os.PathError{Op: "mkdir", Path: current, Err: err}
+		}
+		next, err := syscall.Openat(fd, element, flags, 0)
+		syscall.Close(fd)
+		if err == syscall.ELOOP || err == syscall.ENOTDIR {
+			return -1, fmt.Errorf("subPath %q goes through %s, which isn't a directory", subPath, current)
+		}
+		if err != nil {
+			return -1, &os.PathError{Op: "open", Path: current, Err: err}
+		}
+		fd = next
+	}
+	return fd, nil
+}
+
+// UnmountSubPaths unmounts and removes the mounts of MountSubPath under dir, and dir itself.
+// The mounts are expected at dir/(CONTAINER)/(MOUNT).
+func UnmountSubPaths(dir string) error {
+	return unmountSubPaths(systemMounter{}, dir)
+}
+
+func unmountSubPaths(m mounter, dir string) error {
+	containerDirs, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, containerDir := range containerDirs {
+		containerPath := path.Join(dir, containerDir.Name())
+		mountDirs, err := ioutil.ReadDir(containerPath)
+		if err != nil {
+			return err
+		}
+		for _, mountDir := range mountDirs {
+			mountPath := path.Join(containerPath, mountDir.Name())
+			if err := unmountIfMounted(m, mountPath); err != nil {
+				return err
+			}
+			// Remove fails rather than deleting the files of the volume if it's still mounted.
+			if err := os.Remove(mountPath); err != nil {
+				return err
+			}
+		}
+		if err := os.Remove(containerPath); err != nil {
+			return err
+		}
+	}
+	return os.Remove(dir)
+}
+
+// unmountIfMounted unmounts the filesystem mounted on dir, if any.
+func unmountIfMounted(m mounter, dir string) error {
+	if err := m.Unmount(dir); err != nil && err != syscall.EINVAL {
+		return err
+	}
+	return nil
+}
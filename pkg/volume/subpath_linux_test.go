@@ -0,0 +1,76 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volume
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestMountSubPath(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "SubPath")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	volumePath := path.Join(tempDir, "volume")
+	if err := os.Mkdir(volumePath, 0750); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	subPaths := path.Join(tempDir, "volume-subpaths")
+	target := path.Join(subPaths, "container", "0")
+
+	mounter := &fakeMounter{binds: map[string]string{}}
+	if err := mountSubPath(mounter, volumePath, "foo/./data", target); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, err := os.Stat(path.Join(volumePath, "foo", "data")); err != nil || !info.IsDir() {
+		t.Errorf("Expected the subdirectory to be created: %v", err)
+	}
+	if source := mounter.binds[target]; !strings.HasPrefix(source, "/proc/self/fd/") {
+		t.Errorf("Expected the opened subdirectory to be mounted on %s, got %q", target, source)
+	}
+
+	// A container of the pod may replace a subdirectory with a symlink to anywhere on the node.
+	if err := os.Symlink("/", path.Join(volumePath, "escape")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.Symlink("/etc", path.Join(volumePath, "foo", "etc")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, subPath := range []string{"escape/etc", "foo/etc", "escape", "foo/../.."} {
+		if err := mountSubPath(mounter, volumePath, subPath, target); err == nil {
+			t.Errorf("%s: expected an error", subPath)
+		}
+	}
+
+	if err := unmountSubPaths(mounter, subPaths); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(mounter.binds) != 0 {
+		t.Errorf("Expected the subPaths to be unmounted, got %v", mounter.binds)
+	}
+	if _, err := os.Stat(subPaths); !os.IsNotExist(err) {
+		t.Errorf("Expected the mounts to be removed: %v", err)
+	}
+	if err := unmountSubPaths(mounter, subPaths); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
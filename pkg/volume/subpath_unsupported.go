@@ -0,0 +1,32 @@
+//go:build !linux
+// +build !linux
+
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package volume
+
+import "fmt"
+
+// MountSubPath is only supported on Linux; elsewhere the volumes can't be mounted with a subPath.
+func MountSubPath(volumePath, subPath, target string) error {
+	return fmt.Errorf("mounting volumes with a subPath is not supported on this platform")
+}
+
+// UnmountSubPaths does nothing, since no subPath can be mounted.
+func UnmountSubPaths(dir string) error {
+	return nil
+}
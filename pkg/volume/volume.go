@@ -17,10 +17,17 @@ limitations under the License.
 package volume
 
 import (
+	"bytes"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/golang/glog"
@@ -71,28 +78,77 @@ type EmptyDirectory struct {
 	Name    string
 	PodID   string
 	RootDir string
+	// The storage backing the directory; memory-backed directories are tmpfs mounts.
+	Medium api.StorageMedium
+	// The maximum size of a memory-backed directory, 0 for no limit.
+	SizeLimit int64
+	mounter   mounter
 }
 
-// SetUp creates new directory.
+// SetUp creates new directory, and mounts a tmpfs on it if it is memory-backed.
 func (emptyDir *EmptyDirectory) SetUp() error {
 	path := emptyDir.GetPath()
 	err := os.MkdirAll(path, 0750)
 	if err != nil {
 		return err
 	}
-	return nil
+	if emptyDir.Medium != api.StorageMediumMemory {
+		return nil
+	}
+	return mountTmpfs(getMounter(emptyDir.mounter), path, emptyDir.SizeLimit)
+}
+
+// getMounter returns m, or the mounter of the system if m is nil.
+func getMounter(m mounter) mounter {
+	if m == nil {
+		return systemMounter{}
+	}
+	return m
+}
+
+// mountTmpfs mounts a tmpfs on a directory, unless one is already mounted on it.
+func mountTmpfs(m mounter, dir string, sizeLimit int64) error {
+	mounted, err := m.IsTmpfs(dir)
+	if err != nil || mounted {
+		return err
+	}
+	return m.MountTmpfs(dir, sizeLimit)
 }
 
 func (emptyDir *EmptyDirectory) GetPath() string {
 	return path.Join(emptyDir.RootDir, emptyDir.PodID, "volumes", "empty", emptyDir.Name)
 }
 
-func (emptyDir *EmptyDirectory) renameDirectory() (string, error) {
-	oldPath := emptyDir.GetPath()
-	newPath, err := ioutil.TempDir(path.Dir(oldPath), emptyDir.Name+".deleting~")
+// TearDown unmounts the tmpfs of the directory if it has one, then simply deletes everything
+// in the directory. The medium of the directory is unknown when it is found under the root
+// directory, so it is checked on disk.
+func (emptyDir *EmptyDirectory) TearDown() error {
+	return unmountAndRemove(getMounter(emptyDir.mounter), emptyDir.GetPath())
+}
+
+// unmountAndRemove unmounts the tmpfs of a directory if it has one, then removes the directory.
+func unmountAndRemove(m mounter, dir string) error {
+	mounted, err := m.IsTmpfs(dir)
+	if err != nil {
+		return err
+	}
+	if mounted {
+		if err := m.Unmount(dir); err != nil {
+			return err
+		}
+	}
+	return removeDirectory(dir)
+}
+
+func renameDirectory(oldPath string) (string, error) {
+	newPath, err := ioutil.TempDir(path.Dir(oldPath), path.Base(oldPath)+".deleting~")
 	if err != nil {
 		return "", err
 	}
+	// The temporary directory only reserves a unique name, os.Rename won't replace a directory.
+	if err := os.Remove(newPath); err != nil {
+		return "", err
+	}
 	err = os.Rename(oldPath, newPath)
 	if err != nil {
 		return "", err
@@ -100,9 +156,9 @@ func (emptyDir *EmptyDirectory) renameDirectory() (string, error) {
 	return newPath, nil
 }
 
-// TearDown simply deletes everything in the directory.
-func (emptyDir *EmptyDirectory) TearDown() error {
-	tmpDir, err := emptyDir.renameDirectory()
+// removeDirectory moves a directory out of the way, then deletes everything in it.
+func removeDirectory(dir string) error {
+	tmpDir, err := renameDirectory(dir)
 	if err != nil {
 		return err
 	}
@@ -113,6 +169,248 @@ func (emptyDir *EmptyDirectory) TearDown() error {
 	return nil
 }
 
+// GitDirectory volumes are directories laid out like EmptyDirectory volumes, into which a
+// git repository is cloned at a revision before the pod's containers start.
+type GitDirectory struct {
+	Name       string
+	PodID      string
+	RootDir    string
+	Repository string
+	Revision   string
+}
+
+// SetUp clones the repository and checks out the revision, unless it has already been done.
+func (gitDir *GitDirectory) SetUp() error {
+	dir := gitDir.GetPath()
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	parent := path.Dir(dir)
+	if err := os.MkdirAll(parent, 0750); err != nil {
+		return err
+	}
+	// Clone into a temporary directory so that a failed clone is retried by the next SetUp.
+	tmpDir, err := ioutil.TempDir(parent, gitDir.Name+".cloning~")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := runGit(tmpDir, "clone", "--", gitDir.Repository, "repo"); err != nil {
+		return err
+	}
+	repoDir := path.Join(tmpDir, "repo")
+	if gitDir.Revision != "" {
+		if err := runGit(repoDir, "checkout", gitDir.Revision); err != nil {
+			return err
+		}
+	}
+	return os.Rename(repoDir, dir)
+}
+
+func (gitDir *GitDirectory) GetPath() string {
+	return path.Join(gitDir.RootDir, gitDir.PodID, "volumes", "git", gitDir.Name)
+}
+
+// TearDown deletes the clone of the repository.
+func (gitDir *GitDirectory) TearDown() error {
+	return removeDirectory(gitDir.GetPath())
+}
+
+// SecretGetter reads the Secrets of secret volumes.
+type SecretGetter interface {
+	GetSecret(id string) (*api.Secret, error)
+}
+
+// SecretVolume volumes are memory-backed directories holding a file for each key of a Secret,
+// so that the data of the Secret never touches the disk of the node.
+type SecretVolume struct {
+	Name     string
+	PodID    string
+	RootDir  string
+	SecretID string
+	getter   SecretGetter
+	mounter  mounter
+}
+
+// SetUp mounts a tmpfs on the directory, then writes the data of the secret to it. SetUp is
+// called on every sync of the pod, so that updates to the secret eventually reach the files.
+// Once the files are written, failing to read the secret again only logs an error and keeps
+// them, so that the pod keeps running while the secret can't be read.
+func (secretVol *SecretVolume) SetUp() error {
+	dir := secretVol.GetPath()
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+	if err := mountTmpfs(getMounter(secretVol.mounter), dir, 0); err != nil {
+		return err
+	}
+	secret, err := secretVol.getter.GetSecret(secretVol.SecretID)
+	if err != nil {
+		err = fmt.Errorf("unable to read secret %q: %v", secretVol.SecretID, err)
+		if files, readErr := ioutil.ReadDir(dir); readErr == nil && len(files) > 0 {
+			glog.Errorf("Keeping the files of volume %s of pod %s: %v", secretVol.Name, secretVol.PodID, err)
+			return nil
+		}
+		return err
+	}
+	return writeSecretData(dir, secret.Data)
+}
+
+func (secretVol *SecretVolume) GetPath() string {
+	return path.Join(secretVol.RootDir, secretVol.PodID, "volumes", "secret", secretVol.Name)
+}
+
+// TearDown unmounts the tmpfs of the directory and removes it.
+func (secretVol *SecretVolume) TearDown() error {
+	return unmountAndRemove(getMounter(secretVol.mounter), secretVol.GetPath())
+}
+
+// writeSecretData writes a file in dir for each key of the base64 encoded data, and removes the
+// other files. The files whose contents change are replaced atomically.
+func writeSecretData(dir string, data map[string]string) error {
+	files := map[string][]byte{}
+	for key, value := range data {
+		contents, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("unable to decode secret key %q: %v", key, err)
+		}
+		files[key] = contents
+	}
+	return writeFiles(dir, files, 0440)
+}
+
+// writeFiles writes the files in dir with the permissions perm, and removes the other files of
+// dir. The files whose contents change are replaced atomically.
+func writeFiles(dir string, files map[string][]byte, perm os.FileMode) error {
+	for name, contents := range files {
+		if strings.Contains(name, "/") || name == "." || name == ".." {
+			return fmt.Errorf("invalid file name %q", name)
+		}
+		file := path.Join(dir, name)
+		if existing, err := ioutil.ReadFile(file); err == nil && bytes.Equal(existing, contents) {
+			continue
+		}
+		tmpFile, err := ioutil.TempFile(dir, name+".updating~")
+		if err != nil {
+			return err
+		}
+		_, err = tmpFile.Write(contents)
+		if closeErr := tmpFile.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Chmod(tmpFile.Name(), perm)
+		}
+		if err == nil {
+			err = os.Rename(tmpFile.Name(), file)
+		}
+		if err != nil {
+			os.Remove(tmpFile.Name())
+			return err
+		}
+	}
+	existing, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range existing {
+		if _, found := files[file.Name()]; !found {
+			if err := os.Remove(path.Join(dir, file.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PodFieldGetter reads the fields of the pods of downward API volumes.
+type PodFieldGetter interface {
+	// GetPodField returns the value of a field of the pod with the given ID, e.g. "labels".
+	GetPodField(podID, fieldPath string) (string, error)
+}
+
+// DownwardAPIVolume volumes are directories holding a file for each of their items, with the
+// value of a field of their pod.
+type DownwardAPIVolume struct {
+	Name    string
+	PodID   string
+	RootDir string
+	Items   []api.DownwardAPIVolumeFile
+	getter  PodFieldGetter
+}
+
+// SetUp writes the fields of the pod to the files of the directory. SetUp is called on every
+// sync of the pod, so that changes to the labels of the pod eventually reach the files.
+func (downwardVol *DownwardAPIVolume) SetUp() error {
+	dir := downwardVol.GetPath()
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+	files := map[string][]byte{}
+	for _, item := range downwardVol.Items {
+		value, err := downwardVol.getter.GetPodField(downwardVol.PodID, item.FieldPath)
+		if err != nil {
+			return fmt.Errorf("unable to get field %q of pod %s: %v", item.FieldPath, downwardVol.PodID, err)
+		}
+		files[item.Name] = []byte(value)
+	}
+	return writeFiles(dir, files, 0444)
+}
+
+func (downwardVol *DownwardAPIVolume) GetPath() string {
+	return path.Join(downwardVol.RootDir, downwardVol.PodID, "volumes", "downward", downwardVol.Name)
+}
+
+func (downwardVol *DownwardAPIVolume) TearDown() error {
+	return removeDirectory(downwardVol.GetPath())
+}
+
+// gitTimeout is how long a git command may run, so that an unresponsive repository doesn't
+// block the sync of its pod forever. The command is retried by the next SetUp.
+var gitTimeout = 5 * time.Minute
+
+// runGit runs git with args in a directory, killing it if it runs longer than gitTimeout. git
+// runs in its own process group, so that the transports it started are killed along with it.
+func runGit(dir string, args ...string) error {
+	var output bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(gitTimeout):
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-done
+		err = fmt.Errorf("timed out after %v", gitTimeout)
+	}
+	if err != nil {
+		return fmt.Errorf("git %s failed: %v (%s)", strings.Join(args, " "), err, output.String())
+	}
+	return nil
+}
+
+// IsReadOnly returns true if the containers may only read a volume, since its files are written
+// by the kubelet.
+func IsReadOnly(vol Interface) bool {
+	switch vol.(type) {
+	case *SecretVolume, *DownwardAPIVolume:
+		return true
+	}
+	return false
+}
+
 // createHostDirectory interprets API volume as a HostDirectory.
 func createHostDirectory(volume *api.Volume) *HostDirectory {
 	return &HostDirectory{volume.Source.HostDirectory.Path}
@@ -120,44 +418,74 @@ func createHostDirectory(volume *api.Volume) *HostDirectory {
 
 // createEmptyDirectory interprets API volume as an EmptyDirectory.
 func createEmptyDirectory(volume *api.Volume, podID string, rootDir string) *EmptyDirectory {
-	return &EmptyDirectory{volume.Name, podID, rootDir}
+	source := volume.Source.EmptyDirectory
+	return &EmptyDirectory{
+		Name:      volume.Name,
+		PodID:     podID,
+		RootDir:   rootDir,
+		Medium:    source.Medium,
+		SizeLimit: source.SizeLimit,
+	}
+}
+
+// createGitDirectory interprets API volume as a GitDirectory.
+func createGitDirectory(volume *api.Volume, podID string, rootDir string) *GitDirectory {
+	gitRepo := volume.Source.GitRepo
+	return &GitDirectory{volume.Name, podID, rootDir, gitRepo.Repository, gitRepo.Revision}
+}
+
+// createSecretVolume interprets API volume as a SecretVolume.
+func createSecretVolume(volume *api.Volume, podID string, rootDir string, getter SecretGetter) *SecretVolume {
+	return &SecretVolume{
+		Name:     volume.Name,
+		PodID:    podID,
+		RootDir:  rootDir,
+		SecretID: volume.Source.Secret.SecretID,
+		getter:   getter,
+	}
+}
+
+// createDownwardAPIVolume interprets API volume as a DownwardAPIVolume.
+func createDownwardAPIVolume(volume *api.Volume, podID string, rootDir string, getter PodFieldGetter) *DownwardAPIVolume {
+	return &DownwardAPIVolume{
+		Name:    volume.Name,
+		PodID:   podID,
+		RootDir: rootDir,
+		Items:   volume.Source.DownwardAPI.Items,
+		getter:  getter,
+	}
 }
 
 // CreateVolumeBuilder returns a Builder capable of mounting a volume described by an
-// *api.Volume, or an error.
-func CreateVolumeBuilder(volume *api.Volume, podID string, rootDir string) (Builder, error) {
+// *api.Volume, or an error. The Builder is created by the plugin which supports the source
+// of the volume.
+func (r *PluginRegistry) CreateVolumeBuilder(volume *api.Volume, podID string, rootDir string) (Builder, error) {
 	source := volume.Source
 	// TODO(jonesdl) We will want to throw an error here when we no longer
 	// support the default behavior.
 	if source == nil {
 		return nil, nil
 	}
-	var vol Builder
-	// TODO(jonesdl) We should probably not check every pointer and directly
-	// resolve these types instead.
-	if source.HostDirectory != nil {
-		vol = createHostDirectory(volume)
-	} else if source.EmptyDirectory != nil {
-		vol = createEmptyDirectory(volume, podID, rootDir)
-	} else {
-		return nil, ErrUnsupportedVolumeType
+	plugin, err := r.findPluginBySource(source)
+	if err != nil {
+		return nil, err
 	}
-	return vol, nil
+	return plugin.NewBuilder(volume, podID, rootDir)
 }
 
-// CreateVolumeCleaner returns a Cleaner capable of tearing down a volume.
-func CreateVolumeCleaner(kind string, name string, podID string, rootDir string) (Cleaner, error) {
-	switch kind {
-	case "empty":
-		return &EmptyDirectory{name, podID, rootDir}, nil
-	default:
+// CreateVolumeCleaner returns a Cleaner capable of tearing down a volume of the given kind.
+func (r *PluginRegistry) CreateVolumeCleaner(kind string, name string, podID string, rootDir string) (Cleaner, error) {
+	plugin := r.findPluginByName(kind)
+	if plugin == nil {
 		return nil, ErrUnsupportedVolumeType
 	}
+	return plugin.NewCleaner(name, podID, rootDir)
 }
 
 // GetCurrentVolumes examines directory structure to determine volumes that are
-// presently active and mounted. Returns a map of Cleaner types.
-func GetCurrentVolumes(rootDirectory string) map[string]Cleaner {
+// presently active and mounted, of any kind with a plugin in the registry. Returns a map of
+// Cleaner types.
+func (r *PluginRegistry) GetCurrentVolumes(rootDirectory string) map[string]Cleaner {
 	currentVolumes := make(map[string]Cleaner)
 	mountPath := rootDirectory
 	podIDDirs, err := ioutil.ReadDir(mountPath)
@@ -173,6 +501,10 @@ func GetCurrentVolumes(rootDirectory string) map[string]Cleaner {
 		podID := podIDDir.Name()
 		podIDPath := path.Join(mountPath, podID, "volumes")
 		volumeKindDirs, err := ioutil.ReadDir(podIDPath)
+		if os.IsNotExist(err) {
+			// The directory of the pod holds no volumes, only e.g. its resolv.conf.
+			continue
+		}
 		if err != nil {
 			glog.Errorf("Could not read directory: %s, (%s)", podIDPath, err)
 		}
@@ -187,9 +519,9 @@ func GetCurrentVolumes(rootDirectory string) map[string]Cleaner {
 				volumeName := volumeNameDir.Name()
 				identifier := path.Join(podID, volumeName)
 				// TODO(thockin) This should instead return a reference to an extant volume object
-				cleaner, err := CreateVolumeCleaner(volumeKind, volumeName, podID, rootDirectory)
+				cleaner, err := r.CreateVolumeCleaner(volumeKind, volumeName, podID, rootDirectory)
 				if err != nil {
-					glog.Errorf("Could not create volume cleaner: %s, (%s)", volumeNameDirs, err)
+					glog.Errorf("Could not create volume cleaner: %s, (%s)", volumeName, err)
 					continue
 				}
 				currentVolumes[identifier] = cleaner
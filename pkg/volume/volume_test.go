@@ -17,10 +17,16 @@ limitations under the License.
 package volume
 
 import (
+	"encoding/base64"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 )
@@ -72,7 +78,7 @@ func TestCreateVolumeBuilders(t *testing.T) {
 	}
 	for _, createVolumesTest := range createVolumesTests {
 		tt := createVolumesTest
-		vb, err := CreateVolumeBuilder(&tt.volume, tt.podID, tempDir)
+		vb, err := newTestPluginRegistry(t).CreateVolumeBuilder(&tt.volume, tt.podID, tempDir)
 		if tt.volume.Source == nil {
 			if vb != nil {
 				t.Errorf("Expected volume to be nil")
@@ -96,7 +102,7 @@ func TestCreateVolumeBuilders(t *testing.T) {
 		if path != tt.path {
 			t.Errorf("Unexpected bind path. Expected %v, got %v", tt.path, path)
 		}
-		vc, err := CreateVolumeCleaner(tt.kind, tt.volume.Name, tt.podID, tempDir)
+		vc, err := newTestPluginRegistry(t).CreateVolumeCleaner(tt.kind, tt.volume.Name, tt.podID, tempDir)
 		if tt.kind == "" {
 			if err != ErrUnsupportedVolumeType {
 				t.Errorf("Unexpected error: %v", err)
@@ -134,10 +140,452 @@ func TestGetActiveVolumes(t *testing.T) {
 		os.MkdirAll(volumeDir, 0750)
 		expectedIdentifiers = append(expectedIdentifiers, test.identifier)
 	}
-	volumeMap := GetCurrentVolumes(tempDir)
+	volumeMap := newTestPluginRegistry(t).GetCurrentVolumes(tempDir)
 	for _, name := range expectedIdentifiers {
 		if _, ok := volumeMap[name]; !ok {
 			t.Errorf("Expected volume map entry not found: %v", name)
 		}
 	}
 }
+
+type fakePlugin struct {
+	name string
+}
+
+// newTestPluginRegistry returns a registry of the default plugins.
+func newTestPluginRegistry(t *testing.T) *PluginRegistry {
+	plugins, err := NewPluginRegistry(DefaultPlugins()...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return plugins
+}
+
+func (plugin *fakePlugin) Name() string {
+	return plugin.name
+}
+
+func (plugin *fakePlugin) CanSupport(source *api.VolumeSource) bool {
+	return source.HostDirectory != nil && source.HostDirectory.Path == "/"+plugin.name
+}
+
+func (plugin *fakePlugin) NewBuilder(volume *api.Volume, podID string, rootDir string) (Builder, error) {
+	return &HostDirectory{volume.Source.HostDirectory.Path}, nil
+}
+
+func (plugin *fakePlugin) NewCleaner(volumeName string, podID string, rootDir string) (Cleaner, error) {
+	return &EmptyDirectory{Name: volumeName, PodID: podID, RootDir: rootDir}, nil
+}
+
+func TestPluginRegistry(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "PluginRegistry")
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	plugins := newTestPluginRegistry(t)
+	if err := plugins.Register(&fakePlugin{"fake"}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := plugins.Register(&fakePlugin{"fake"}); err == nil {
+		t.Errorf("Expected an error for a plugin registered twice")
+	}
+
+	// The fake plugin supports some host directories, which the host plugin supports too.
+	volume := api.Volume{
+		Name: "fake-dir",
+		Source: &api.VolumeSource{
+			HostDirectory: &api.HostDirectory{Path: "/fake"},
+		},
+	}
+	if _, err := plugins.CreateVolumeBuilder(&volume, "my-id", tempDir); err == nil || err == ErrUnsupportedVolumeType {
+		t.Errorf("Expected an error for a volume supported by multiple plugins, got %v", err)
+	}
+
+	os.MkdirAll(path.Join(tempDir, "my-id", "volumes", "fake", "fake-dir"), 0750)
+	os.MkdirAll(path.Join(tempDir, "my-id", "volumes", "unknown", "unknown-dir"), 0750)
+	volumeMap := plugins.GetCurrentVolumes(tempDir)
+	if _, ok := volumeMap["my-id/fake-dir"]; !ok {
+		t.Errorf("Expected volume map entry not found: %v", "my-id/fake-dir")
+	}
+	if _, ok := volumeMap["my-id/unknown-dir"]; ok {
+		t.Errorf("Unexpected volume map entry without a plugin: %v", "my-id/unknown-dir")
+	}
+}
+
+func TestGitDirectory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	tempDir, err := ioutil.TempDir("", "GitDirectory")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Make a bare repository with two commits changing a file.
+	bareDir := path.Join(tempDir, "repo.git")
+	workDir := path.Join(tempDir, "work")
+	git := func(dir string, args ...string) string {
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v (%s)", args, err, output)
+		}
+		return strings.TrimSpace(string(output))
+	}
+	git(tempDir, "init", "--bare", bareDir)
+	git(tempDir, "clone", bareDir, workDir)
+	var revisions []string
+	for _, contents := range []string{"first", "second"} {
+		if err := ioutil.WriteFile(path.Join(workDir, "file"), []byte(contents), 0640); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		git(workDir, "add", "file")
+		git(workDir, "commit", "-m", contents)
+		revisions = append(revisions, git(workDir, "rev-parse", "HEAD"))
+	}
+	git(workDir, "push", "origin", "HEAD")
+
+	rootDir := path.Join(tempDir, "root")
+	tests := []struct {
+		name     string
+		revision string
+		contents string
+	}{
+		{"head", "", "second"},
+		{"first", revisions[0], "first"},
+	}
+	for _, test := range tests {
+		volume := api.Volume{
+			Name: test.name,
+			Source: &api.VolumeSource{
+				GitRepo: &api.GitRepo{Repository: "file://" + bareDir, Revision: test.revision},
+			},
+		}
+		builder, err := newTestPluginRegistry(t).CreateVolumeBuilder(&volume, "my-id", rootDir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if err := builder.SetUp(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		expectedPath := path.Join(rootDir, "my-id/volumes/git", test.name)
+		if builder.GetPath() != expectedPath {
+			t.Errorf("Unexpected path. Expected %v, got %v", expectedPath, builder.GetPath())
+		}
+		// A second SetUp leaves the clone alone.
+		if err := builder.SetUp(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		contents, err := ioutil.ReadFile(path.Join(expectedPath, "file"))
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		if string(contents) != test.contents {
+			t.Errorf("Expected %q at revision %q, got %q", test.contents, test.revision, contents)
+		}
+	}
+
+	volumeMap := newTestPluginRegistry(t).GetCurrentVolumes(rootDir)
+	for _, name := range []string{"my-id/head", "my-id/first"} {
+		if _, ok := volumeMap[name].(*GitDirectory); !ok {
+			t.Errorf("Expected git volume map entry not found: %v", name)
+		}
+	}
+
+	volume := api.Volume{
+		Name: "missing",
+		Source: &api.VolumeSource{
+			GitRepo: &api.GitRepo{Repository: "file://" + path.Join(tempDir, "missing.git")},
+		},
+	}
+	builder, err := newTestPluginRegistry(t).CreateVolumeBuilder(&volume, "my-id", rootDir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := builder.SetUp(); err == nil {
+		t.Errorf("Expected an error cloning a missing repository")
+	}
+	if _, err := os.Stat(builder.GetPath()); !os.IsNotExist(err) {
+		t.Errorf("Expected no volume after a failed clone: %v", err)
+	}
+}
+
+type fakeMounter struct {
+	mounted  map[string]int64
+	binds    map[string]string
+	mounts   int
+	unmounts int
+}
+
+func (f *fakeMounter) MountTmpfs(dir string, sizeLimit int64) error {
+	f.mounts++
+	f.mounted[dir] = sizeLimit
+	return nil
+}
+
+func (f *fakeMounter) BindMount(source, dir string) error {
+	f.mounts++
+	f.binds[dir] = source
+	return nil
+}
+
+func (f *fakeMounter) Unmount(dir string) error {
+	f.unmounts++
+	delete(f.mounted, dir)
+	delete(f.binds, dir)
+	return nil
+}
+
+func (f *fakeMounter) IsTmpfs(dir string) (bool, error) {
+	_, found := f.mounted[dir]
+	return found, nil
+}
+
+func TestRunGitTimesOut(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	defer func(timeout time.Duration) { gitTimeout = timeout }(gitTimeout)
+	gitTimeout = 100 * time.Millisecond
+	start := time.Now()
+	if err := runGit(os.TempDir(), "-c", "alias.hang=!sleep 10", "hang"); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected git to be killed, it ran for %v", elapsed)
+	}
+}
+
+func TestMemoryEmptyDirectory(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "MemoryEmptyDirectory")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	mounter := &fakeMounter{mounted: map[string]int64{}}
+	volume := api.Volume{
+		Name: "tmpfs",
+		Source: &api.VolumeSource{
+			EmptyDirectory: &api.EmptyDirectory{Medium: api.StorageMediumMemory, SizeLimit: 1 << 20},
+		},
+	}
+	emptyDir := createEmptyDirectory(&volume, "my-id", tempDir)
+	emptyDir.mounter = mounter
+	for i := 0; i < 2; i++ {
+		if err := emptyDir.SetUp(); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	dir := path.Join(tempDir, "my-id/volumes/empty/tmpfs")
+	if size, found := mounter.mounted[dir]; !found || size != 1<<20 {
+		t.Errorf("Expected a tmpfs of %d bytes on %s, got %v", 1<<20, dir, mounter.mounted)
+	}
+	if mounter.mounts != 1 {
+		t.Errorf("Expected the tmpfs to be mounted once, got %d mounts", mounter.mounts)
+	}
+
+	cleaner := &EmptyDirectory{Name: "tmpfs", PodID: "my-id", RootDir: tempDir, mounter: mounter}
+	if err := cleaner.TearDown(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if mounter.unmounts != 1 || len(mounter.mounted) != 0 {
+		t.Errorf("Expected the tmpfs to be unmounted, got %d unmounts of %v", mounter.unmounts, mounter.mounted)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("TearDown() failed, original volume path not properly removed: %v", dir)
+	}
+
+	// Disk-backed directories are never mounted.
+	diskDir := &EmptyDirectory{Name: "disk", PodID: "my-id", RootDir: tempDir, SizeLimit: 1 << 20, mounter: mounter}
+	if err := diskDir.SetUp(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := diskDir.TearDown(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if mounter.mounts != 1 || mounter.unmounts != 1 {
+		t.Errorf("Unexpected mounts of a disk-backed directory: %d mounts, %d unmounts", mounter.mounts, mounter.unmounts)
+	}
+}
+
+type fakeSecretGetter struct {
+	secrets map[string]*api.Secret
+}
+
+func (f *fakeSecretGetter) GetSecret(id string) (*api.Secret, error) {
+	secret, found := f.secrets[id]
+	if !found {
+		return nil, fmt.Errorf("secret %q not found", id)
+	}
+	return secret, nil
+}
+
+func TestSecretVolume(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "SecretVolume")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	encode := func(value string) string {
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	getter := &fakeSecretGetter{map[string]*api.Secret{
+		"my-secret": {
+			JSONBase: api.JSONBase{ID: "my-secret"},
+			Data:     map[string]string{"username": encode("admin"), "password": encode("secret")},
+		},
+	}}
+	mounter := &fakeMounter{mounted: map[string]int64{}}
+	volume := api.Volume{
+		Name:   "creds",
+		Source: &api.VolumeSource{Secret: &api.SecretSource{SecretID: "my-secret"}},
+	}
+	secretVol := createSecretVolume(&volume, "my-id", tempDir, getter)
+	secretVol.mounter = mounter
+	dir := path.Join(tempDir, "my-id/volumes/secret/creds")
+	if secretVol.GetPath() != dir {
+		t.Errorf("Unexpected path. Expected %v, got %v", dir, secretVol.GetPath())
+	}
+	expectFiles := func(expected map[string]string) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		actual := map[string]string{}
+		for _, file := range files {
+			contents, err := ioutil.ReadFile(path.Join(dir, file.Name()))
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			actual[file.Name()] = string(contents)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected files %v, got %v", expected, actual)
+		}
+	}
+
+	if err := secretVol.SetUp(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, found := mounter.mounted[dir]; !found {
+		t.Errorf("Expected a tmpfs on %s, got %v", dir, mounter.mounted)
+	}
+	expectFiles(map[string]string{"username": "admin", "password": "secret"})
+
+	// The files follow the updates to the secret.
+	getter.secrets["my-secret"].Data = map[string]string{"password": encode("new-secret"), "token": encode("1234")}
+	if err := secretVol.SetUp(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectFiles(map[string]string{"password": "new-secret", "token": "1234"})
+	if mounter.mounts != 1 {
+		t.Errorf("Expected the tmpfs to be mounted once, got %d mounts", mounter.mounts)
+	}
+
+	// The files are kept while the secret can't be read.
+	delete(getter.secrets, "my-secret")
+	if err := secretVol.SetUp(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	expectFiles(map[string]string{"password": "new-secret", "token": "1234"})
+
+	cleaner := &SecretVolume{Name: "creds", PodID: "my-id", RootDir: tempDir, mounter: mounter}
+	if err := cleaner.TearDown(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if mounter.unmounts != 1 {
+		t.Errorf("Expected the tmpfs to be unmounted, got %d unmounts", mounter.unmounts)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("TearDown() failed, original volume path not properly removed: %v", dir)
+	}
+
+	missing := createSecretVolume(&api.Volume{
+		Name:   "missing",
+		Source: &api.VolumeSource{Secret: &api.SecretSource{SecretID: "missing"}},
+	}, "my-id", tempDir, getter)
+	missing.mounter = mounter
+	if err := missing.SetUp(); err == nil {
+		t.Errorf("Expected an error setting up a volume of a missing secret")
+	}
+}
+
+type fakePodFieldGetter struct {
+	fields map[string]string
+}
+
+func (f *fakePodFieldGetter) GetPodField(podID, fieldPath string) (string, error) {
+	value, found := f.fields[fieldPath]
+	if !found {
+		return "", fmt.Errorf("field %q of pod %s not found", fieldPath, podID)
+	}
+	return value, nil
+}
+
+func TestDownwardAPIVolume(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "DownwardAPIVolume")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	getter := &fakePodFieldGetter{map[string]string{
+		"id":          "my-id",
+		"labels":      "name=\"foo\"\ntier=\"backend\"\n",
+		"labels.tier": "backend",
+	}}
+	volume := api.Volume{
+		Name: "podinfo",
+		Source: &api.VolumeSource{DownwardAPI: &api.DownwardAPIVolumeSource{Items: []api.DownwardAPIVolumeFile{
+			{Name: "id", FieldPath: "id"},
+			{Name: "labels", FieldPath: "labels"},
+			{Name: "tier", FieldPath: "labels.tier"},
+		}}},
+	}
+	builder, err := newTestPluginRegistry(t).CreateVolumeBuilder(&volume, "my-id", tempDir)
+	if err != ErrUnsupportedVolumeType {
+		t.Errorf("Expected the volume to be unsupported without the plugin, got %v, %v", builder, err)
+	}
+	downwardVol := createDownwardAPIVolume(&volume, "my-id", tempDir, getter)
+	dir := path.Join(tempDir, "my-id/volumes/downward/podinfo")
+	if downwardVol.GetPath() != dir {
+		t.Errorf("Unexpected path. Expected %v, got %v", dir, downwardVol.GetPath())
+	}
+	expectFiles := func(expected map[string]string) {
+		actual := map[string]string{}
+		for name := range expected {
+			contents, err := ioutil.ReadFile(path.Join(dir, name))
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			actual[name] = string(contents)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected files %v, got %v", expected, actual)
+		}
+	}
+
+	if err := downwardVol.SetUp(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectFiles(map[string]string{"id": "my-id", "labels": "name=\"foo\"\ntier=\"backend\"\n", "tier": "backend"})
+
+	// The files follow the changes to the labels of the pod.
+	getter.fields["labels"] = "name=\"foo\"\ntier=\"frontend\"\n"
+	getter.fields["labels.tier"] = "frontend"
+	if err := downwardVol.SetUp(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectFiles(map[string]string{"id": "my-id", "labels": "name=\"foo\"\ntier=\"frontend\"\n", "tier": "frontend"})
+
+	cleaner := &DownwardAPIVolume{Name: "podinfo", PodID: "my-id", RootDir: tempDir}
+	if err := cleaner.TearDown(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("TearDown() failed, original volume path not properly removed: %v", dir)
+	}
+}
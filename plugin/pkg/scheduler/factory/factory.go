@@ -181,13 +181,30 @@ type storeToMinionLister struct {
 	cache.Store
 }
 
+// List returns the minions which are not under resource pressure, since their kubelets are
+// evicting pods.
 func (s *storeToMinionLister) List() (machines []string, err error) {
 	for _, m := range s.Store.List() {
-		machines = append(machines, m.(*api.Minion).ID)
+		minion := m.(*api.Minion)
+		if underPressure(minion) {
+			glog.V(2).Infof("Minion %s is under pressure, not scheduling pods on it: %v", minion.ID, minion.Conditions)
+			continue
+		}
+		machines = append(machines, minion.ID)
 	}
 	return machines, nil
 }
 
+func underPressure(minion *api.Minion) bool {
+	for _, condition := range minion.Conditions {
+		switch condition.Kind {
+		case api.NodeMemoryPressure, api.NodeDiskPressure:
+			return true
+		}
+	}
+	return false
+}
+
 // storeToPodLister turns a store into a pod lister. The store must contain (only) pods.
 type storeToPodLister struct {
 	cache.Store
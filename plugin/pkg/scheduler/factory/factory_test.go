@@ -223,6 +223,24 @@ func TestStoreToMinionLister(t *testing.T) {
 	}
 }
 
+func TestStoreToMinionListerSkipsMinionsUnderPressure(t *testing.T) {
+	store := cache.NewStore()
+	store.Add("foo", &api.Minion{JSONBase: api.JSONBase{ID: "foo"}})
+	store.Add("bar", &api.Minion{
+		JSONBase:   api.JSONBase{ID: "bar"},
+		Conditions: []api.NodeCondition{{Kind: api.NodeDiskPressure}},
+	})
+	sml := storeToMinionLister{store}
+
+	got, err := sml.List()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0] != "foo" {
+		t.Errorf("Expected [foo], got %v", got)
+	}
+}
+
 func TestStoreToPodLister(t *testing.T) {
 	store := cache.NewStore()
 	ids := []string{"foo", "bar", "baz"}